@@ -0,0 +1,888 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configVersion is the current licer.yml schema version. When a field is
+// added, bump it and append a step to configMigrations that fills the
+// field's default, so older files are upgraded instead of rejected.
+const configVersion = 2
+
+// configMigrations[i] upgrades a config from version i+1 to version i+2.
+// Files written before VERSION existed are version 1.
+var configMigrations = []func(*Config){
+	// 1 -> 2: adds VERSION itself; COPYRIGHT_OWNER, LEGACY_PATTERNS and
+	// OWNER_ALIASES are optional and default to empty
+	func(*Config) {},
+}
+
+type Config struct {
+	Version      int    `yaml:"VERSION" toml:"VERSION"`
+	FullName     string `yaml:"FULL_NAME" toml:"FULL_NAME"`
+	DefaultRole  string `yaml:"DEFAULT_ROLE" toml:"DEFAULT_ROLE"`
+	DeptOrLab    string `yaml:"DEPT_OR_LAB" toml:"DEPT_OR_LAB"`
+	Organization string `yaml:"ORGANIZATION" toml:"ORGANIZATION"`
+
+	// Optional: names the copyright holder instead of the role default
+	// (the student for Student, the organization for Faculty/Staff)
+	CopyrightOwner string `yaml:"COPYRIGHT_OWNER,omitempty" toml:"COPYRIGHT_OWNER,omitempty"`
+
+	// Optional: names the organization as copyright holder whatever the
+	// role, e.g. for grant-funded student code that keeps the MIT license
+	OwnerOrgOnly bool `yaml:"OWNER_ORG_ONLY,omitempty" toml:"OWNER_ORG_ONLY,omitempty"`
+
+	// Optional: regular expressions matching older hand-written headers
+	// that --migrate treats as ours and rewrites to the current template
+	LegacyPatterns []string `yaml:"LEGACY_PATTERNS,omitempty" toml:"LEGACY_PATTERNS,omitempty"`
+
+	// Optional: other names that mark a header as ours for --remove, such
+	// as a former name or an abbreviated organization
+	OwnerAliases []string `yaml:"OWNER_ALIASES,omitempty" toml:"OWNER_ALIASES,omitempty"`
+
+	// Optional: overrides the license of a role by SPDX id, e.g.
+	// Student: BSD-3-Clause; unlisted roles keep the default mapping
+	RoleLicenses map[string]string `yaml:"ROLE_LICENSES,omitempty" toml:"ROLE_LICENSES,omitempty"`
+
+	// Optional: where headers go per extension, e.g. .go: after-package
+	// for linters that reject comments above the package clause
+	HeaderPositions map[string]string `yaml:"HEADER_POSITIONS,omitempty" toml:"HEADER_POSITIONS,omitempty"`
+
+	// Optional: regular expressions per extension ("*" for all) for leading
+	// lines a new header must go after, e.g. .tpl: ['^\{\{/\*'], on top
+	// of the built-in ones (see defaultHeaderAfterLines)
+	HeaderAfterLines map[string][]string `yaml:"HEADER_AFTER_LINES,omitempty" toml:"HEADER_AFTER_LINES,omitempty"`
+
+	// Optional: name of the license file licer creates in the repository
+	// root, e.g. LICENSE.md; defaults to LICENSE
+	LicenseFile string `yaml:"LICENSE_FILE,omitempty" toml:"LICENSE_FILE,omitempty"`
+
+	// Optional: extensions to skip, and text extensions skipped by default
+	// (.md, .jsonc) to process, as --exclude-ext and --include-ext do
+	ExcludeExtensions []string `yaml:"EXCLUDE_EXTENSIONS,omitempty" toml:"EXCLUDE_EXTENSIONS,omitempty"`
+	IncludeExtensions []string `yaml:"INCLUDE_EXTENSIONS,omitempty" toml:"INCLUDE_EXTENSIONS,omitempty"`
+
+	// Optional: the line comment marker per extension, for languages whose
+	// comment syntax depends on the toolchain, e.g. .s: ";"
+	LineComments map[string]string `yaml:"LINE_COMMENTS,omitempty" toml:"LINE_COMMENTS,omitempty"`
+
+	// Optional: also create a NOTICE file, naming the copyright owner and
+	// organization, in repositories licensed Apache-2.0
+	CreateNotice bool `yaml:"CREATE_NOTICE,omitempty" toml:"CREATE_NOTICE,omitempty"`
+
+	// Optional: update the copyright year of a LICENSE licer wrote in an
+	// earlier year, e.g. 2024 to 2024-2025; it is left alone by default
+	UpdateLicenseYear bool `yaml:"UPDATE_LICENSE_YEAR,omitempty" toml:"UPDATE_LICENSE_YEAR,omitempty"`
+
+	// Optional: regular expressions recognizing headers that other tools
+	// write without an SPDX identifier, mapped to the SPDX id they declare
+	HeaderFormats map[string]string `yaml:"HEADER_FORMATS,omitempty" toml:"HEADER_FORMATS,omitempty"`
+
+	// Optional: starts the lines inside /* */ headers, e.g. a tab and
+	// "* " for projects whose formatter aligns them so; defaults to " * "
+	BlockCommentPrefix string `yaml:"BLOCK_COMMENT_PREFIX,omitempty" toml:"BLOCK_COMMENT_PREFIX,omitempty"`
+
+	// Optional: spdx for headers of only the SPDX-License-Identifier line,
+	// spdx-copyright to add a single copyright line; defaults to full
+	HeaderStyle string `yaml:"HEADER_STYLE,omitempty" toml:"HEADER_STYLE,omitempty"`
+
+	// Optional: the built-in header wording, minimal, standard, spdx-only
+	// or apache-full; takes precedence over HEADER_STYLE when set
+	HeaderTemplate string `yaml:"HEADER_TEMPLATE,omitempty" toml:"HEADER_TEMPLATE,omitempty"`
+
+	// Optional: wraps the prose lines of headers, comment markers
+	// included, to this many columns; the SPDX line is never wrapped.
+	// Defaults to 0, unlimited.
+	HeaderWidth int `yaml:"HEADER_WIDTH,omitempty" toml:"HEADER_WIDTH,omitempty"`
+
+	// Optional: false stops a copyright notice without SPDX identifier from
+	// being taken for a third-party header, for repositories with no
+	// external code; defaults to true
+	DetectThirdParty *bool `yaml:"DETECT_THIRD_PARTY,omitempty" toml:"DETECT_THIRD_PARTY,omitempty"`
+}
+
+// DetectsThirdParty reports whether files are checked for third-party
+// copyright notices (DETECT_THIRD_PARTY, on unless set to false)
+func (c *Config) DetectsThirdParty() bool {
+	return c.DetectThirdParty == nil || *c.DetectThirdParty
+}
+
+// excludesExtension reports whether EXCLUDE_EXTENSIONS lists ext. A nil
+// config, as for the functions that take none, lists no extension.
+func (c *Config) excludesExtension(ext string) bool {
+	return c != nil && containsExtension(c.ExcludeExtensions, ext)
+}
+
+// includesExtension reports whether INCLUDE_EXTENSIONS lists ext
+func (c *Config) includesExtension(ext string) bool {
+	return c != nil && containsExtension(c.IncludeExtensions, ext)
+}
+
+// lineComment returns the LINE_COMMENTS marker for ext. Entries that
+// loading would reject, in a Config built in code, are ignored.
+func (c *Config) lineComment(ext string) (string, bool) {
+	if c == nil || !lineCommentExtensions[ext] {
+		return "", false
+	}
+	for e, marker := range c.LineComments {
+		if NormalizeExtension(e) == ext && lineCommentMarkers[marker] {
+			return marker, true
+		}
+	}
+	return "", false
+}
+
+// compiledHeaderFormats caches the HEADER_FORMATS compiled by headerFormats,
+// keyed by formatsKey, as every file is checked against them
+var compiledHeaderFormats sync.Map
+
+// headerFormats returns HEADER_FORMATS compiled. Formats that loading
+// would reject, in a Config built in code, give none.
+func (c *Config) headerFormats() []HeaderFormat {
+	if c == nil || len(c.HeaderFormats) == 0 {
+		return nil
+	}
+	key := formatsKey(c.HeaderFormats)
+	if formats, ok := compiledHeaderFormats.Load(key); ok {
+		return formats.([]HeaderFormat)
+	}
+	formats, err := compileHeaderFormats(c.HeaderFormats)
+	if err != nil {
+		formats = nil
+	}
+	compiledHeaderFormats.Store(key, formats)
+	return formats
+}
+
+// formatsKey identifies the contents of HEADER_FORMATS
+func formatsKey(formats map[string]string) string {
+	pairs := make([]string, 0, len(formats))
+	for pattern, license := range formats {
+		pairs = append(pairs, pattern+"\x00"+license)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x00")
+}
+
+// RepoConfigName is the optional per-repository config in the repository
+// root. It overrides the user config for that repository only, and one in
+// a subdirectory overrides that for the subtree below it.
+const RepoConfigName = ".licer.yml"
+
+// RepoConfig holds the settings a .licer.yml may override
+type RepoConfig struct {
+	// Optional: the role whose headers and license the repository gets,
+	// e.g. Student for a student-led project a staff member contributes to
+	DefaultRole string `yaml:"DEFAULT_ROLE,omitempty"`
+
+	// Optional: who a directory of a monorepo belongs to, e.g. the lab
+	// and organization of one top-level project
+	DeptOrLab      string `yaml:"DEPT_OR_LAB,omitempty"`
+	Organization   string `yaml:"ORGANIZATION,omitempty"`
+	CopyrightOwner string `yaml:"COPYRIGHT_OWNER,omitempty"`
+
+	// Optional: false for a repository or subtree without external code,
+	// where third-party detection only finds false positives
+	DetectThirdParty *bool `yaml:"DETECT_THIRD_PARTY,omitempty"`
+}
+
+// ValidateRole checks that role is Student, Faculty or Staff
+func ValidateRole(role string) error {
+	if _, ok := defaultRoleLicenses[role]; !ok {
+		return fmt.Errorf("invalid role '%s', must be Student, Faculty, or Staff", role)
+	}
+	return nil
+}
+
+// WithRepoConfig returns config with the overrides of the .licer.yml in
+// dir, a repository root or a directory below it, applied, or config
+// itself when dir has none. Unset keys keep the value of config, which is
+// never modified.
+func WithRepoConfig(config *Config, dir string) (*Config, error) {
+	path := filepath.Join(dir, RepoConfigName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var repoConfig RepoConfig
+	if err := yaml.Unmarshal(data, &repoConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	merged := *config
+	if repoConfig.DefaultRole != "" {
+		if err := ValidateRole(repoConfig.DefaultRole); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		merged.DefaultRole = repoConfig.DefaultRole
+	}
+	if repoConfig.DeptOrLab != "" {
+		merged.DeptOrLab = repoConfig.DeptOrLab
+	}
+	if repoConfig.Organization != "" {
+		merged.Organization = repoConfig.Organization
+	}
+	if repoConfig.CopyrightOwner != "" {
+		merged.CopyrightOwner = repoConfig.CopyrightOwner
+	}
+	if repoConfig.DetectThirdParty != nil {
+		merged.DetectThirdParty = repoConfig.DetectThirdParty
+	}
+	return &merged, nil
+}
+
+func getConfigPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	
+	configDir := filepath.Join(homeDir, ".config")
+	// Nothing is saved in read-only mode, so the directory isn't needed
+	if !readOnly.Load() {
+		if err := os.MkdirAll(configDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create config directory: %w", err)
+		}
+	}
+	
+	// YAML is the default; licer.toml is used when it is the only config
+	// file present, for teams that keep their tool configs in TOML
+	yamlPath := filepath.Join(configDir, "licer.yml")
+	tomlPath := filepath.Join(configDir, "licer.toml")
+	if _, err := os.Stat(yamlPath); os.IsNotExist(err) {
+		if _, err := os.Stat(tomlPath); err == nil {
+			return tomlPath, nil
+		}
+	}
+
+	return yamlPath, nil
+}
+
+// isTOMLConfig reports whether configPath is read and written as TOML
+func isTOMLConfig(configPath string) bool {
+	return strings.EqualFold(filepath.Ext(configPath), ".toml")
+}
+
+func LoadOrCreateConfig() (*Config, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	
+	// Try to load existing config
+	if _, err := os.Stat(configPath); err == nil {
+		return loadConfig(configPath)
+	}
+	
+	// Create new config, which can't be saved in read-only mode
+	if readOnly.Load() {
+		return nil, fmt.Errorf("no config file at %s: %w", configPath, ErrReadOnly)
+	}
+	config, err := createConfig()
+	if err != nil {
+		return nil, err
+	}
+	
+	// Save config
+	if err := saveConfig(config, configPath); err != nil {
+		return nil, err
+	}
+	
+	return config, nil
+}
+
+// LoadExistingConfig loads the configuration without ever prompting, for
+// modes where stdin is not available for interactive input.
+func LoadExistingConfig() (*Config, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("no configuration found at %s, run licer interactively once to create it", configPath)
+	}
+
+	return loadConfig(configPath)
+}
+
+// InitConfig runs the interactive setup and saves the result, without
+// processing anything. When a config exists its values are offered as the
+// defaults, so the prompts update it, e.g. to switch roles; settings that
+// have no prompt are kept.
+func InitConfig() (*Config, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return initConfig(configPath, os.Stdin)
+}
+
+func initConfig(configPath string, input io.Reader) (*Config, error) {
+	current := newConfigDefaults()
+	if _, err := os.Stat(configPath); err == nil {
+		// Read without validating, so an incomplete file can be completed
+		if current, err = readConfigFile(configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	config, err := promptConfig(current, input)
+	if err != nil {
+		return nil, err
+	}
+	config.Version = configVersion
+	if err := saveConfig(config, configPath); err != nil {
+		return nil, err
+	}
+
+	return loadConfig(configPath)
+}
+
+// readConfigFile parses configPath without validating it
+func readConfigFile(configPath string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	
+	var config Config
+	if isTOMLConfig(configPath) {
+		err = toml.Unmarshal(data, &config)
+	} else {
+		err = yaml.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return &config, nil
+}
+
+func loadConfig(configPath string) (*Config, error) {
+	loaded, err := readConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	config := *loaded
+	
+	// Validate required fields (only the original four)
+	if config.FullName == "" || config.DefaultRole == "" ||
+	   config.DeptOrLab == "" || config.Organization == "" {
+		return nil, fmt.Errorf("config file is incomplete, please delete it and run again to recreate")
+	}
+	
+	// Validate role
+	if err := ValidateRole(config.DefaultRole); err != nil {
+		return nil, err
+	}
+	
+	// Validate the role to license mapping
+	if err := validateRoleLicenses(config.RoleLicenses); err != nil {
+		return nil, err
+	}
+
+	// Validate header positions
+	if err := validateHeaderPositions(config.HeaderPositions); err != nil {
+		return nil, err
+	}
+
+	// Validate header anchors
+	if err := ValidateHeaderAfterLines(config.HeaderAfterLines); err != nil {
+		return nil, err
+	}
+
+	// Validate the license file name
+	if err := validateLicenseFile(config.LicenseFile); err != nil {
+		return nil, err
+	}
+
+	// Validate the block comment prefix
+	if err := validateBlockCommentPrefix(config.BlockCommentPrefix); err != nil {
+		return nil, err
+	}
+
+	// Validate the header style
+	if err := validateHeaderStyle(config.HeaderStyle); err != nil {
+		return nil, err
+	}
+
+	// Validate the header template
+	if err := ValidateHeaderTemplate(config.HeaderTemplate); err != nil {
+		return nil, err
+	}
+
+	// Validate the header width
+	if err := ValidateHeaderWidth(config.HeaderWidth); err != nil {
+		return nil, err
+	}
+
+	// Validate legacy header patterns
+	if _, err := CompileLegacyPatterns(config.LegacyPatterns); err != nil {
+		return nil, err
+	}
+
+	// Validate the header formats, extensions and comment markers that
+	// files processed with the config are detected, skipped and commented by
+	if _, err := compileHeaderFormats(config.HeaderFormats); err != nil {
+		return nil, err
+	}
+	for _, ext := range config.IncludeExtensions {
+		if err := ValidateIncludeExtension(ext); err != nil {
+			return nil, fmt.Errorf("INCLUDE_EXTENSIONS: %w", err)
+		}
+	}
+	if err := validateLineComments(config.LineComments); err != nil {
+		return nil, err
+	}
+
+	// Upgrade older config files in place rather than rejecting them
+	if config.Version < configVersion {
+		if err := migrateConfig(&config, configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return &config, nil
+}
+
+// migrateConfig applies the pending configMigrations steps and rewrites
+// the file with the new VERSION
+func migrateConfig(config *Config, configPath string) error {
+	from := config.Version
+	if from < 1 {
+		from = 1
+	}
+	for v := from; v < configVersion; v++ {
+		configMigrations[v-1](config)
+	}
+	config.Version = configVersion
+
+	// In read-only mode the upgrade only applies to this run
+	if readOnly.Load() {
+		return nil
+	}
+	if err := saveConfig(config, configPath); err != nil {
+		return fmt.Errorf("failed to upgrade config file: %w", err)
+	}
+	return nil
+}
+
+func createConfig() (*Config, error) {
+	config, err := promptConfig(newConfigDefaults(), os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	config.Version = configVersion
+	return config, nil
+}
+
+// newConfigDefaults returns the defaults offered when creating a config:
+// the git user name and Oregon State University
+func newConfigDefaults() *Config {
+	return &Config{FullName: getGitUserName(), Organization: "Oregon State University"}
+}
+
+// roleChoices are the answers to the role prompt
+var roleChoices = map[string]string{"1": "Student", "2": "Faculty", "3": "Staff"}
+
+// promptConfig asks for the four required settings on input, offering the
+// values of current as defaults, and returns a copy of current with the
+// answers
+func promptConfig(current *Config, input io.Reader) (*Config, error) {
+	config := *current
+	reader := bufio.NewReader(input)
+	
+	// Get full name with git fallback
+	if config.FullName != "" {
+		fmt.Printf("Full Name (default: %s): ", config.FullName)
+	} else {
+		fmt.Print("Full Name: ")
+	}
+	
+	nameInput, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	
+	if nameInput = strings.TrimSpace(nameInput); nameInput != "" {
+		config.FullName = nameInput
+	} else if config.FullName == "" {
+		return nil, fmt.Errorf("full name is required")
+	}
+	
+	// Get role
+	defaultChoice := ""
+	for choice, role := range roleChoices {
+		if role == config.DefaultRole {
+			defaultChoice = choice
+		}
+	}
+	for {
+		if defaultChoice != "" {
+			fmt.Printf("Role (1=Student, 2=Faculty, 3=Staff, default: %s): ", defaultChoice)
+		} else {
+			fmt.Print("Role (1=Student, 2=Faculty, 3=Staff): ")
+		}
+		roleInput, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input: %w", err)
+		}
+		
+		roleInput = strings.TrimSpace(roleInput)
+		if roleInput == "" {
+			roleInput = defaultChoice
+		}
+		role, ok := roleChoices[roleInput]
+		if !ok {
+			fmt.Println("Please enter 1, 2, or 3")
+			continue
+		}
+		config.DefaultRole = role
+		break
+	}
+	
+	// Get department/lab
+	if config.DeptOrLab != "" {
+		fmt.Printf("Department/Lab (default: %s): ", config.DeptOrLab)
+	} else {
+		fmt.Print("Department/Lab: ")
+	}
+	deptInput, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	if deptInput = strings.TrimSpace(deptInput); deptInput != "" {
+		config.DeptOrLab = deptInput
+	}
+	if config.DeptOrLab == "" {
+		return nil, fmt.Errorf("department/lab is required")
+	}
+	
+	// Get organization
+	fmt.Printf("Organization (default: %s): ", config.Organization)
+	orgInput, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	
+	if orgInput = strings.TrimSpace(orgInput); orgInput != "" {
+		config.Organization = orgInput
+	}
+	if config.Organization == "" {
+		config.Organization = "Oregon State University"
+	}
+	
+	return &config, nil
+}
+
+// saveConfig writes config to configPath. An existing YAML file is edited
+// rather than replaced, so its comments, key order and keys licer doesn't
+// know survive the rewrite.
+func saveConfig(config *Config, configPath string) error {
+	var data []byte
+	var err error
+	if isTOMLConfig(configPath) {
+		data, err = toml.Marshal(config)
+	} else {
+		data, err = marshalYAMLConfig(config, configPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	
+	if err := writeFile(configPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	
+	fmt.Fprintf(os.Stderr, "Configuration saved to %s\n", configPath)
+	return nil
+}
+
+// marshalYAMLConfig encodes config as YAML, merged into the file at
+// configPath when that holds a YAML mapping
+func marshalYAMLConfig(config *Config, configPath string) ([]byte, error) {
+	var updated yaml.Node
+	if err := updated.Encode(config); err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(configPath)
+	if err != nil || yaml.Unmarshal(data, &doc) != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return yaml.Marshal(&updated)
+	}
+	mergeYAMLMapping(doc.Content[0], &updated)
+	return yaml.Marshal(&doc)
+}
+
+// configYAMLKeys are the keys of the Config fields
+var configYAMLKeys = func() map[string]bool {
+	keys := map[string]bool{}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		keys[strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]] = true
+	}
+	return keys
+}()
+
+// mergeYAMLMapping updates the mapping existing to the values of updated.
+// Values that are unchanged keep their node, and with it any anchor,
+// alias, quoting or comment; changed values are replaced but keep their
+// comments and anchor. Config fields that are no longer set are dropped,
+// unknown keys are kept, and new fields are inserted where updated has them.
+func mergeYAMLMapping(existing, updated *yaml.Node) {
+	values := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(updated.Content); i += 2 {
+		values[updated.Content[i].Value] = updated.Content[i+1]
+	}
+
+	var content []*yaml.Node
+	seen := map[string]bool{}
+	replaced := map[*yaml.Node]*yaml.Node{}
+	for i := 0; i+1 < len(existing.Content); i += 2 {
+		key, value := existing.Content[i], existing.Content[i+1]
+		newValue, ok := values[key.Value]
+		switch {
+		case !ok && configYAMLKeys[key.Value]:
+			continue
+		case ok && !sameYAMLValue(value, newValue):
+			newValue.HeadComment = value.HeadComment
+			newValue.LineComment = value.LineComment
+			newValue.FootComment = value.FootComment
+			newValue.Anchor = value.Anchor
+			replaced[value] = newValue
+			value = newValue
+		}
+		seen[key.Value] = true
+		content = append(content, key, value)
+	}
+
+	// Aliases of a replaced anchored value follow it to the new value,
+	// unless they are Config fields that keep a value of their own
+	for i := 0; i+1 < len(content); i += 2 {
+		key, value := content[i], content[i+1]
+		target, ok := replaced[value.Alias]
+		if value.Kind != yaml.AliasNode || !ok {
+			continue
+		}
+		if newValue, isField := values[key.Value]; isField && !sameYAMLValue(target, newValue) {
+			newValue.HeadComment = value.HeadComment
+			newValue.LineComment = value.LineComment
+			newValue.FootComment = value.FootComment
+			content[i+1] = newValue
+			continue
+		}
+		value.Alias = target
+	}
+
+	// Insert new fields before the next field the file already has, going
+	// backwards so that field is in place when an earlier one looks for it
+	for i := len(updated.Content) - 2; i >= 0; i -= 2 {
+		key := updated.Content[i]
+		if seen[key.Value] {
+			continue
+		}
+		at := len(content)
+		for j := i + 2; j < len(updated.Content) && at == len(content); j += 2 {
+			for k := 0; k < len(content); k += 2 {
+				if content[k].Value == updated.Content[j].Value {
+					at = k
+					break
+				}
+			}
+		}
+		if at == 0 && len(content) > 0 {
+			// A comment at the top of the file stays at the top
+			key.HeadComment, content[0].HeadComment = content[0].HeadComment, ""
+		}
+		content = append(content[:at], append([]*yaml.Node{key, updated.Content[i+1]}, content[at:]...)...)
+		seen[key.Value] = true
+	}
+
+	existing.Content = content
+}
+
+// sameYAMLValue reports whether two nodes decode to the same value
+func sameYAMLValue(a, b *yaml.Node) bool {
+	var va, vb interface{}
+	if a.Decode(&va) != nil || b.Decode(&vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func getGitUserName() string {
+	cmd := exec.Command("git", "config", "--global", "user.name")
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// validateRoleLicenses checks that ROLE_LICENSES only maps known roles to
+// licenses licer can write
+func validateRoleLicenses(roleLicenses map[string]string) error {
+	for role, license := range roleLicenses {
+		if _, ok := defaultRoleLicenses[role]; !ok {
+			return fmt.Errorf("invalid role '%s' in ROLE_LICENSES, must be Student, Faculty, or Staff", role)
+		}
+		if _, ok := knownLicenses[license]; !ok {
+			return fmt.Errorf("unsupported license '%s' for %s in ROLE_LICENSES, must be one of %s", license, role, strings.Join(knownLicenseIDs(), ", "))
+		}
+	}
+	return nil
+}
+
+// validateLicenseFile checks that LICENSE_FILE names a file in the
+// repository root rather than a path
+func validateLicenseFile(name string) error {
+	if name != "" && (name != filepath.Base(name) || name == "." || name == "..") {
+		return fmt.Errorf("invalid LICENSE_FILE '%s', must be a file name in the repository root", name)
+	}
+	return nil
+}
+
+// blockCommentPrefixPattern accepts continuation prefixes that keep the
+// header lines recognizable as comment lines: optional indentation, one
+// or more stars and optional spacing
+var blockCommentPrefixPattern = regexp.MustCompile(`^[ \t]*\*+[ \t]*$`)
+
+// validateBlockCommentPrefix checks that BLOCK_COMMENT_PREFIX is
+// indentation and stars, so detection still sees a comment block
+func validateBlockCommentPrefix(prefix string) error {
+	if prefix != "" && !blockCommentPrefixPattern.MatchString(prefix) {
+		return fmt.Errorf("invalid BLOCK_COMMENT_PREFIX %q, must be spaces or tabs around one or more '*'", prefix)
+	}
+	return nil
+}
+
+// validateLineComments checks that every LINE_COMMENTS entry names an
+// extension of lineCommentExtensions and a marker of lineCommentMarkers
+func validateLineComments(comments map[string]string) error {
+	for ext, marker := range comments {
+		if !lineCommentExtensions[NormalizeExtension(ext)] {
+			return fmt.Errorf("invalid LINE_COMMENTS extension %q, must be an assembler extension (.asm or .s)", ext)
+		}
+		if !lineCommentMarkers[marker] {
+			return fmt.Errorf("invalid LINE_COMMENTS marker %q for %s, must be one of # ; ;; // -- %% !", marker, ext)
+		}
+	}
+	return nil
+}
+
+// Header styles of HEADER_STYLE
+const (
+	HeaderStyleFull          = "full"
+	HeaderStyleSPDX          = "spdx"
+	HeaderStyleSPDXCopyright = "spdx-copyright"
+)
+
+func validateHeaderStyle(style string) error {
+	switch style {
+	case "", HeaderStyleFull, HeaderStyleSPDX, HeaderStyleSPDXCopyright:
+		return nil
+	}
+	return fmt.Errorf("invalid HEADER_STYLE %q, must be %s, %s or %s", style, HeaderStyleFull, HeaderStyleSPDX, HeaderStyleSPDXCopyright)
+}
+
+// ValidateIncludeExtension checks that ext may be opted in: binary formats
+// of AlwaysExcludedExtensions never get a header
+func ValidateIncludeExtension(ext string) error {
+	if AlwaysExcludedExtensions[NormalizeExtension(ext)] {
+		return fmt.Errorf("%s is a binary file type and is never processed", NormalizeExtension(ext))
+	}
+	return nil
+}
+
+// Built-in header wordings of HEADER_TEMPLATE and --template-name
+const (
+	HeaderTemplateMinimal    = "minimal"
+	HeaderTemplateStandard   = "standard"
+	HeaderTemplateSPDXOnly   = "spdx-only"
+	HeaderTemplateApacheFull = "apache-full"
+)
+
+// minHeaderWidth is the narrowest HEADER_WIDTH accepted, below which the
+// wrapped header would be mostly continuation lines
+const minHeaderWidth = 40
+
+// ValidateHeaderWidth checks that HEADER_WIDTH is 0 (unlimited) or at least
+// minHeaderWidth columns
+func ValidateHeaderWidth(width int) error {
+	if width != 0 && width < minHeaderWidth {
+		return fmt.Errorf("invalid HEADER_WIDTH %d, must be 0 (unlimited) or at least %d", width, minHeaderWidth)
+	}
+	return nil
+}
+
+// ValidateHeaderTemplate checks that name is one of the built-in header
+// templates, or empty for the HEADER_STYLE default
+func ValidateHeaderTemplate(name string) error {
+	switch name {
+	case "", HeaderTemplateMinimal, HeaderTemplateStandard, HeaderTemplateSPDXOnly, HeaderTemplateApacheFull:
+		return nil
+	}
+	return fmt.Errorf("invalid HEADER_TEMPLATE %q, must be %s, %s, %s or %s", name, HeaderTemplateMinimal, HeaderTemplateStandard, HeaderTemplateSPDXOnly, HeaderTemplateApacheFull)
+}
+
+// compileHeaderFormats turns HEADER_FORMATS into HeaderFormats, in pattern
+// order so detection does not depend on map order
+func compileHeaderFormats(formats map[string]string) ([]HeaderFormat, error) {
+	patterns := make([]string, 0, len(formats))
+	for pattern := range formats {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	var compiled []HeaderFormat
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s' in HEADER_FORMATS: %w", pattern, err)
+		}
+		if strings.TrimSpace(formats[pattern]) == "" {
+			return nil, fmt.Errorf("no license given for '%s' in HEADER_FORMATS", pattern)
+		}
+		if err := ValidateSPDXExpression(formats[pattern]); err != nil {
+			return nil, fmt.Errorf("license for '%s' in HEADER_FORMATS: %w", pattern, err)
+		}
+		compiled = append(compiled, HeaderFormat{Name: "HEADER_FORMATS", Pattern: re, LicenseID: strings.TrimSpace(formats[pattern])})
+	}
+	return compiled, nil
+}
+
+func knownLicenseIDs() []string {
+	ids := make([]string, 0, len(knownLicenses))
+	for id := range knownLicenses {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,733 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// SplitBOM separates a leading UTF-8 byte order mark from the content so
+// line analysis never sees it and writers can put it back first
+func SplitBOM(content []byte) ([]byte, []byte) {
+	if bytes.HasPrefix(content, utf8BOM) {
+		// Cap the BOM slice so appending to it never writes into content
+		return content[:len(utf8BOM):len(utf8BOM)], content[len(utf8BOM):]
+	}
+	return nil, content
+}
+
+// headerSearchLines is how far into a file an SPDX identifier is looked for
+const headerSearchLines = 20
+
+type HeaderInfo struct {
+	HasHeader              bool
+	HasThirdPartyCopyright bool
+	StartLine              int
+	EndLine                int
+	HasShebang             bool
+	PreambleLines          int    // leading lines that must stay first (shebang, Dockerfile directives)
+	LicenseID              string // SPDX license expression of the header, if any
+	BlockComment           bool   // the header is a /* */ or <!-- --> block, delimiters included in StartLine..EndLine
+	Docstring              bool   // the header shares a Python module docstring with the module's documentation
+	Format                 string // name of the HeaderFormat that recognized a header without SPDX identifier
+}
+
+// HeaderFormat recognizes headers that other tools write without an SPDX
+// identifier by a line that is distinctive for the tool's template
+type HeaderFormat struct {
+	Name      string
+	Pattern   *regexp.Regexp
+	LicenseID string // SPDX id of the license the template declares
+}
+
+// HeaderFormats are the headers without SPDX identifier that are detected
+// as headers, so they are not duplicated and --force can normalize them.
+// HEADER_FORMATS in the config adds more.
+var HeaderFormats = []HeaderFormat{
+	// google/addlicense templates
+	{"addlicense-apache", regexp.MustCompile(`Licensed under the Apache License, Version 2\.0 \(the "License"\)`), "Apache-2.0"},
+	{"addlicense-mit", regexp.MustCompile(`Use of this source code is governed by an MIT-style`), "MIT"},
+	{"addlicense-bsd", regexp.MustCompile(`Use of this source code is governed by a BSD-style`), "BSD-3-Clause"},
+	{"addlicense-mpl", regexp.MustCompile(`This Source Code Form is subject to the terms of the Mozilla Public`), "MPL-2.0"},
+}
+
+// findHeaderFormat returns the first line within headerSearchLines that a
+// HeaderFormat, or one of the HEADER_FORMATS of config, recognizes, and
+// that format
+func findHeaderFormat(lines []string, config *Config) (int, HeaderFormat, bool) {
+	formats := append(append([]HeaderFormat(nil), HeaderFormats...), config.headerFormats()...)
+	for i := preambleLines(lines); i < len(lines) && i < headerSearchLines; i++ {
+		for _, format := range formats {
+			if format.Pattern.MatchString(lines[i]) {
+				return i, format, true
+			}
+		}
+	}
+	return -1, HeaderFormat{}, false
+}
+
+// DetectExistingHeader detects the header of a file from its first
+// headerScanBytes only, so large files are never read in full. Line numbers
+// are valid for the whole file.
+func DetectExistingHeader(filename string) (HeaderInfo, error) {
+	prefix, err := ReadFileForProcessing(filename, func([]byte) bool { return true })
+	if err != nil {
+		return HeaderInfo{}, err
+	}
+
+	prefix, _ = DecodeText(prefix)
+	return DetectHeaderInFile(filename, prefix), nil
+}
+
+// DetectHeaderInContent runs header detection on an in-memory copy of a file
+func DetectHeaderInContent(content []byte) HeaderInfo {
+	return detectHeader(content, "", nil)
+}
+
+// DetectHeaderInFile is DetectHeaderInContent for content read from
+// filename. The block comments of its language, such as CoffeeScript's
+// "###", bound a header too, and in a Python source so does a license
+// written as the module docstring.
+func DetectHeaderInFile(filename string, content []byte) HeaderInfo {
+	return detectHeader(content, filename, nil)
+}
+
+// isPythonSource reports whether filename is a Python module or stub,
+// whose module docstring may hold the license
+func isPythonSource(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return ext == ".py" || ext == ".pyi"
+}
+
+// detectHeader detects the header of content, read from filename and
+// processed with config if those are known
+func detectHeader(content []byte, filename string, config *Config) HeaderInfo {
+	_, content = SplitBOM(content)
+	lines := SplitLines(content)
+	
+	info := HeaderInfo{
+		HasHeader:              false,
+		HasThirdPartyCopyright: false,
+		StartLine:              -1,
+		EndLine:                -1,
+		HasShebang:             false,
+	}
+	
+	info.PreambleLines = preambleLines(lines)
+	info.HasShebang = info.PreambleLines > 0
+
+	lineNum := 0
+	
+	// Read first few lines to check for shebang and third-party copyright
+	var firstThreeLines []string
+	
+	// Check first line for shebang
+	if lineNum < len(lines) {
+		line := strings.TrimSpace(lines[lineNum])
+		firstThreeLines = append(firstThreeLines, line)
+		lineNum++
+		
+		// Check for SPDX identifier in first line (rare but possible)
+		if containsSPDXIdentifier(line) {
+			info.HasHeader = true
+			info.StartLine = lineNum - 1 // 0-based
+			info.LicenseID = extractLicenseID(line)
+		}
+	}
+	
+	// Read next two lines for third-party copyright detection
+	for i := 0; i < 2 && lineNum < len(lines); i++ {
+		line := strings.TrimSpace(lines[lineNum])
+		firstThreeLines = append(firstThreeLines, line)
+		lineNum++
+		
+		if containsSPDXIdentifier(line) {
+			info.HasHeader = true
+			if info.StartLine == -1 {
+				info.StartLine = findHeaderStart(lines, lineNum)
+			}
+			info.EndLine = lineNum - 1 // 0-based, this line contains SPDX
+			if info.LicenseID == "" {
+				info.LicenseID = extractLicenseID(line)
+			}
+		}
+	}
+	
+	// Check for third-party copyright in first 3 lines (excluding SPDX headers)
+	if !info.HasHeader {
+		for _, line := range firstThreeLines {
+			if isCopyrightLine(line) {
+				info.HasThirdPartyCopyright = true
+				break
+			}
+		}
+	}
+	
+	// Continue scanning for SPDX identifier in remaining lines
+	for lineNum < len(lines) && lineNum < headerSearchLines {
+		line := strings.TrimSpace(lines[lineNum])
+		lineNum++
+		
+		if containsSPDXIdentifier(line) {
+			info.HasHeader = true
+			info.HasThirdPartyCopyright = false // The copyright line belongs to this header
+			if info.StartLine == -1 {
+				// Find the start of the header block
+				info.StartLine = findHeaderStart(lines, lineNum)
+			}
+			info.EndLine = lineNum - 1 // 0-based, this line contains SPDX
+			if info.LicenseID == "" {
+				info.LicenseID = extractLicenseID(line)
+			}
+			break
+		}
+	}
+	
+	// Headers written by other tools, such as addlicense, may carry no SPDX
+	// identifier; a line distinctive for their template marks them instead
+	if !info.HasHeader {
+		if idx, format, ok := findHeaderFormat(lines, config); ok {
+			info.HasHeader = true
+			info.HasThirdPartyCopyright = false
+			info.Format = format.Name
+			info.LicenseID = format.LicenseID
+			info.StartLine = findHeaderStart(lines, idx+1)
+			info.EndLine = idx
+		}
+	}
+
+	// If we found a header, extend the end to include any following copyright/license lines
+	anchor := -1
+	if info.HasHeader {
+		anchor = info.EndLine
+		if anchor == -1 {
+			anchor = info.StartLine
+		}
+		info.EndLine = findHeaderEnd(lines, info.EndLine)
+	} else if info.HasThirdPartyCopyright {
+		// For third-party copyright, find the end of the license block
+		info.StartLine, info.EndLine = findThirdPartyCopyrightBlock(lines)
+		anchor = info.StartLine
+	}
+
+	// A header inside a multi-line block comment, or a Python module
+	// docstring that holds just the license, spans the whole block,
+	// delimiters included
+	if anchor >= 0 {
+		start, end, ok := enclosingBlockComment(lines, anchor, headerBlockComments(filename, content, config))
+		if !ok && isPythonSource(filename) {
+			start, end, ok = enclosingDocstring(lines, anchor)
+			if ok && !docstringOpensWithLicense(lines, start) {
+				info.Docstring = true
+				ok = false
+			}
+		}
+		if ok {
+			info.BlockComment = true
+			if start < info.StartLine {
+				info.StartLine = start
+			}
+			if end > info.EndLine {
+				info.EndLine = end
+			}
+		}
+	}
+	
+	return info
+}
+
+// DetectHeaderForConfig is DetectHeaderInFile for a file processed with
+// config, whose HEADER_FORMATS are detected too. With DETECT_THIRD_PARTY:
+// false a copyright notice without SPDX identifier is not taken for a
+// third-party header, so a file is either headered or not.
+func DetectHeaderForConfig(filename string, content []byte, config *Config) HeaderInfo {
+	info := detectHeader(content, filename, config)
+	if info.HasThirdPartyCopyright && !config.DetectsThirdParty() {
+		info.HasThirdPartyCopyright = false
+		info.StartLine, info.EndLine = -1, -1
+		info.BlockComment = false
+		info.Docstring = false
+	}
+	return info
+}
+
+// Block comments whose body lines need not carry a comment prefix of their
+// own, so a header inside them can only be bounded by locating the
+// delimiters. Slicing by line would otherwise leave a dangling opener or
+// closer behind on --force and --remove.
+var blockCommentDelimiters = []blockDelimiter{
+	{"<!--", "-->"},
+	{"/*", "*/"},
+	{"{-", "-}"},
+}
+
+type blockDelimiter struct {
+	start string
+	end   string
+}
+
+// coffeeBlockComment is the block comment of CoffeeScript. It is only
+// bounded in CoffeeScript sources: in the other languages that comment with
+// "#", a "###" line is an ordinary comment, often a section heading.
+var coffeeBlockComment = blockDelimiter{"###", "###"}
+
+// headerBlockComments returns the block comments a header in filename may
+// sit in
+func headerBlockComments(filename string, content []byte, config *Config) []blockDelimiter {
+	if filename == "" {
+		return blockCommentDelimiters
+	}
+	if style, ok := getCommentStyleForContent(filename, content, config); ok && style.BlockStart == coffeeBlockComment.start {
+		return append([]blockDelimiter{coffeeBlockComment}, blockCommentDelimiters...)
+	}
+	return blockCommentDelimiters
+}
+
+// enclosingBlockComment returns the first and last line of the multi-line
+// block comment of delimiters that contains line idx, if there is one.
+func enclosingBlockComment(lines []string, idx int, delimiters []blockDelimiter) (int, int, bool) {
+	for _, delim := range delimiters {
+		if delim.start == delim.end {
+			if start, end, ok := enclosingSymmetricBlock(lines, idx, delim.start); ok {
+				return start, end, true
+			}
+			continue
+		}
+
+		// Walk up to the opening delimiter; a closing delimiter on the way
+		// means idx is not inside a block
+		start := -1
+		for i := idx; i >= 0; i-- {
+			trimmed := strings.TrimSpace(lines[i])
+			if strings.HasPrefix(trimmed, delim.start) {
+				start = i
+				break
+			}
+			if i < idx && strings.Contains(trimmed, delim.end) {
+				break
+			}
+		}
+		if start == -1 {
+			continue
+		}
+
+		// Walk down to the closing delimiter
+		for j := start; j < len(lines); j++ {
+			text := lines[j]
+			if j == start {
+				text = text[strings.Index(text, delim.start)+len(delim.start):]
+			}
+			if strings.Contains(text, delim.end) {
+				if j > start && j >= idx {
+					return start, j, true
+				}
+				break
+			}
+		}
+	}
+
+	return -1, -1, false
+}
+
+// enclosingSymmetricBlock is enclosingBlockComment for a block comment
+// that opens and closes with the same delimiter line, such as "###". An
+// opener looks like a closer, so the delimiter lines are paired from the
+// top of the file. A line that also ends in the delimiter is a one-line
+// block, and one that continues with more of its last character, such as
+// "####", an ordinary comment.
+func enclosingSymmetricBlock(lines []string, idx int, delim string) (int, int, bool) {
+	open := -1
+	for i := 0; i < len(lines) && (i <= idx || open != -1); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(trimmed, delim) || strings.HasPrefix(trimmed[len(delim):], delim[len(delim)-1:]) {
+			continue
+		}
+		if open == -1 {
+			if !strings.Contains(trimmed[len(delim):], delim) {
+				open = i
+			}
+			continue
+		}
+		if open < idx && i >= idx {
+			return open, i, true
+		}
+		open = -1
+	}
+	return -1, -1, false
+}
+
+// docstringQuotes open and close a Python docstring
+var docstringQuotes = []string{`"""`, `'''`}
+
+// enclosingDocstring returns the first and last line of the module
+// docstring, when line idx is inside it. That is a triple-quoted string
+// opening the file after the preamble and any comments, as in a license
+// written as the docstring. Its body lines carry no comment marker, so
+// like a block comment it can only be bounded by its quotes.
+func enclosingDocstring(lines []string, idx int) (int, int, bool) {
+	start := preambleLines(lines)
+	for start < len(lines) && start < idx && (strings.TrimSpace(lines[start]) == "" || isCommentLine(lines[start])) {
+		start++
+	}
+	if start >= len(lines) || start > idx {
+		return -1, -1, false
+	}
+
+	// String prefixes such as r"""...""" or u"""..."""
+	opening := strings.TrimLeft(strings.TrimSpace(lines[start]), "rRuU")
+	for _, quote := range docstringQuotes {
+		if !strings.HasPrefix(opening, quote) {
+			continue
+		}
+		if strings.Contains(opening[len(quote):], quote) {
+			return start, start, start == idx
+		}
+		for end := start + 1; end < len(lines); end++ {
+			if strings.Contains(lines[end], quote) {
+				return start, end, idx <= end
+			}
+		}
+	}
+	return -1, -1, false
+}
+
+// docstringOpensWithLicense reports whether the docstring starting on line
+// start opens with a copyright notice or SPDX tag. Anything else, such as
+// the summary line PEP 257 puts first, documents the module, and removing
+// the docstring would remove that documentation from __doc__.
+func docstringOpensWithLicense(lines []string, start int) bool {
+	text := strings.TrimLeft(strings.TrimSpace(lines[start]), "rRuU")
+	for _, quote := range docstringQuotes {
+		text = strings.TrimPrefix(text, quote)
+	}
+	for i := start + 1; strings.TrimSpace(text) == "" && i < len(lines); i++ {
+		text = lines[i]
+	}
+	text = strings.TrimSpace(text)
+	return isCopyrightLine(text) || strings.HasPrefix(strings.ToLower(text), "spdx-")
+}
+
+// HeaderCopyrightLine returns the first copyright line of the header or
+// third-party block that info found in content, without comment markers,
+// or "" when the block names no copyright holder (e.g. a bare SPDX line)
+func HeaderCopyrightLine(content []byte, info HeaderInfo) string {
+	if (!info.HasHeader && !info.HasThirdPartyCopyright) || info.StartLine < 0 {
+		return ""
+	}
+	_, content = SplitBOM(content)
+	lines := SplitLines(content)
+	for i := info.StartLine; i <= info.EndLine && i < len(lines); i++ {
+		if isCopyrightLine(lines[i]) {
+			return strings.TrimSpace(uncommentLine(lines[i]))
+		}
+	}
+	return ""
+}
+
+// SplitLines splits content the way bufio.Scanner would: no trailing empty
+// element after a final newline and no trailing carriage returns.
+func SplitLines(content []byte) []string {
+	text := string(content)
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// isShebangLine reports whether line has to stay the first line of the
+// file: a real shebang, or a TeX magic comment (%!TEX) or Emacs mode line
+// (% -*- ... -*-) that editors only honor on line one.
+func isShebangLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "#!") {
+		return true
+	}
+
+	if strings.HasPrefix(trimmed, "%") {
+		directive := strings.TrimSpace(strings.TrimPrefix(trimmed, "%"))
+		return strings.HasPrefix(strings.ToUpper(directive), "!TEX") || strings.HasPrefix(directive, "-*-")
+	}
+
+	return false
+}
+
+// copyrightLinePattern matches a genuine copyright notice: an optional
+// comment marker, an optional (c) or ©, then "Copyright" (or the
+// Copyright-Holder/Copyright: metadata forms) followed by a year, (c), ©,
+// "the", "by" or "contributors". Mentions such as "Copyright notice: do
+// not remove" or "see the COPYRIGHT file" don't match.
+var copyrightLinePattern = regexp.MustCompile(`(?i)^\s*(?:(?://+|#+|/\*+|\*+|<!--|--|;+|%+|\(\*|rem\s|!+|"{1,3}|'{3})\s*)?(?:(?:\(c\)|©)\s*)?copyright(?:[- ]holders?)?\s*(?::|\(c\)|©|\d{4}|the\b|by\b|contributors\b)`)
+
+// isCopyrightLine reports whether line is a copyright notice rather than a
+// passing mention of the word
+func isCopyrightLine(line string) bool {
+	return copyrightLinePattern.MatchString(line)
+}
+
+// dockerDirectivePattern matches a Dockerfile parser directive. BuildKit
+// only honors directives before the first comment, blank line or
+// instruction, so a header must go below them.
+var dockerDirectivePattern = regexp.MustCompile(`(?i)^#\s*(syntax|escape|check)\s*=\s*\S+$`)
+
+// powershellRequiresPattern matches a PowerShell #Requires statement,
+// which authors keep at the top of a script
+var powershellRequiresPattern = regexp.MustCompile(`(?i)^#requires\s+-`)
+
+// gherkinLanguagePattern matches the "# language: de" line of a Gherkin
+// feature, which Cucumber only reads on the first line
+var gherkinLanguagePattern = regexp.MustCompile(`(?i)^#\s*language\s*:\s*\S+$`)
+
+// tclExecPattern matches the exec line of the Tcl trampoline, a shell
+// script that restarts itself under tclsh; see isTclTrampoline
+var tclExecPattern = regexp.MustCompile(`^exec\s+\S*(tclsh|wish|expect)[\d.]*\s`)
+
+// preambleLines returns how many leading lines must stay at the top of the
+// file, ahead of any header: a shebang (or TeX/Emacs first-line comment),
+// a batch "@echo off" (so the header's REM lines aren't echoed), a Gherkin
+// "# language:" line, a Tcl trampoline, or a run of Dockerfile parser
+// directives or PowerShell #Requires statements
+func preambleLines(lines []string) int {
+	if len(lines) == 0 {
+		return 0
+	}
+	first := strings.TrimSpace(lines[0])
+	if isShebangLine(first) && isTclTrampoline(lines[1:]) {
+		return 3
+	}
+	if isShebangLine(first) || strings.EqualFold(first, "@echo off") || gherkinLanguagePattern.MatchString(first) {
+		return 1
+	}
+
+	for _, pattern := range []*regexp.Regexp{dockerDirectivePattern, powershellRequiresPattern} {
+		n := 0
+		for n < len(lines) && pattern.MatchString(strings.TrimSpace(lines[n])) {
+			n++
+		}
+		if n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// isTclTrampoline reports whether lines, following a /bin/sh shebang, are
+// the comment ending in a backslash and the exec line that restart a
+// script under tclsh. Tcl continues the comment onto the exec line, so the
+// two must stay together, ahead of the header.
+func isTclTrampoline(lines []string) bool {
+	if len(lines) < 2 {
+		return false
+	}
+	comment := strings.TrimSpace(lines[0])
+	return strings.HasPrefix(comment, "#") && strings.HasSuffix(comment, "\\") &&
+		tclExecPattern.MatchString(strings.TrimSpace(lines[1]))
+}
+
+func containsSPDXIdentifier(line string) bool {
+	return strings.Contains(strings.ToLower(line), "spdx-license-identifier")
+}
+
+// extractLicenseID returns the SPDX license expression from an
+// SPDX-License-Identifier line, without the comment markers around it
+func extractLicenseID(line string) string {
+	idx := strings.Index(strings.ToLower(line), "spdx-license-identifier")
+	if idx == -1 {
+		return ""
+	}
+
+	id := strings.TrimLeft(line[idx+len("spdx-license-identifier"):], ": \t")
+	for _, closer := range []string{"*/", "-->", "*)", "#>", "=#", "--]]", `"""`, `'''`} {
+		id = strings.TrimSuffix(strings.TrimSpace(id), closer)
+	}
+	return strings.TrimSpace(id)
+}
+
+// findHeaderStart returns the first line of the comment block containing
+// the SPDX identifier on (1-based) spdxLine: the block is followed upwards
+// to the first line that is not a comment, or to the end of the preamble.
+func findHeaderStart(lines []string, spdxLine int) int {
+	startLine := preambleLines(lines)
+	
+	for i := spdxLine - 2; i >= startLine; i-- { // spdxLine is 1-based, array is 0-based
+		if i >= len(lines) {
+			continue
+		}
+		if !isCommentLine(lines[i]) {
+			// Found non-header line, start is after this
+			return i + 1
+		}
+	}
+	
+	return startLine
+}
+
+// findHeaderEnd returns the last line of the comment block containing the
+// SPDX identifier on (0-based) spdxLine, wherever in the block that line
+// sits. Blank separators written as just the comment marker ("//", "#",
+// " *", "REM") are comment lines and stay in the block, so a
+// multi-paragraph header like the Apache one is bounded as a whole; an
+// empty line or code ends it.
+func findHeaderEnd(lines []string, spdxLine int) int {
+	endLine := spdxLine
+	
+	for lineNum := spdxLine + 1; lineNum < len(lines); lineNum++ {
+		if !isCommentLine(lines[lineNum]) {
+			break
+		}
+		endLine = lineNum
+	}
+	
+	return endLine
+}
+
+// isCommentLine reports whether line starts with a comment marker. A line
+// holding only the marker, like the blank separator lines in a header,
+// counts; an empty line does not.
+func isCommentLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return false
+	}
+
+	// Unambiguous comment prefixes
+	commentPrefixes := []string{"//", "#", "/*", "*", ";;", "--", "<!--", "(*", "<#"}
+
+	for _, prefix := range commentPrefixes {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+
+	// Ambiguous prefixes (Fortran "C"/"!", Batch "REM", Vim "\"", Erlang "%",
+	// assembler ";"): require trailing whitespace so ordinary code such as
+	// `Config = ...`, `"""docstring` or `!important` is never mistaken for a
+	// comment.
+	ambiguousPrefixes := []string{"REM", "C", "!", "%", "\"", ";"}
+
+	for _, prefix := range ambiguousPrefixes {
+		if trimmed == prefix || strings.HasPrefix(trimmed, prefix+" ") || strings.HasPrefix(trimmed, prefix+"\t") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func findThirdPartyCopyrightBlock(lines []string) (int, int) {
+	startLine := -1
+	endLine := -1
+	
+	preamble := preambleLines(lines)
+	for lineNum, raw := range lines {
+		line := strings.TrimSpace(raw)
+		lineLower := strings.ToLower(line)
+		
+		// Skip shebang and other preamble lines if present
+		if lineNum < preamble {
+			continue
+		}
+		
+		// If we haven't found the start yet, look for copyright
+		if startLine == -1 && isCopyrightLine(line) {
+			startLine = lineNum // 0-based
+		}
+		
+		// If we have a start, look for the end of license text.
+		// Only comment lines, blank lines, and lines with strong license
+		// phrases extend the block - generic words like "use" or "software"
+		// would swallow real code (e.g. `use std::io;`) under --force.
+		if startLine != -1 {
+			if isCommentLine(raw) ||
+			   line == "" ||
+			   strings.Contains(lineLower, "copyright") ||
+			   strings.Contains(lineLower, "permission") ||
+			   strings.Contains(lineLower, "licens") ||
+			   strings.Contains(lineLower, "warrant") ||
+			   strings.Contains(lineLower, "liabilit") ||
+			   strings.Contains(lineLower, "redistribut") ||
+			   strings.Contains(lineLower, "merchantab") ||
+			   strings.Contains(lineLower, "damages") {
+				endLine = lineNum // 0-based, continue expanding
+			} else {
+				// Found non-license content, end the block
+				break
+			}
+		}
+	}
+	
+	// If we found a start but no clear end, assume it goes to the end of license text we saw
+	if startLine != -1 && endLine == -1 {
+		endLine = startLine // Minimal block
+	}
+	
+	return startLine, endLine
+}
+
+// shebangInterpreter returns the name of the interpreter on a shebang line,
+// or "" if line is not a shebang. `/usr/bin/env` and its options are
+// followed, and version suffixes are dropped (python3.11 -> python).
+func shebangInterpreter(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "#!") {
+		return ""
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(trimmed, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for i := 1; i < len(fields); i++ {
+			field := fields[i]
+			if field == "-u" || field == "--unset" {
+				i++ // skip the variable name
+				continue
+			}
+			if strings.HasPrefix(field, "-") || strings.Contains(field, "=") {
+				continue // env options (-S, -i) and VAR=value assignments
+			}
+			interpreter = filepath.Base(field)
+			break
+		}
+	}
+	return strings.TrimRight(interpreter, "0123456789.")
+}
+
+func HasShebang(filename string) (bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	
+	scanner := bufio.NewScanner(file)
+	if scanner.Scan() {
+		_, first := SplitBOM(scanner.Bytes())
+		firstLine := strings.TrimSpace(string(first))
+		return isShebangLine(firstLine), nil
+	}
+	
+	return false, scanner.Err()
+}
\ No newline at end of file
@@ -0,0 +1,29 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+// Package licer generates, detects and rewrites SPDX copyright headers. It
+// is the core of the licer command and can be used by other tools, such as
+// linters, that want the same header rules without running the CLI.
+//
+// A Config describes the copyright holder. GenerateHeaderForFile renders
+// the header for a file, formatted with the CommentStyle that
+// GetCommentStyle picks for it. DetectHeaderInContent, DetectHeaderInFile
+// and DetectExistingHeader locate an existing header. ProcessContent applies
+// the same add, replace and remove logic as the CLI to content held in
+// memory, and ProcessFileWithOptions does the same for a file on disk.
+// Both take EXCLUDE_EXTENSIONS, INCLUDE_EXTENSIONS, LINE_COMMENTS and
+// HEADER_FORMATS from the Config they are given, so several configs can be
+// used in one process.
+//
+//	config := &licer.Config{FullName: "Jane Doe", DefaultRole: "Staff", Organization: "Example University"}
+//	updated, result := licer.ProcessContent("main.go", content, config, licer.ProcessOptions{})
+//	if result.Action == "ADD" {
+//		// updated holds content with the new header
+//	}
+package licer
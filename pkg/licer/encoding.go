@@ -0,0 +1,128 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// TextEncoding is how a file's text is stored on disk. Everything except
+// BOM-prefixed UTF-16 is treated as UTF-8 (or a compatible 8-bit encoding),
+// unless Latin-1 is asked for (--encoding latin1).
+type TextEncoding int
+
+const (
+	EncodingUTF8 TextEncoding = iota
+	EncodingUTF16LE
+	EncodingUTF16BE
+	EncodingLatin1
+)
+
+// ParseEncoding returns the encoding --encoding names: utf-8 (the default,
+// with UTF-16 recognized by its byte order mark) or latin1
+func ParseEncoding(name string) (TextEncoding, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "utf-8", "utf8":
+		return EncodingUTF8, nil
+	case "latin1", "latin-1", "iso-8859-1", "iso8859-1":
+		return EncodingLatin1, nil
+	}
+	return EncodingUTF8, fmt.Errorf("unsupported encoding %q, must be utf-8 or latin1", name)
+}
+
+// detectEncoding recognizes UTF-16 files by their byte order mark
+func detectEncoding(content []byte) TextEncoding {
+	switch {
+	case bytes.HasPrefix(content, utf16LEBOM):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(content, utf16BEBOM):
+		return EncodingUTF16BE
+	default:
+		return EncodingUTF8
+	}
+}
+
+// DecodeText converts UTF-16 content to UTF-8 without its byte order mark
+// so the detector and header writer can work on it; other content is
+// returned unchanged. A trailing odd byte, as left by a cut-off prefix, is
+// dropped.
+func DecodeText(content []byte) ([]byte, TextEncoding) {
+	enc := detectEncoding(content)
+	if enc == EncodingUTF8 {
+		return content, enc
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if enc == EncodingUTF16BE {
+		order = binary.BigEndian
+	}
+
+	body := content[2:]
+	units := make([]uint16, len(body)/2)
+	for i := range units {
+		units[i] = order.Uint16(body[2*i:])
+	}
+	return []byte(string(utf16.Decode(units))), enc
+}
+
+// decodeLatin1 converts Latin-1 content to UTF-8, one rune per byte
+func decodeLatin1(content []byte) []byte {
+	runes := make([]rune, len(content))
+	for i, b := range content {
+		runes[i] = rune(b)
+	}
+	return []byte(string(runes))
+}
+
+// encodeLatin1 is the inverse of decodeLatin1. It fails on characters
+// Latin-1 has no byte for, e.g. in a configured name, rather than writing
+// them as UTF-8 into a Latin-1 file.
+func encodeLatin1(content []byte) ([]byte, error) {
+	out := make([]byte, 0, len(content))
+	for _, r := range string(content) {
+		if r > 0xFF {
+			return nil, fmt.Errorf("%q cannot be written in latin1", r)
+		}
+		out = append(out, byte(r))
+	}
+	return out, nil
+}
+
+// EncodeText is the inverse of DecodeText: UTF-16 output gets its byte
+// order mark back in front
+func EncodeText(content []byte, enc TextEncoding) []byte {
+	if enc == EncodingUTF8 {
+		return content
+	}
+
+	var order binary.AppendByteOrder = binary.LittleEndian
+	bom := utf16LEBOM
+	if enc == EncodingUTF16BE {
+		order = binary.BigEndian
+		bom = utf16BEBOM
+	}
+
+	out := make([]byte, 0, len(bom)+2*utf8.RuneCount(content))
+	out = append(out, bom...)
+	for _, unit := range utf16.Encode([]rune(string(content))) {
+		out = order.AppendUint16(out, unit)
+	}
+	return out
+}
@@ -0,0 +1,602 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type CommentStyle struct {
+	Line       string
+	BlockStart string
+	BlockEnd   string
+}
+
+var CommentStyles = map[string]CommentStyle{
+	".go":       {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".py":       {Line: "#"},
+	".sh":       {Line: "#"},
+	".rb":       {Line: "#"},
+	".js":       {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".jsonc":    {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".json5":    {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".mjs":      {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".cjs":      {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".ts":       {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".tsx":      {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".jsx":      {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".coffee":   {Line: "#", BlockStart: "###", BlockEnd: "###"},
+	".html":     {Line: "<!--", BlockStart: "<!--", BlockEnd: "-->"},
+	".htm":      {Line: "<!--", BlockStart: "<!--", BlockEnd: "-->"},
+	".md":       {Line: "<!--", BlockStart: "<!--", BlockEnd: "-->"},
+	".vue":      {BlockStart: "<!--", BlockEnd: "-->"},
+	".svelte":   {BlockStart: "<!--", BlockEnd: "-->"},
+	".css":      {Line: "/*", BlockStart: "/*", BlockEnd: "*/"},
+	".scss":     {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".sass":     {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".less":     {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".java":     {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".c":        {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".cpp":      {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".cc":       {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".cxx":      {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".h":        {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".hpp":      {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".rs":       {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".swift":    {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".kt":       {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".scala":    {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".cs":       {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".yaml":     {Line: "#"},
+	".yml":      {Line: "#"},
+	".toml":     {Line: "#"},
+	".ini":      {Line: "#"},
+	".cfg":      {Line: "#"},
+	".conf":     {Line: "#"},
+	".sql":      {Line: "--", BlockStart: "/*", BlockEnd: "*/"},
+	".lua":      {Line: "--", BlockStart: "--[[", BlockEnd: "--]]"},
+	".tcl":      {Line: "#"},
+	".awk":      {Line: "#"},
+	".sed":      {Line: "#"},
+	".r":        {Line: "#"},
+	".R":        {Line: "#"},
+	".rmd":      {Line: "<!--", BlockStart: "<!--", BlockEnd: "-->"},
+	".Rmd":      {Line: "<!--", BlockStart: "<!--", BlockEnd: "-->"},
+	".m":        {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".mm":       {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".vim":      {Line: "\""},
+	".vimrc":    {Line: "\""},
+	".el":       {Line: ";;"},
+	".lisp":     {Line: ";;"},
+	".lsp":      {Line: ";;"},
+	".clj":      {Line: ";;"},
+	".cljs":     {Line: ";;"},
+	".hs":       {Line: "--", BlockStart: "{-", BlockEnd: "-}"},
+	".lhs":      {Line: "--", BlockStart: "{-", BlockEnd: "-}"},
+	".elm":      {Line: "--", BlockStart: "{-", BlockEnd: "-}"},
+	".purs":     {Line: "--", BlockStart: "{-", BlockEnd: "-}"},
+	".ml":       {Line: "(*", BlockStart: "(*", BlockEnd: "*)"},
+	".mli":      {Line: "(*", BlockStart: "(*", BlockEnd: "*)"},
+	".pas":      {Line: "//", BlockStart: "{", BlockEnd: "}"},
+	".pl":       {Line: "#"},
+	".pm":       {Line: "#"},
+	".php":      {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".sol":      {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".dart":     {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".f":        {Line: "C", BlockStart: "C", BlockEnd: "C"},
+	".f90":      {Line: "!", BlockStart: "!", BlockEnd: "!"},
+	".f95":      {Line: "!", BlockStart: "!", BlockEnd: "!"},
+	".jl":       {Line: "#", BlockStart: "#=", BlockEnd: "=#"},
+	".zig":      {Line: "//"},
+	".nim":      {Line: "#", BlockStart: "#[", BlockEnd: "]#"},
+	".cr":       {Line: "#"},
+	".d":        {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".ex":       {Line: "#"},
+	".exs":      {Line: "#"},
+	".erl":      {Line: "%"},
+	".hrl":      {Line: "%"},
+	".fs":       {Line: "//", BlockStart: "(*", BlockEnd: "*)"},
+	".fsx":      {Line: "//", BlockStart: "(*", BlockEnd: "*)"},
+	".fsi":      {Line: "//", BlockStart: "(*", BlockEnd: "*)"},
+	".v":        {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".vv":       {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".proto":    {Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".graphql":  {Line: "#"},
+	".gql":      {Line: "#"},
+	".feature":  {Line: "#"},
+	".cucumber": {Line: "#"},
+	".tex":      {Line: "%"},
+	".sty":      {Line: "%"},
+	".cls":      {Line: "%"},
+	".bib":      {Line: "%"},
+	".bat":      {Line: "REM"},
+	".cmd":      {Line: "REM"},
+	".ps1":      {Line: "#", BlockStart: "<#", BlockEnd: "#>"},
+	".psm1":     {Line: "#", BlockStart: "<#", BlockEnd: "#>"},
+	".asm":      {Line: ";"},
+	".s":        {Line: "#"}, // GNU as, .S too (extensions are lowercased); LINE_COMMENTS sets ";" or "//" for other assemblers
+	"":          {Line: "#"}, // No extension = shell script
+}
+
+// Extensionless files that must never receive headers: license and notice
+// files are legal documents, not source code.
+var ExcludedBasenames = map[string]bool{
+	"LICENSE":      true,
+	"LICENCE":      true,
+	"COPYING":      true,
+	"COPYRIGHT":    true,
+	"NOTICE":       true,
+	"AUTHORS":      true,
+	"CONTRIBUTORS": true,
+	"PATENTS":      true,
+	"CHANGELOG":    true,
+	"VERSION":      true,
+	"DEP5":         true, // REUSE's .reuse/dep5 copyright file
+}
+
+// licenseFileStems are license documents that are also skipped under a
+// variant name such as LICENSE-MIT, LICENSE.rst or COPYING.LESSER, when
+// spelled in capitals as license files conventionally are (a lowercase
+// license.py is source code)
+var licenseFileStems = map[string]bool{
+	"LICENSE":   true,
+	"LICENCE":   true,
+	"UNLICENSE": true,
+	"COPYING":   true,
+	"COPYRIGHT": true,
+	"NOTICE":    true,
+	"PATENTS":   true,
+}
+
+func isExcludedBasename(filename string) bool {
+	base := filepath.Base(filename)
+	if ExcludedBasenames[strings.ToUpper(base)] {
+		return true
+	}
+
+	stem := base
+	if i := strings.IndexAny(base, ".-_"); i > 0 {
+		stem = base[:i]
+	}
+	return licenseFileStems[stem]
+}
+
+// AlwaysExcludedExtensions are binary, archive and media formats, which
+// never get a header; --include-ext and INCLUDE_EXTENSIONS cannot opt them in
+var AlwaysExcludedExtensions = map[string]bool{
+	".pdf":     true,
+	".doc":     true,
+	".docx":    true,
+	".xls":     true,
+	".xlsx":    true,
+	".ppt":     true,
+	".pptx":    true,
+	".zip":     true,
+	".tar":     true,
+	".gz":      true,
+	".bz2":     true,
+	".xz":      true,
+	".7z":      true,
+	".rar":     true,
+	".png":     true,
+	".jpg":     true,
+	".jpeg":    true,
+	".gif":     true,
+	".bmp":     true,
+	".tiff":    true,
+	".ico":     true,
+	".mp3":     true,
+	".mp4":     true,
+	".avi":     true,
+	".mov":     true,
+	".mkv":     true,
+	".wav":     true,
+	".flac":    true,
+	".exe":     true,
+	".dll":     true,
+	".so":      true,
+	".dylib":   true,
+	".a":       true,
+	".lib":     true,
+	".obj":     true,
+	".o":       true,
+	".class":   true,
+	".jar":     true,
+	".war":     true,
+	".ear":     true,
+	".pyc":     true,
+	".pyo":     true,
+	".pyd":     true,
+	".whl":     true,
+	".egg":     true,
+	".deb":     true,
+	".rpm":     true,
+	".msi":     true,
+	".dmg":     true,
+	".iso":     true,
+	".img":     true,
+	".license": true, // REUSE sidecar files carry another file's header
+}
+
+// DefaultExcludedExtensions are text formats licer skips unless they are
+// opted in with --include-ext or INCLUDE_EXTENSIONS, e.g. .md for projects
+// that license their documentation. Those without a comment style (.json,
+// .txt) are skipped either way.
+var DefaultExcludedExtensions = map[string]bool{
+	".md":    true,
+	".txt":   true,
+	".json":  true,
+	".jsonc": true,
+	".json5": true,
+	".xml":   true,
+	".csv":   true,
+	".tsv":   true,
+	".log":   true,
+	".out":   true,
+	".svg":   true,
+}
+
+// Per-run overrides of DefaultExcludedExtensions set by --exclude-ext and
+// --include-ext. EXCLUDE_EXTENSIONS and INCLUDE_EXTENSIONS of a Config
+// override them the same way for the files processed with it. An extension
+// in both lists stays excluded.
+var (
+	RuntimeExcluded = map[string]bool{}
+	runtimeIncluded = map[string]bool{}
+)
+
+// SetExtensionOverrides replaces the per-run extension overrides. It must
+// be called before processing starts since workers read the maps
+// concurrently.
+func SetExtensionOverrides(exclude, include []string) {
+	RuntimeExcluded = make(map[string]bool)
+	runtimeIncluded = make(map[string]bool)
+
+	for _, ext := range exclude {
+		RuntimeExcluded[NormalizeExtension(ext)] = true
+	}
+	for _, ext := range include {
+		runtimeIncluded[NormalizeExtension(ext)] = true
+	}
+}
+
+// containsExtension reports whether exts, as written in a config, name ext
+func containsExtension(exts []string, ext string) bool {
+	for _, e := range exts {
+		if NormalizeExtension(e) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeExtension lowercases ext and adds the leading dot if missing
+func NormalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+func IsExcludedExtension(ext string) bool {
+	return isExcludedExtension(ext, nil)
+}
+
+// isExcludedExtension is IsExcludedExtension for the files processed with
+// config, which may be nil
+func isExcludedExtension(ext string, config *Config) bool {
+	if AlwaysExcludedExtensions[ext] || RuntimeExcluded[ext] || config.excludesExtension(ext) {
+		return true
+	}
+	if runtimeIncluded[ext] || config.includesExtension(ext) {
+		return false
+	}
+	return DefaultExcludedExtensions[ext]
+}
+
+func GetCommentStyle(filename string) (CommentStyle, bool) {
+	return getCommentStyle(filename, func() []byte { return readFileHead(filename) }, nil)
+}
+
+// getCommentStyleForContent is GetCommentStyle for an in-memory file
+// processed with config
+func getCommentStyleForContent(filename string, content []byte, config *Config) (CommentStyle, bool) {
+	return getCommentStyle(filename, func() []byte { return content }, config)
+}
+
+// getCommentStyle looks the style up by extension. Extensionless files are
+// only read (through head) to pick the style from the shebang interpreter.
+func getCommentStyle(filename string, head func() []byte, config *Config) (CommentStyle, bool) {
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	// Check if file should be excluded
+	if isExcludedExtension(ext, config) || isExcludedBasename(filename) {
+		return CommentStyle{}, false
+	}
+	
+	// Extensionless scripts take their style from the shebang interpreter
+	if ext == "" {
+		if style, ok := shebangCommentStyle(head()); ok {
+			return style, true
+		}
+	}
+
+	// Get comment style
+	style, exists := CommentStyles[ext]
+	if !exists {
+		return CommentStyle{}, false
+	}
+	if marker, ok := config.lineComment(ext); ok {
+		style = CommentStyle{Line: marker}
+	}
+	
+	return style, true
+}
+
+// lineCommentExtensions are the extensions LINE_COMMENTS may set a marker
+// for: assembler sources, whose comment syntax depends on the assembler.
+// Every other language has one comment syntax, and a marker it doesn't
+// know would turn the header into code.
+var lineCommentExtensions = map[string]bool{".asm": true, ".s": true}
+
+// lineCommentMarkers are the markers LINE_COMMENTS may choose from; header
+// detection and removal know all of them
+var lineCommentMarkers = map[string]bool{"#": true, ";": true, ";;": true, "//": true, "--": true, "%": true, "!": true}
+
+func ShouldProcessFile(filename string) bool {
+	return shouldProcess(filename, func() bool { return isTextFile(filename) }, nil)
+}
+
+// ShouldProcessContent is ShouldProcessFile for an in-memory file
+func ShouldProcessContent(filename string, content []byte) bool {
+	return ShouldProcessContentForConfig(filename, content, nil)
+}
+
+// ShouldProcessContentForConfig is ShouldProcessContent for a file
+// processed with config, whose EXCLUDE_EXTENSIONS and INCLUDE_EXTENSIONS
+// apply
+func ShouldProcessContentForConfig(filename string, content []byte, config *Config) bool {
+	return shouldProcess(filename, func() bool { return isTextContent(content) }, config)
+}
+
+// skipReason explains why shouldProcess rejected filename. Text files whose
+// extension has no known comment style may be source code in a language
+// that is not configured yet, so they are told apart from file types that
+// are excluded on purpose.
+func skipReason(filename string, isText func() bool, config *Config) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" || isExcludedExtension(ext, config) || isExcludedBasename(filename) {
+		return "Excluded file type"
+	}
+	if _, exists := CommentStyles[ext]; !exists && isText() {
+		return "No comment style available"
+	}
+	return "Excluded file type"
+}
+
+func shouldProcess(filename string, isText func() bool, config *Config) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	// Skip excluded extensions and license/notice files
+	if isExcludedExtension(ext, config) || isExcludedBasename(filename) {
+		return false
+	}
+	
+	// Skip if no comment style available
+	_, exists := CommentStyles[ext]
+	if !exists && ext != "" {
+		return false
+	}
+	
+	// For files with no extension, check if they're text files
+	if ext == "" {
+		return isText()
+	}
+	
+	return true
+}
+
+func isTextFile(filename string) bool {
+	return isTextContent(readFileHead(filename))
+}
+
+// readFileHead returns the first 512 bytes of a file, enough to sniff for
+// binary content and to read a shebang line. It returns nil on error.
+func readFileHead(filename string) []byte {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	
+	buffer := make([]byte, 512)
+	n, err := file.Read(buffer)
+	if err != nil && n == 0 {
+		return nil
+	}
+
+	return buffer[:n]
+}
+
+// shebangExtensions maps the interpreter named on a shebang line to the
+// extension whose comment style its scripts use. Interpreters not listed
+// here fall back to the "#" style of extensionless files.
+var shebangExtensions = map[string]string{
+	"sh":      ".sh",
+	"bash":    ".sh",
+	"dash":    ".sh",
+	"ksh":     ".sh",
+	"zsh":     ".sh",
+	"python":  ".py",
+	"perl":    ".pl",
+	"ruby":    ".rb",
+	"node":    ".js",
+	"deno":    ".ts",
+	"coffee":  ".coffee",
+	"php":     ".php",
+	"lua":     ".lua",
+	"tclsh":   ".tcl",
+	"wish":    ".tcl",
+	"awk":     ".awk",
+	"gawk":    ".awk",
+	"mawk":    ".awk",
+	"nawk":    ".awk",
+	"sed":     ".sed",
+	"Rscript": ".r",
+	"julia":   ".jl",
+}
+
+// shebangCommentStyle picks the comment style of an extensionless script
+// from the interpreter on its shebang line
+func shebangCommentStyle(content []byte) (CommentStyle, bool) {
+	_, content = SplitBOM(content)
+	firstLine, _, _ := strings.Cut(string(content), "\n")
+	ext, ok := shebangExtensions[shebangInterpreter(firstLine)]
+	if !ok {
+		return CommentStyle{}, false
+	}
+	return CommentStyles[ext], true
+}
+
+func isTextContent(data []byte) bool {
+	// Only the first 512 bytes are sniffed for binary content
+	if len(data) > 512 {
+		data = data[:512]
+	}
+	if len(data) == 0 {
+		return false
+	}
+
+	// UTF-16 text is full of null bytes; sniff it decoded instead
+	data, _ = DecodeText(data)
+
+	// Check for null bytes or too many non-printable characters
+	nullBytes := 0
+	nonPrintable := 0
+	
+	for _, b := range data {
+		if b == 0 {
+			nullBytes++
+		} else if !unicode.IsPrint(rune(b)) && !unicode.IsSpace(rune(b)) {
+			nonPrintable++
+		}
+	}
+	
+	// If more than 30% non-printable or any null bytes, likely binary
+	if nullBytes > 0 || float64(nonPrintable)/float64(len(data)) > 0.30 {
+		return false
+	}
+	
+	return true
+}
+
+// defaultBlockCommentPrefix starts the lines inside a /* */ header unless
+// BLOCK_COMMENT_PREFIX sets another one
+const defaultBlockCommentPrefix = " * "
+
+// blockCommentPrefix returns the configured BLOCK_COMMENT_PREFIX or the
+// default " * "
+func blockCommentPrefix(config *Config) string {
+	if config != nil && config.BlockCommentPrefix != "" {
+		return config.BlockCommentPrefix
+	}
+	return defaultBlockCommentPrefix
+}
+
+func FormatHeader(header string, style CommentStyle) string {
+	return formatHeader(header, style, defaultBlockCommentPrefix)
+}
+
+// formatHeaderForConfig is FormatHeader with the block comment prefix of
+// config
+func formatHeaderForConfig(header string, style CommentStyle, config *Config) string {
+	prefix := blockCommentPrefix(config)
+	if config != nil && config.HeaderWidth > 0 {
+		header = wrapHeader(header, config.HeaderWidth-commentOverhead(style, prefix))
+	}
+	return formatHeader(header, style, prefix)
+}
+
+// commentOverhead returns the columns the comment markers of style add to
+// a line of header text, e.g. 3 for "// " and 6 for "(* " and " *)"
+func commentOverhead(style CommentStyle, prefix string) int {
+	const probe = "\x00"
+	for _, line := range strings.Split(formatHeader(probe, style, prefix), "\n") {
+		if strings.Contains(line, probe) {
+			return utf8.RuneCountInString(line) - 1
+		}
+	}
+	return 0
+}
+
+func formatHeader(header string, style CommentStyle, prefix string) string {
+	lines := strings.Split(header, "\n")
+	var result []string
+	
+	// For CSS files, use block comments for better formatting. The closer
+	// is indented like the prefix, so " * " ends with " */".
+	if style.Line == "/*" && style.BlockStart == "/*" && style.BlockEnd == "*/" {
+		result = append(result, "/*")
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				result = append(result, strings.TrimRight(prefix, " \t"))
+			} else {
+				result = append(result, prefix+line)
+			}
+		}
+		indent := prefix[:len(prefix)-len(strings.TrimLeft(prefix, " \t"))]
+		result = append(result, indent+"*/")
+		return strings.Join(result, "\n")
+	}
+
+	// Single-file components (Vue, Svelte) mix markup, script and style, so
+	// the header goes in one markup comment block that is valid at the top
+	if style.Line == "" && style.BlockStart != "" {
+		result = append(result, style.BlockStart)
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				result = append(result, "")
+			} else {
+				result = append(result, "  "+line)
+			}
+		}
+		result = append(result, style.BlockEnd)
+		return strings.Join(result, "\n")
+	}
+
+	// Languages without a true line-comment form (HTML, OCaml): wrap every
+	// line as a complete block comment so each line is valid on its own.
+	if style.BlockEnd != "" && style.Line == style.BlockStart {
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				result = append(result, style.BlockStart+" "+style.BlockEnd)
+			} else {
+				result = append(result, style.BlockStart+" "+line+" "+style.BlockEnd)
+			}
+		}
+		return strings.Join(result, "\n")
+	}
+
+	// Use line comments for headers (more consistent)
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			result = append(result, style.Line)
+		} else {
+			result = append(result, style.Line+" "+line)
+		}
+	}
+	
+	return strings.Join(result, "\n")
+}
\ No newline at end of file
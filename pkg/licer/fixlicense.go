@@ -0,0 +1,111 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fixLicenseContent rewrites a header that is ours (ownership match) but
+// declares a different license than the configured one, keeping its year.
+// Unlike --force it never touches third-party headers or correct ones.
+func fixLicenseContent(filename string, content []byte, config *Config, templates map[string]string) ([]byte, ProcessResult) {
+	if !ShouldProcessContentForConfig(filename, content, config) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: skipReason(filename, func() bool { return isTextContent(content) }, config),
+		}
+	}
+
+	commentStyle, ok := getCommentStyleForContent(filename, content, config)
+	if !ok {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "No comment style available",
+		}
+	}
+
+	headerInfo := DetectHeaderForConfig(filename, content, config)
+	if !headerInfo.HasHeader {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "No header found",
+		}
+	}
+
+	if headerInfo.Docstring {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: sharedDocstringReason,
+		}
+	}
+
+	if !CanRemoveHeaderContent(content, headerInfo, config) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "Header ownership mismatch (safety check)",
+		}
+	}
+
+	want := GetLicenseType(config)
+	if strings.EqualFold(headerInfo.LicenseID, want) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "License already correct",
+		}
+	}
+
+	year := headerYear(content, headerInfo)
+	headerText := headerTextForFile(config, filename, strconv.Itoa(year), templates)
+	formattedHeader := formatHeaderForConfig(headerText, commentStyle, config)
+
+	return modifyContent(content, formattedHeader, headerInfo), ProcessResult{
+		Action:    "REPLACE",
+		Reason:    fmt.Sprintf("Fixed license %s -> %s (year %d kept)", headerInfo.LicenseID, want, year),
+		Modified:  true,
+		LicenseID: want,
+	}
+}
+
+// headerYear returns the first copyright year inside the detected header,
+// or the current year if it has none
+func headerYear(content []byte, headerInfo HeaderInfo) int {
+	_, body := SplitBOM(content)
+	lines := SplitLines(body)
+	for i := headerInfo.StartLine; i >= 0 && i <= headerInfo.EndLine && i < len(lines); i++ {
+		if found := copyrightYearPattern.FindString(lines[i]); found != "" {
+			year, _ := strconv.Atoi(found)
+			return year
+		}
+	}
+	return time.Now().Year()
+}
+
+// headerLatestYear returns the latest year on the first line of the
+// detected header that has one, e.g. 2025 for "Copyright 2019-2025"
+func headerLatestYear(content []byte, headerInfo HeaderInfo) (int, bool) {
+	_, body := SplitBOM(content)
+	lines := SplitLines(body)
+	for i := headerInfo.StartLine; i >= 0 && i <= headerInfo.EndLine && i < len(lines); i++ {
+		latest := 0
+		for _, found := range copyrightYearPattern.FindAllString(lines[i], -1) {
+			if year, _ := strconv.Atoi(found); year > latest {
+				latest = year
+			}
+		}
+		if latest > 0 {
+			return latest, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,334 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Languages whose toolchains expect the SPDX identifier on the very first
+// line (solc warns when it is missing there)
+var spdxFirstExtensions = map[string]bool{
+	".sol": true,
+}
+
+// GenerateHeaderForFile returns the header text for filename, adjusting the
+// generic GenerateHeader layout to the conventions of its language.
+func GenerateHeaderForFile(config *Config, filename string) string {
+	return generateHeaderForFileYear(config, filename, time.Now().Year())
+}
+
+func generateHeaderForFileYear(config *Config, filename string, year int) string {
+	return generateHeaderForFileYears(config, filename, strconv.Itoa(year))
+}
+
+// generateHeaderForFileYears is generateHeaderForFileYear with the copyright
+// years as text, e.g. a range from yearRange
+func generateHeaderForFileYears(config *Config, filename string, years string) string {
+	header := generateHeaderForYears(config, years)
+
+	if spdxFirstExtensions[strings.ToLower(filepath.Ext(filename))] {
+		header = moveSPDXLineFirst(header)
+	}
+
+	return header
+}
+
+// headerTextForFile returns the header text for filename dated years: its
+// entry in templates (--header-dir) when there is one, otherwise the
+// generated header
+func headerTextForFile(config *Config, filename string, years string, templates map[string]string) string {
+	template, ok := headerTemplateFor(filename, templates)
+	if !ok {
+		return generateHeaderForFileYears(config, filename, years)
+	}
+
+	header := fillHeaderTemplate(template, config, years)
+	if spdxFirstExtensions[strings.ToLower(filepath.Ext(filename))] {
+		header = moveSPDXLineFirst(header)
+	}
+	return header
+}
+
+// headerTemplateFor looks filename up in templates by its extension, or by
+// its base name when it has none (e.g. Dockerfile)
+func headerTemplateFor(filename string, templates map[string]string) (string, bool) {
+	if len(templates) == 0 {
+		return "", false
+	}
+	key := strings.TrimPrefix(NormalizeExtension(filepath.Ext(filename)), ".")
+	if key == "" {
+		key = strings.ToLower(filepath.Base(filename))
+	}
+	template, ok := templates[key]
+	return template, ok
+}
+
+// fillHeaderTemplate substitutes {year}, {spdx} and {owner} in template.
+// Without an SPDX line one is appended, so the header is detected and
+// removable like a generated one.
+func fillHeaderTemplate(template string, config *Config, years string) string {
+	license := GetLicenseType(config)
+	header := strings.NewReplacer("{year}", years, "{spdx}", license, "{owner}", copyrightOwner(config)).Replace(template)
+	if !containsSPDXIdentifier(header) {
+		header += "\n\nSPDX-License-Identifier: " + license
+	}
+	return header
+}
+
+// headerTemplatePrefix and headerTemplateSuffix frame the file type in the
+// names of --header-dir files, e.g. header.go.txt
+const (
+	headerTemplatePrefix = "header."
+	headerTemplateSuffix = ".txt"
+)
+
+// LoadHeaderTemplates reads the header.<ext>.txt files of dir, e.g.
+// header.go.txt or header.dockerfile.txt, keyed by the lowercase
+// extension or base name they apply to. Their text replaces the generated
+// header of those files, without comment markers.
+func LoadHeaderTemplates(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header directory: %w", err)
+	}
+
+	templates := map[string]string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, headerTemplatePrefix) || !strings.HasSuffix(name, headerTemplateSuffix) {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(name, headerTemplatePrefix), headerTemplateSuffix))
+		if key == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header file: %w", err)
+		}
+		text := strings.TrimRight(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+		if strings.TrimSpace(text) == "" {
+			return nil, fmt.Errorf("header file %s is empty", name)
+		}
+		templates[key] = text
+	}
+	return templates, nil
+}
+
+func moveSPDXLineFirst(header string) string {
+	var spdxLine string
+	var rest []string
+	for _, line := range strings.Split(header, "\n") {
+		if spdxLine == "" && containsSPDXIdentifier(line) {
+			spdxLine = line
+			continue
+		}
+		rest = append(rest, line)
+	}
+
+	if spdxLine == "" {
+		return header
+	}
+	return strings.Join(append([]string{spdxLine}, rest...), "\n")
+}
+
+func GenerateHeader(config *Config) string {
+	return generateHeaderForYears(config, strconv.Itoa(time.Now().Year()))
+}
+
+// yearRange formats the copyright years of a file first created in first:
+// "2019-2025", or just "2025" when both are the same year
+func yearRange(first, last int) string {
+	if first >= last {
+		return strconv.Itoa(last)
+	}
+	return fmt.Sprintf("%d-%d", first, last)
+}
+
+func generateHeaderForYears(config *Config, years string) string {
+	switch config.HeaderTemplate {
+	case HeaderTemplateMinimal:
+		return fmt.Sprintf("Copyright (c) %s %s\nSPDX-License-Identifier: %s", years, copyrightOwner(config), GetLicenseType(config))
+	case HeaderTemplateSPDXOnly:
+		return "SPDX-License-Identifier: " + GetLicenseType(config)
+	case HeaderTemplateApacheFull:
+		// Other licenses have no per-file boilerplate of their own
+		if GetLicenseType(config) == "Apache-2.0" {
+			return generateApacheFullHeader(config, years)
+		}
+		return generateRoleHeader(config, years)
+	case HeaderTemplateStandard:
+		return generateRoleHeader(config, years)
+	}
+
+	switch config.HeaderStyle {
+	case HeaderStyleSPDX:
+		return "SPDX-License-Identifier: " + GetLicenseType(config)
+	case HeaderStyleSPDXCopyright:
+		return fmt.Sprintf("SPDX-License-Identifier: %s\nCopyright (c) %s %s", GetLicenseType(config), years, copyrightOwner(config))
+	}
+
+	return generateRoleHeader(config, years)
+}
+
+// generateRoleHeader returns the standard header of the configured role
+func generateRoleHeader(config *Config, years string) string {
+	switch config.DefaultRole {
+	case "Student":
+		return generateStudentHeader(config, years)
+	case "Faculty", "Staff":
+		return generateFacultyStaffHeader(config, years)
+	default:
+		// Default to student if role is unclear
+		return generateStudentHeader(config, years)
+	}
+}
+
+func generateStudentHeader(config *Config, years string) string {
+	return fmt.Sprintf(`Copyright (c) %s %s
+
+SPDX-License-Identifier: %s
+See LICENSE file for full license text.`, years, copyrightOwner(config), GetLicenseType(config))
+}
+
+func generateFacultyStaffHeader(config *Config, years string) string {
+	license := GetLicenseType(config)
+	return fmt.Sprintf(`Copyright %s %s
+
+Licensed under the %s.
+See the LICENSE file for details.
+SPDX-License-Identifier: %s
+
+Developed by: %s
+              %s`, years, copyrightOwner(config), knownLicenses[license].Name, license, config.FullName, config.DeptOrLab)
+}
+
+// generateApacheFullHeader returns the boilerplate of the Apache License
+// appendix with a pointer to the NOTICE file. The SPDX line comes right
+// after the copyright line, well within headerSearchLines of the top.
+func generateApacheFullHeader(config *Config, years string) string {
+	return fmt.Sprintf(`Copyright %s %s
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+See the NOTICE file distributed with this work for additional
+information regarding copyright ownership.`, years, copyrightOwner(config))
+}
+
+// headerFieldPattern matches what a wrapped header line continues under:
+// the years of a copyright line or a label such as "Developed by: "
+var headerFieldPattern = regexp.MustCompile(`^(?:Copyright\s+(?:\(c\)\s+)?\d{4}(?:\s*[-,]\s*\d{4})*\s+|[A-Z][A-Za-z ]*:\s+)`)
+
+// wrapHeader wraps the lines of header longer than width at spaces. A
+// continuation is aligned under the field the line starts with, e.g. the
+// owner of the copyright line, or under the line's own indentation. The
+// SPDX line stays whole, since tools read it as one line.
+func wrapHeader(header string, width int) string {
+	lines := strings.Split(header, "\n")
+	var wrapped []string
+	for _, line := range lines {
+		if utf8.RuneCountInString(line) <= width || containsSPDXIdentifier(line) {
+			wrapped = append(wrapped, line)
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if field := headerFieldPattern.FindString(line); field != "" {
+			indent = utf8.RuneCountInString(field)
+		}
+		continuation := strings.Repeat(" ", indent)
+
+		current := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		for i, word := range strings.Fields(line) {
+			switch {
+			case i == 0:
+				current += word
+			case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= width:
+				current += " " + word
+			default:
+				wrapped = append(wrapped, current)
+				current = continuation + word
+			}
+		}
+		wrapped = append(wrapped, current)
+	}
+	return strings.Join(wrapped, "\n")
+}
+
+// copyrightOwner returns who the copyright line names: COPYRIGHT_OWNER when
+// set, the organization with OWNER_ORG_ONLY, otherwise the student for
+// Student and the organization for Faculty/Staff. The license stays the
+// role's either way.
+func copyrightOwner(config *Config) string {
+	if config.CopyrightOwner != "" {
+		return config.CopyrightOwner
+	}
+	if config.OwnerOrgOnly {
+		return config.Organization
+	}
+	
+	switch config.DefaultRole {
+	case "Faculty", "Staff":
+		return config.Organization
+	default:
+		return config.FullName
+	}
+}
+
+func GetHeaderTemplate(config *Config) HeaderTemplate {
+	return HeaderTemplate{
+		LicenseType:    roleLicense(config),
+		CopyrightOwner: copyrightOwner(config),
+	}
+}
+
+// defaultRoleLicenses is the license each role uses unless ROLE_LICENSES
+// maps it to another one
+var defaultRoleLicenses = map[string]string{
+	"Student": "MIT",
+	"Faculty": "Apache-2.0",
+	"Staff":   "Apache-2.0",
+}
+
+// roleLicense returns the SPDX id of the license for the configured role
+func roleLicense(config *Config) string {
+	if license, ok := config.RoleLicenses[config.DefaultRole]; ok {
+		return license
+	}
+	if license, ok := defaultRoleLicenses[config.DefaultRole]; ok {
+		return license
+	}
+	return "MIT"
+}
+
+type HeaderTemplate struct {
+	LicenseType     string
+	CopyrightOwner  string
+}
\ No newline at end of file
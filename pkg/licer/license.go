@@ -7,107 +7,260 @@
 // Developed by: Dirk Petersen
 //               UIT/ARCS
 
-package main
+package licer
 
 import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// licenseFileNames are the names a repository's license file commonly goes
+// by. A non-empty one of them means the repository is already licensed.
+var licenseFileNames = []string{
+	"LICENSE", "LICENSE.txt", "LICENSE.md", "LICENSE.rst",
+	"LICENCE", "LICENCE.txt", "LICENCE.md",
+	"COPYING", "COPYING.txt", "COPYING.md",
+	"UNLICENSE",
+}
+
 func ManageLicenseFile(repoRoot string, config *Config, verbose bool) error {
-	licensePath := filepath.Join(repoRoot, "LICENSE")
-	licenseOrigPath := filepath.Join(repoRoot, "LICENSE.orig")
+	name := licenseFileName(config)
+	licensePath := filepath.Join(repoRoot, name)
+	licenseOrigPath := licensePath + ".orig"
 	
-	// Check if LICENSE file exists
+	// Check if the license file exists
 	_, err := os.Stat(licensePath)
 	licenseExists := !os.IsNotExist(err)
 	
-	// Check if LICENSE.orig already exists
+	// Check if the .orig backup already exists
 	_, err = os.Stat(licenseOrigPath)
 	licenseOrigExists := !os.IsNotExist(err)
 	
 	if !licenseExists {
-		// No LICENSE file exists, create one
+		// A license under another common name, such as COPYING, is not
+		// duplicated
+		if existing, ok := existingLicenseFile(repoRoot); ok {
+			if verbose {
+				fmt.Fprintf(os.Stderr, "[LICENSE] Skipped LICENSE management (%s already exists)\n", existing)
+			}
+			return nil
+		}
+
+		// No license file exists, create one
 		if verbose {
-			fmt.Printf("[LICENSE] Creating LICENSE file (%s)\n", GetLicenseType(config))
+			fmt.Fprintf(os.Stderr, "[LICENSE] Creating %s file (%s)\n", name, GetLicenseType(config))
 		}
 		return createLicenseFile(licensePath, config)
 	}
 	
-	// LICENSE file exists, check if it contains SPDX identifier
-	hasSPDX, err := licenseFileHasSPDX(licensePath)
+	// The license file exists, compare it with the one licer would write
+	content, err := os.ReadFile(licensePath)
 	if err != nil {
 		if verbose {
-			fmt.Printf("[LICENSE] Error reading LICENSE file: %v\n", err)
+			fmt.Fprintf(os.Stderr, "[LICENSE] Error reading %s file: %v\n", name, err)
 		}
 		return nil // Don't fail the whole process
 	}
 	
-	if hasSPDX {
-		// LICENSE file already has SPDX, leave it alone
+	year := time.Now().Year()
+	intended := licenseFileContent(config, year)
+	if sameLicenseText(string(content), intended) {
+		// Rewriting it would only cause a diff
+		if verbose {
+			fmt.Fprintf(os.Stderr, "[LICENSE] %s file already up to date\n", name)
+		}
+		return nil
+	}
+
+	if sameLicenseText(licenseYearPattern.ReplaceAllString(string(content), "${1}"), licenseYearPattern.ReplaceAllString(intended, "${1}")) {
+		// Licer wrote it in an earlier year
+		if !config.UpdateLicenseYear {
+			if verbose {
+				fmt.Fprintf(os.Stderr, "[LICENSE] %s file differs only in its copyright year, left unchanged (use --update-license-year)\n", name)
+			}
+			return nil
+		}
 		if verbose {
-			fmt.Printf("[LICENSE] LICENSE file already compatible (contains SPDX identifier)\n")
+			fmt.Fprintf(os.Stderr, "[LICENSE] Updating the copyright year of %s\n", name)
+		}
+		info, err := os.Stat(licensePath)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", name, err)
+		}
+		return writeFile(licensePath, []byte(updateCopyrightYears(string(content), year)), info.Mode().Perm())
+	}
+
+	if licenseTextHasSPDX(content) {
+		// The license file already has SPDX, leave it alone
+		if verbose {
+			fmt.Fprintf(os.Stderr, "[LICENSE] %s file already compatible (contains SPDX identifier)\n", name)
 		}
 		return nil
 	}
 	
-	// LICENSE file exists but no SPDX identifier
+	// The license file exists but no SPDX identifier
 	if licenseOrigExists {
-		// LICENSE.orig already exists, don't touch anything
+		// The backup already exists, don't touch anything
 		if verbose {
-			fmt.Printf("[LICENSE] Skipped LICENSE management (LICENSE.orig already exists)\n")
+			fmt.Fprintf(os.Stderr, "[LICENSE] Skipped LICENSE management (%s.orig already exists)\n", name)
 		}
 		return nil
 	}
 	
-	// Rename LICENSE to LICENSE.orig and create new LICENSE
+	// Rename the license file to .orig and create a new one
 	if verbose {
-		fmt.Printf("[LICENSE] Renaming LICENSE to LICENSE.orig, creating new LICENSE (%s)\n", GetLicenseType(config))
+		fmt.Fprintf(os.Stderr, "[LICENSE] Renaming %s to %s.orig, creating new %s (%s)\n", name, name, name, GetLicenseType(config))
 	}
 	
-	err = os.Rename(licensePath, licenseOrigPath)
+	err = renameFile(licensePath, licenseOrigPath)
 	if err != nil {
-		return fmt.Errorf("failed to rename LICENSE to LICENSE.orig: %w", err)
+		return fmt.Errorf("failed to rename %s to %s.orig: %w", name, name, err)
 	}
 	
 	err = createLicenseFile(licensePath, config)
 	if err != nil {
 		// Try to restore original file if creation fails
-		os.Rename(licenseOrigPath, licensePath)
-		return fmt.Errorf("failed to create new LICENSE file: %w", err)
+		renameFile(licenseOrigPath, licensePath)
+		return fmt.Errorf("failed to create new %s file: %w", name, err)
 	}
 	
 	return nil
 }
 
-func licenseFileHasSPDX(licensePath string) (bool, error) {
-	content, err := os.ReadFile(licensePath)
+// ManageNoticeFile creates the NOTICE file Apache-2.0 projects
+// conventionally ship, when CREATE_NOTICE asks for it and repoRoot is
+// licensed Apache-2.0. A non-empty NOTICE is never touched, and other
+// licenses get none.
+func ManageNoticeFile(repoRoot string, config *Config, verbose bool) error {
+	if !config.CreateNotice || GetLicenseType(config) != "Apache-2.0" {
+		return nil
+	}
+
+	noticePath := filepath.Join(repoRoot, "NOTICE")
+	if info, err := os.Stat(noticePath); err == nil && info.Size() > 0 {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "[LICENSE] Skipped NOTICE management (NOTICE already exists)\n")
+		}
+		return nil
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "[LICENSE] Creating NOTICE file\n")
+	}
+	if err := writeFile(noticePath, []byte(noticeFileContent(config, time.Now().Year())), 0644); err != nil {
+		return fmt.Errorf("failed to create NOTICE file: %w", err)
+	}
+	return nil
+}
+
+// noticeFileContent is the NOTICE text licer writes, in the form the
+// Apache License suggests, dated year like the headers of the same run
+func noticeFileContent(config *Config, year int) string {
+	developer := config.Organization
+	if config.DeptOrLab != "" {
+		developer = fmt.Sprintf("%s, %s", config.DeptOrLab, config.Organization)
+	}
+	return fmt.Sprintf(`Copyright %d %s
+
+This product includes software developed at
+%s.
+`, year, copyrightOwner(config), developer)
+}
+
+// licenseFileName is the license file licer manages, LICENSE_FILE or
+// LICENSE by default
+func licenseFileName(config *Config) string {
+	if config.LicenseFile != "" {
+		return config.LicenseFile
+	}
+	return "LICENSE"
+}
+
+// existingLicenseFile returns a non-empty file in repoRoot with one of the
+// licenseFileNames, compared case-insensitively
+func existingLicenseFile(repoRoot string) (string, bool) {
+	entries, err := os.ReadDir(repoRoot)
 	if err != nil {
-		return false, err
+		return "", false
 	}
-	
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		for _, known := range licenseFileNames {
+			if !strings.EqualFold(name, known) {
+				continue
+			}
+			if info, err := entry.Info(); err == nil && info.Size() > 0 {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+func licenseTextHasSPDX(content []byte) bool {
 	contentLower := strings.ToLower(string(content))
-	return strings.Contains(contentLower, "spdx-license-identifier"), nil
+	return strings.Contains(contentLower, "spdx-license-identifier")
 }
 
 func createLicenseFile(licensePath string, config *Config) error {
-	var licenseContent string
-	
-	year := time.Now().Year()
-	
-	switch config.DefaultRole {
-	case "Student":
-		licenseContent = generateMITLicense(config.FullName, year)
-	case "Faculty", "Staff":
-		licenseContent = generateApache2License(year)
-	default:
-		licenseContent = generateMITLicense(config.FullName, year)
+	licenseContent := licenseFileContent(config, time.Now().Year())
+	return writeFile(licensePath, []byte(licenseContent), 0644)
+}
+
+// licenseFileContent is the LICENSE text licer writes for config, dated
+// year like the headers of the same run
+func licenseFileContent(config *Config, year int) string {
+	license := knownLicenses[GetLicenseType(config)]
+	return license.Text(copyrightOwner(config), year)
+}
+
+// sameLicenseText reports whether two license texts differ at most in line
+// endings and trailing whitespace, which don't make them different licenses
+func sameLicenseText(a, b string) bool {
+	normalize := func(text string) string {
+		lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(line, " \t")
+		}
+		return strings.TrimRight(strings.Join(lines, "\n"), "\n")
 	}
-	
-	return os.WriteFile(licensePath, []byte(licenseContent), 0644)
+	return normalize(a) == normalize(b)
+}
+
+// licenseYearPattern matches the year or year range of a copyright line
+// of the license texts, e.g. "Copyright (c) 2024" or "Copyright 2019-2024"
+var licenseYearPattern = regexp.MustCompile(`(?i)(copyright\s+(?:\(c\)\s+)?)(\d{4})(?:\s*-\s*\d{4})?`)
+
+// updateCopyrightYears extends the copyright years of content to year,
+// keeping the first one: 2024 becomes 2024-2025
+func updateCopyrightYears(content string, year int) string {
+	return licenseYearPattern.ReplaceAllStringFunc(content, func(match string) string {
+		groups := licenseYearPattern.FindStringSubmatch(match)
+		first, _ := strconv.Atoi(groups[2])
+		return groups[1] + yearRange(first, year)
+	})
+}
+
+// knownLicense is a license licer can write headers and LICENSE files for
+type knownLicense struct {
+	Name string // as used in "Licensed under the ..." header lines
+	Text func(owner string, year int) string
+}
+
+// knownLicenses are the SPDX ids ROLE_LICENSES may map a role to
+var knownLicenses = map[string]knownLicense{
+	"MIT":          {"MIT License", generateMITLicense},
+	"Apache-2.0":   {"Apache License, Version 2.0", generateApache2License},
+	"BSD-3-Clause": {"BSD 3-Clause License", generateBSD3License},
 }
 
 func generateMITLicense(fullName string, year int) string {
@@ -135,7 +288,7 @@ SOFTWARE.
 `, year, fullName)
 }
 
-func generateApache2License(year int) string {
+func generateApache2License(owner string, year int) string {
 	return fmt.Sprintf(`                                 Apache License
                            Version 2.0, January 2004
                         http://www.apache.org/licenses/
@@ -324,7 +477,7 @@ func generateApache2License(year int) string {
       same "printed page" as the copyright notice for easier
       identification within third-party archives.
 
-   Copyright %d Oregon State University
+   Copyright %d %s
 
    Licensed under the Apache License, Version 2.0 (the "License");
    you may not use this file except in compliance with the License.
@@ -337,5 +490,37 @@ func generateApache2License(year int) string {
    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
    See the License for the specific language governing permissions and
    limitations under the License.
-`, year)
-}
\ No newline at end of file
+`, year, owner)
+}
+
+func generateBSD3License(owner string, year int) string {
+	return fmt.Sprintf(`BSD 3-Clause License
+
+Copyright (c) %d, %s
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its
+   contributors may be used to endorse or promote products derived from
+   this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+`, year, owner)
+}
@@ -0,0 +1,2777 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testConfig() *Config {
+	return &Config{
+		FullName:     "Test User",
+		DefaultRole:  "Staff",
+		DeptOrLab:    "Test Lab",
+		Organization: "Oregon State University",
+	}
+}
+
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	return path
+}
+
+func TestFormatHeaderLineComments(t *testing.T) {
+	style := CommentStyles[".go"]
+	out := FormatHeader("Copyright 2025 Test\n\nSPDX-License-Identifier: MIT", style)
+
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.HasPrefix(line, "//") {
+			t.Errorf("line does not start with //: %q", line)
+		}
+	}
+}
+
+func TestFormatHeaderCSSBlock(t *testing.T) {
+	style := CommentStyles[".css"]
+	out := FormatHeader("Copyright 2025 Test", style)
+
+	lines := strings.Split(out, "\n")
+	if lines[0] != "/*" || lines[len(lines)-1] != " */" {
+		t.Errorf("CSS header is not a /* ... */ block:\n%s", out)
+	}
+}
+
+func TestBlockCommentPrefix(t *testing.T) {
+	config := testConfig()
+	config.BlockCommentPrefix = "\t* "
+	content := []byte("body { margin: 0; }\n")
+
+	updated, result := ProcessContent("site.css", content, config, ProcessOptions{})
+	if result.Action != "ADD" {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+	lines := strings.Split(string(updated), "\n")
+	if lines[0] != "/*" || lines[1] != fmt.Sprintf("\t* Copyright %d Oregon State University", time.Now().Year()) || lines[2] != "\t*" {
+		t.Errorf("header does not use the configured prefix:\n%s", updated)
+	}
+	if !strings.Contains(string(updated), "\n\t*/\n") {
+		t.Errorf("closer not aligned with the prefix:\n%s", updated)
+	}
+
+	// The header is still recognized as ours
+	if _, result := ProcessContent("site.css", updated, config, ProcessOptions{}); result.Action != "SKIP" {
+		t.Errorf("header added twice: %s (%s)", result.Action, result.Reason)
+	}
+	removed, result := ProcessContent("site.css", updated, config, ProcessOptions{RemoveMode: true})
+	if result.Action != "REMOVE" || string(removed) != string(content) {
+		t.Errorf("header not removed: %s (%s)\n%s", result.Action, result.Reason, removed)
+	}
+
+	for prefix, valid := range map[string]bool{"": true, " * ": true, "** ": true, "  ": false, " # ": false, " */": false} {
+		if err := validateBlockCommentPrefix(prefix); (err == nil) != valid {
+			t.Errorf("validateBlockCommentPrefix(%q) = %v", prefix, err)
+		}
+	}
+}
+
+func TestSPDXOnlyHeaderStyle(t *testing.T) {
+	year := time.Now().Year()
+	cases := []struct {
+		style, filename, source, want string
+	}{
+		{HeaderStyleSPDX, "main.go", "package main\n", "// SPDX-License-Identifier: Apache-2.0\n\npackage main\n"},
+		{HeaderStyleSPDX, "main.py", "x = 1\n", "# SPDX-License-Identifier: Apache-2.0\n\nx = 1\n"},
+		{HeaderStyleSPDXCopyright, "main.go", "package main\n", fmt.Sprintf("// SPDX-License-Identifier: Apache-2.0\n// Copyright (c) %d Oregon State University\n\npackage main\n", year)},
+		{HeaderStyleSPDXCopyright, "main.py", "x = 1\n", fmt.Sprintf("# SPDX-License-Identifier: Apache-2.0\n# Copyright (c) %d Oregon State University\n\nx = 1\n", year)},
+	}
+	for _, c := range cases {
+		config := testConfig()
+		config.HeaderStyle = c.style
+		updated, result := ProcessContent(c.filename, []byte(c.source), config, ProcessOptions{})
+		if result.Action != "ADD" || string(updated) != c.want {
+			t.Errorf("%s %s: %s (%s)\n%s", c.style, c.filename, result.Action, result.Reason, updated)
+			continue
+		}
+
+		// The minimal header is detected like a full one
+		if _, result := ProcessContent(c.filename, updated, config, ProcessOptions{}); result.Action != "SKIP" {
+			t.Errorf("%s %s: header added twice: %s (%s)", c.style, c.filename, result.Action, result.Reason)
+		}
+	}
+
+	if err := validateHeaderStyle("kernel"); err == nil {
+		t.Error("validateHeaderStyle accepted an unknown style")
+	}
+}
+
+func TestNamedHeaderTemplates(t *testing.T) {
+	year := time.Now().Year()
+	cases := []struct {
+		template string
+		want     []string // lines of the header, in order
+	}{
+		{HeaderTemplateMinimal, []string{
+			fmt.Sprintf("# Copyright (c) %d Oregon State University", year),
+			"# SPDX-License-Identifier: Apache-2.0",
+		}},
+		{HeaderTemplateStandard, []string{
+			fmt.Sprintf("# Copyright %d Oregon State University", year),
+			"# Licensed under the Apache License, Version 2.0.",
+			"# SPDX-License-Identifier: Apache-2.0",
+			"# Developed by: Test User",
+		}},
+		{HeaderTemplateSPDXOnly, []string{
+			"# SPDX-License-Identifier: Apache-2.0",
+		}},
+		{HeaderTemplateApacheFull, []string{
+			fmt.Sprintf("# Copyright %d Oregon State University", year),
+			"# SPDX-License-Identifier: Apache-2.0",
+			`# Licensed under the Apache License, Version 2.0 (the "License");`,
+			"#     http://www.apache.org/licenses/LICENSE-2.0",
+			"# limitations under the License.",
+			"# See the NOTICE file distributed with this work for additional",
+		}},
+	}
+	for _, c := range cases {
+		config := testConfig()
+		config.HeaderTemplate = c.template
+		config.HeaderStyle = HeaderStyleSPDX // the template takes precedence
+		updated, result := ProcessContent("main.py", []byte("x = 1\n"), config, ProcessOptions{})
+		if result.Action != "ADD" {
+			t.Errorf("%s: %s (%s)", c.template, result.Action, result.Reason)
+			continue
+		}
+		rest := string(updated)
+		for _, line := range c.want {
+			i := strings.Index(rest, line+"\n")
+			if i < 0 {
+				t.Errorf("%s: missing or out of order %q in\n%s", c.template, line, updated)
+				break
+			}
+			rest = rest[i+len(line):]
+		}
+		if c.template == HeaderTemplateSPDXOnly && !strings.HasPrefix(string(updated), "# SPDX-License-Identifier: Apache-2.0\n\nx = 1") {
+			t.Errorf("spdx-only: expected only the SPDX line, got\n%s", updated)
+		}
+
+		// Every template is detected, so a second run changes nothing
+		if _, result := ProcessContent("main.py", updated, config, ProcessOptions{}); result.Action != "SKIP" {
+			t.Errorf("%s: header added twice: %s (%s)", c.template, result.Action, result.Reason)
+		}
+	}
+
+	// apache-full has no wording for other licenses and falls back to standard
+	config := testConfig()
+	config.DefaultRole = "Student"
+	config.HeaderTemplate = HeaderTemplateApacheFull
+	if header := GenerateHeader(config); !strings.Contains(header, "SPDX-License-Identifier: MIT") || strings.Contains(header, "NOTICE") {
+		t.Errorf("apache-full for MIT should fall back to the standard header, got\n%s", header)
+	}
+
+	if err := ValidateHeaderTemplate("verbose"); err == nil {
+		t.Error("ValidateHeaderTemplate accepted an unknown template")
+	}
+}
+
+func TestHeaderWidthWrapsLongNames(t *testing.T) {
+	config := testConfig()
+	config.Organization = "The Extraordinarily Long Named Regional Consortium of Research Universities"
+	config.DeptOrLab = "Advanced Research Computing Services and Scientific Data Infrastructure"
+	config.HeaderWidth = 72
+	path := writeTempFile(t, "main.go", "package main\n")
+
+	if result := ProcessFile(path, config, false, false, false); result.Action != "ADD" {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+	content, _ := os.ReadFile(path)
+	lines := strings.Split(string(content), "\n")
+	for _, line := range lines {
+		if len(line) > 72 {
+			t.Errorf("line longer than 72 columns: %q", line)
+		}
+	}
+
+	// Continuations are aligned under the owner and under the lab
+	year := time.Now().Year()
+	owner := fmt.Sprintf("// Copyright %d ", year)
+	if !strings.HasPrefix(lines[0], owner+"The Extraordinarily") || lines[1] != "//"+strings.Repeat(" ", len(owner)-2)+"Research Universities" {
+		t.Errorf("copyright line not wrapped under the owner:\n%s", content)
+	}
+	if !strings.Contains(string(content), "\n//               Advanced Research Computing Services and Scientific\n//               Data Infrastructure\n") {
+		t.Errorf("lab not wrapped under its field:\n%s", content)
+	}
+	if !strings.Contains(string(content), "\n// SPDX-License-Identifier: Apache-2.0\n") {
+		t.Errorf("SPDX line changed:\n%s", content)
+	}
+
+	// The wrapped header is still ours and current
+	if result := ProcessFile(path, config, true, false, false); result.Modified {
+		t.Errorf("force run rewrote the wrapped header: %s (%s)", result.Action, result.Reason)
+	}
+	if result := ProcessFile(path, config, false, true, false); result.Action != "REMOVE" {
+		t.Errorf("wrapped header not removable: %s (%s)", result.Action, result.Reason)
+	}
+}
+
+func TestFormatHeaderHTMLIsValid(t *testing.T) {
+	style := CommentStyles[".html"]
+	out := FormatHeader("Copyright 2025 Test\n\nSPDX-License-Identifier: MIT", style)
+
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.HasPrefix(line, "<!--") || !strings.HasSuffix(line, "-->") {
+			t.Errorf("HTML header line is not a closed comment: %q", line)
+		}
+	}
+}
+
+func TestFormatHeaderOCamlIsValid(t *testing.T) {
+	style := CommentStyles[".ml"]
+	out := FormatHeader("Copyright 2025 Test", style)
+
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.HasPrefix(line, "(*") || !strings.HasSuffix(line, "*)") {
+			t.Errorf("OCaml header line is not a closed comment: %q", line)
+		}
+	}
+}
+
+func TestLicenseFilesAreExcluded(t *testing.T) {
+	for _, name := range []string{"LICENSE", "LICENSE.orig", "COPYING", "NOTICE", "license"} {
+		path := writeTempFile(t, name, "Apache License\nVersion 2.0, January 2004\n")
+		if ShouldProcessFile(path) {
+			t.Errorf("%s should be excluded from processing", name)
+		}
+	}
+}
+
+func TestAddHeaderIsIdempotent(t *testing.T) {
+	path := writeTempFile(t, "example.py", "def main():\n    pass\n")
+	config := testConfig()
+
+	result := ProcessFile(path, config, false, false, false)
+	if result.Action != "ADD" || !result.Modified {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+
+	result = ProcessFile(path, config, false, false, false)
+	if result.Action != "SKIP" || result.Modified {
+		t.Fatalf("second run should SKIP, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	if !strings.Contains(string(content), "SPDX-License-Identifier: Apache-2.0") {
+		t.Error("header missing SPDX identifier")
+	}
+	if !strings.Contains(string(content), "def main():") {
+		t.Error("original code was lost")
+	}
+}
+
+func TestForceReplaceIsStable(t *testing.T) {
+	path := writeTempFile(t, "example.py", "def main():\n    pass\n")
+	config := testConfig()
+
+	ProcessFile(path, config, false, false, false)
+	ProcessFile(path, config, true, false, false)
+	first, _ := os.ReadFile(path)
+	ProcessFile(path, config, true, false, false)
+	second, _ := os.ReadFile(path)
+
+	if string(first) != string(second) {
+		t.Errorf("repeated --force runs changed the file:\n--- first ---\n%s\n--- second ---\n%s", first, second)
+	}
+	if !strings.Contains(string(second), "def main():") {
+		t.Error("original code was lost during force replace")
+	}
+}
+
+func TestShebangIsPreserved(t *testing.T) {
+	path := writeTempFile(t, "deploy.sh", "#!/bin/bash\necho hello\n")
+	config := testConfig()
+
+	result := ProcessFile(path, config, false, false, false)
+	if !result.Modified {
+		t.Fatalf("expected file to be modified, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	lines := strings.Split(string(content), "\n")
+	if lines[0] != "#!/bin/bash" {
+		t.Errorf("shebang not preserved as first line, got %q", lines[0])
+	}
+	if !strings.Contains(string(content), "echo hello") {
+		t.Error("original code was lost")
+	}
+
+	// Force replace must also keep the shebang
+	ProcessFile(path, config, true, false, false)
+	content, _ = os.ReadFile(path)
+	if !strings.HasPrefix(string(content), "#!/bin/bash") {
+		t.Error("shebang lost after force replace")
+	}
+}
+
+func TestThirdPartyCopyrightIsProtected(t *testing.T) {
+	source := "// Copyright (c) 2020 Other Corp\n\nuse std::io;\n\nfn main() {}\n"
+	path := writeTempFile(t, "lib.rs", source)
+	config := testConfig()
+
+	result := ProcessFile(path, config, false, false, false)
+	if result.Action != "SKIP" || result.Modified {
+		t.Fatalf("third-party copyright should be skipped without --force, got %s (%s)", result.Action, result.Reason)
+	}
+
+	// --force alone leaves it alone too
+	result = ProcessFile(path, config, true, false, false)
+	if result.Action != "SKIP" || result.Modified {
+		t.Fatalf("third-party copyright should be skipped under --force without --replace-third-party, got %s (%s)", result.Action, result.Reason)
+	}
+	if content, _ := os.ReadFile(path); string(content) != source {
+		t.Fatalf("third-party copyright changed under --force:\n%s", content)
+	}
+
+	// With --replace-third-party the header is replaced but code must survive
+	result = ProcessFileWithOptions(path, config, ProcessOptions{ForceReplace: true, ReplaceThirdParty: true})
+	if !result.Modified {
+		t.Fatalf("expected --replace-third-party to replace third-party header, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	if strings.Contains(string(content), "Other Corp") {
+		t.Error("third-party copyright not replaced under --replace-third-party")
+	}
+	if !strings.Contains(string(content), "use std::io;") || !strings.Contains(string(content), "fn main() {}") {
+		t.Errorf("code lines were lost during third-party replacement:\n%s", content)
+	}
+}
+
+func TestThirdPartyDetectionCanBeDisabled(t *testing.T) {
+	source := "\"\"\"Copyright by-line parsing for the catalog.\n\nReads the holder from scanned records.\n\"\"\"\n\nimport re\n"
+	config := testConfig()
+
+	path := writeTempFile(t, "byline.py", source)
+	if result := ProcessFile(path, config, false, false, false); result.Modified {
+		t.Fatalf("doc comment should be taken for a copyright notice by default, got %s (%s)", result.Action, result.Reason)
+	}
+
+	detect := false
+	config.DetectThirdParty = &detect
+	if info := DetectHeaderForConfig(path, []byte(source), config); info.HasHeader || info.HasThirdPartyCopyright {
+		t.Errorf("doc comment detected as a header: %+v", info)
+	}
+	result := ProcessFile(path, config, false, false, false)
+	if result.Action != "ADD" || !result.Modified {
+		t.Fatalf("expected ADD with DETECT_THIRD_PARTY: false, got %s (%s)", result.Action, result.Reason)
+	}
+	content, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(content), "# Copyright ") || !strings.Contains(string(content), source) {
+		t.Errorf("header not added above the untouched doc comment:\n%s", content)
+	}
+
+	// SPDX headers are still found, so a second run adds no other
+	if result := ProcessFile(path, config, false, false, false); result.Modified {
+		t.Errorf("second run modified the file: %s (%s)", result.Action, result.Reason)
+	}
+}
+
+func TestCodeStartingWithCIsNotAComment(t *testing.T) {
+	if isCommentLine("Config = load()") {
+		t.Error("code starting with 'C' misdetected as comment")
+	}
+	if isCommentLine(`"""Module docstring."""`) {
+		t.Error("Python docstring misdetected as comment")
+	}
+	if !isCommentLine("C Fortran comment") {
+		t.Error("Fortran comment not detected")
+	}
+	if !isCommentLine("# shell comment") || !isCommentLine("// go comment") {
+		t.Error("standard comments not detected")
+	}
+}
+
+func TestRemoveHeaderWithOwnershipMatch(t *testing.T) {
+	path := writeTempFile(t, "example.py", "def main():\n    pass\n")
+	config := testConfig()
+
+	ProcessFile(path, config, false, false, false)
+	result := ProcessFile(path, config, false, true, false)
+	if result.Action != "REMOVE" || !result.Modified {
+		t.Fatalf("expected REMOVE, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	if strings.Contains(string(content), "SPDX-License-Identifier") {
+		t.Error("header not removed")
+	}
+	if !strings.Contains(string(content), "def main():") {
+		t.Error("original code was lost during removal")
+	}
+}
+
+func TestRemoveHeaderOwnershipMismatch(t *testing.T) {
+	source := "# Copyright (c) 2025 Someone Else\n#\n# SPDX-License-Identifier: MIT\n\ndef main():\n    pass\n"
+	path := writeTempFile(t, "example.py", source)
+
+	result := ProcessFile(path, testConfig(), false, true, false)
+	if result.Action != "SKIP" || result.Modified {
+		t.Fatalf("foreign header should not be removed, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != source {
+		t.Error("file was modified despite ownership mismatch")
+	}
+}
+
+func TestRemoveHeaderLeavesNoLeadingBlankLines(t *testing.T) {
+	header := "# Copyright 2025 Oregon State University\n#\n# SPDX-License-Identifier: Apache-2.0\n"
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"shebang, blank, header, blank, code", "#!/bin/sh\n\n" + header + "\necho hi\n", "#!/bin/sh\necho hi\n"},
+		{"header, blank, code", header + "\necho hi\n", "echo hi\n"},
+		{"blanks above the header", "\n\n" + header + "\n\necho hi\n", "echo hi\n"},
+		{"whitespace-only separators", "#!/bin/sh\n  \n" + header + "\t\n\necho hi\n", "#!/bin/sh\necho hi\n"},
+		{"CRLF", strings.ReplaceAll("#!/bin/sh\n\n"+header+"\necho hi\n", "\n", "\r\n"), "#!/bin/sh\r\necho hi\r\n"},
+	}
+	for _, tt := range tests {
+		path := writeTempFile(t, "script.sh", tt.content)
+		if err := RemoveHeader(path); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if content, _ := os.ReadFile(path); string(content) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, content, tt.want)
+		}
+	}
+}
+
+func TestProcessContentTable(t *testing.T) {
+	ownHeader := "# Copyright 2025 Oregon State University\n#\n# SPDX-License-Identifier: Apache-2.0\n\nx = 1\n"
+
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		opts     ProcessOptions
+		action   string
+		modified bool
+	}{
+		{"add to python", "a.py", "x = 1\n", ProcessOptions{}, "ADD", true},
+		{"skip existing header", "a.py", ownHeader, ProcessOptions{}, "SKIP", false},
+		{"force replaces header", "a.py", ownHeader, ProcessOptions{ForceReplace: true}, "REPLACE", true},
+		{"remove own header", "a.py", ownHeader, ProcessOptions{RemoveMode: true}, "REMOVE", true},
+		{"remove without header", "a.py", "x = 1\n", ProcessOptions{RemoveMode: true}, "SKIP", false},
+		{"skip third-party", "a.go", "// Copyright 2019 Other Corp\n\npackage a\n", ProcessOptions{}, "SKIP", false},
+		{"excluded extension", "a.json", "{}\n", ProcessOptions{}, "SKIP", false},
+		{"extensionless text", "run", "echo hi\n", ProcessOptions{}, "ADD", true},
+		{"extensionless binary", "blob", "\x00\x01\x02", ProcessOptions{}, "SKIP", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, result := ProcessContent(tt.filename, []byte(tt.content), testConfig(), tt.opts)
+			if result.Action != tt.action || result.Modified != tt.modified {
+				t.Fatalf("expected %s (modified=%v), got %s (modified=%v): %s",
+					tt.action, tt.modified, result.Action, result.Modified, result.Reason)
+			}
+			if !tt.modified && out != nil {
+				t.Errorf("expected nil content for unmodified result")
+			}
+			if tt.modified && !strings.Contains(string(out), "x = 1") && !strings.Contains(string(out), "echo hi") {
+				t.Errorf("original code lost:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestTexMagicCommentStaysFirst(t *testing.T) {
+	source := "%!TEX program = xelatex\n\\documentclass{article}\n\\begin{document}\nHello\n\\end{document}\n"
+	path := writeTempFile(t, "paper.tex", source)
+	config := testConfig()
+
+	result := ProcessFile(path, config, false, false, false)
+	if result.Action != "ADD" || !result.Modified {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	lines := strings.Split(string(content), "\n")
+	if lines[0] != "%!TEX program = xelatex" {
+		t.Errorf("magic comment displaced from first line, got %q", lines[0])
+	}
+	if !strings.Contains(string(content), "% SPDX-License-Identifier: Apache-2.0") {
+		t.Errorf("header not written with %% comments:\n%s", content)
+	}
+
+	// Force replace keeps the magic comment first as well
+	ProcessFile(path, config, true, false, false)
+	content, _ = os.ReadFile(path)
+	if !strings.HasPrefix(string(content), "%!TEX program = xelatex\n") {
+		t.Errorf("magic comment lost after force replace:\n%s", content)
+	}
+}
+
+func TestRubyMagicCommentsStayFirst(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		keep   string
+	}{
+		{"frozen string literal", "# frozen_string_literal: true\n\nputs 1\n", "# frozen_string_literal: true\n"},
+		{"after shebang", "#!/usr/bin/env ruby\n# frozen_string_literal: true\nputs 1\n", "#!/usr/bin/env ruby\n# frozen_string_literal: true\n"},
+		{"emacs form", "# -*- frozen_string_literal: true -*-\nputs 1\n", "# -*- frozen_string_literal: true -*-\n"},
+		{"several", "# encoding: utf-8\n# warn_indent: true\n# frozen_string_literal: true\nputs 1\n", "# encoding: utf-8\n# warn_indent: true\n# frozen_string_literal: true\n"},
+	}
+	config := testConfig()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTempFile(t, "app.rb", tt.source)
+
+			result := ProcessFile(path, config, false, false, false)
+			if result.Action != "ADD" || !result.Modified {
+				t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+			}
+			content, _ := os.ReadFile(path)
+			if !strings.HasPrefix(string(content), tt.keep) {
+				t.Errorf("magic comments displaced:\n%s", content)
+			}
+			if !strings.Contains(string(content), "# SPDX-License-Identifier: Apache-2.0") {
+				t.Errorf("header not added:\n%s", content)
+			}
+
+			// A second run finds the header below the magic comments
+			result = ProcessFile(path, config, false, false, false)
+			if result.Modified {
+				t.Errorf("second run modified the file: %s (%s)", result.Action, result.Reason)
+			}
+		})
+	}
+}
+
+// firstStatement returns the first line that is neither blank nor a comment
+func firstStatement(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) != "" && !isCommentLine(line) {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestProtoSyntaxStaysFirstStatement(t *testing.T) {
+	source := "syntax = \"proto3\";\n\npackage demo;\n\nmessage Ping {\n  string id = 1;\n}\n"
+	path := writeTempFile(t, "ping.proto", source)
+
+	result := ProcessFile(path, testConfig(), false, false, false)
+	if result.Action != "ADD" || !result.Modified {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(content), "// Copyright") {
+		t.Errorf("expected // header above syntax statement:\n%s", content)
+	}
+	if got := firstStatement(string(content)); got != `syntax = "proto3";` {
+		t.Errorf("syntax must remain the first non-comment statement, got %q", got)
+	}
+}
+
+func TestGraphQLUsesHashComments(t *testing.T) {
+	path := writeTempFile(t, "schema.graphql", "\"\"\"A user\"\"\"\ntype User {\n  id: ID!\n}\n")
+
+	result := ProcessFile(path, testConfig(), false, false, false)
+	if result.Action != "ADD" || !result.Modified {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(content), "# Copyright") {
+		t.Errorf("expected # header in GraphQL file:\n%s", content)
+	}
+	if got := firstStatement(string(content)); got != `"""A user"""` {
+		t.Errorf("docstring displaced, first statement is %q", got)
+	}
+}
+
+func TestVueComponentGetsSingleMarkupBlock(t *testing.T) {
+	source := `<template>
+  <div class="greeting">{{ msg }}</div>
+</template>
+
+<script>
+export default {
+  data() {
+    return { msg: 'Hello' }
+  }
+}
+</script>
+
+<style scoped>
+.greeting { color: red; }
+</style>
+`
+	path := writeTempFile(t, "Greeting.vue", source)
+	config := testConfig()
+
+	result := ProcessFile(path, config, false, false, false)
+	if result.Action != "ADD" || !result.Modified {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(content), "<!--\n") {
+		t.Errorf("expected header to open with a markup comment:\n%s", content)
+	}
+	if strings.Count(string(content), "<!--") != 1 || strings.Count(string(content), "-->") != 1 {
+		t.Errorf("expected exactly one <!-- --> block:\n%s", content)
+	}
+	if !strings.HasSuffix(string(content), source) {
+		t.Errorf("component body was changed:\n%s", content)
+	}
+
+	// The block is recognized as our header on the next run
+	result = ProcessFile(path, config, false, false, false)
+	if result.Action != "SKIP" || result.Reason != "Header already exists" {
+		t.Fatalf("expected existing header to be detected, got %s (%s)", result.Action, result.Reason)
+	}
+
+	// Replacing the block must not leave orphaned delimiters or lines
+	ProcessFile(path, config, true, false, false)
+	replaced, _ := os.ReadFile(path)
+	if string(replaced) != string(content) {
+		t.Errorf("force replace changed the component:\n--- before ---\n%s\n--- after ---\n%s", content, replaced)
+	}
+}
+
+func TestSolidityHeaderStartsWithSPDX(t *testing.T) {
+	source := "pragma solidity ^0.8.20;\n\ncontract Counter {\n    uint256 public count;\n}\n"
+	path := writeTempFile(t, "Counter.sol", source)
+	config := testConfig()
+
+	result := ProcessFile(path, config, false, false, false)
+	if result.Action != "ADD" || !result.Modified {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	lines := strings.Split(string(content), "\n")
+	if lines[0] != "// SPDX-License-Identifier: Apache-2.0" {
+		t.Errorf("expected SPDX identifier on the first line, got %q", lines[0])
+	}
+	if strings.Count(string(content), "SPDX-License-Identifier") != 1 {
+		t.Errorf("SPDX identifier duplicated:\n%s", content)
+	}
+	if got := firstStatement(string(content)); got != "pragma solidity ^0.8.20;" {
+		t.Errorf("pragma displaced, first statement is %q", got)
+	}
+
+	// Re-running and force replacing must neither duplicate nor drift
+	if result = ProcessFile(path, config, false, false, false); result.Modified {
+		t.Errorf("second run modified the file: %s", result.Reason)
+	}
+	ProcessFile(path, config, true, false, false)
+	replaced, _ := os.ReadFile(path)
+	if string(replaced) != string(content) {
+		t.Errorf("force replace changed the file:\n%s", replaced)
+	}
+}
+
+func TestSolidityExistingSPDXLineIsRecognized(t *testing.T) {
+	source := "// SPDX-License-Identifier: MIT\npragma solidity ^0.8.20;\n\ncontract Token {}\n"
+
+	_, result := ProcessContent("Token.sol", []byte(source), testConfig(), ProcessOptions{})
+	if result.Action != "SKIP" || result.Reason != "Header already exists" {
+		t.Errorf("existing SPDX line not recognized, got %s (%s)", result.Action, result.Reason)
+	}
+}
+
+func TestExtensionOverrides(t *testing.T) {
+	defer SetExtensionOverrides(nil, nil)
+	config := testConfig()
+
+	SetExtensionOverrides([]string{"sql"}, nil)
+	if _, result := ProcessContent("dump.sql", []byte("SELECT 1;\n"), config, ProcessOptions{}); result.Action != "SKIP" {
+		t.Errorf("--exclude-ext sql should skip .sql files, got %s (%s)", result.Action, result.Reason)
+	}
+
+	SetExtensionOverrides(nil, nil)
+	if _, result := ProcessContent("dump.sql", []byte("SELECT 1;\n"), config, ProcessOptions{}); result.Action != "ADD" {
+		t.Errorf(".sql should be processed without overrides, got %s (%s)", result.Action, result.Reason)
+	}
+
+	SetExtensionOverrides(nil, []string{".JSON"})
+	if IsExcludedExtension(".json") {
+		t.Error("--include-ext .JSON should remove .json from the excluded set")
+	}
+	if _, result := ProcessContent("data.json", []byte("{}\n"), config, ProcessOptions{}); result.Modified {
+		t.Error(".json has no comment style and must never be modified")
+	}
+
+	SetExtensionOverrides([]string{".json"}, []string{".json"})
+	if !IsExcludedExtension(".json") {
+		t.Error("an extension both excluded and included should stay excluded")
+	}
+}
+
+func TestIncludeTextExtensionsKeepsBinariesExcluded(t *testing.T) {
+	defer SetExtensionOverrides(nil, nil)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configDir := filepath.Join(home, ".config")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	tomlPath := filepath.Join(configDir, "licer.toml")
+	base := "VERSION = 2\nFULL_NAME = \"Test User\"\nDEFAULT_ROLE = \"Staff\"\nDEPT_OR_LAB = \"Test Lab\"\nORGANIZATION = \"Oregon State University\"\n"
+
+	readme := writeTempFile(t, "README.md", "# Project\n")
+	logo := writeTempFile(t, "logo.png", "\x89PNG\r\n")
+	if ShouldProcessFile(readme) || ShouldProcessFile(logo) {
+		t.Fatal(".md and .png should both be excluded by default")
+	}
+
+	if err := os.WriteFile(tomlPath, []byte(base+"INCLUDE_EXTENSIONS = [\"md\"]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadExistingConfig()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if !ShouldProcessContentForConfig(readme, []byte("# Project\n"), config) {
+		t.Error("INCLUDE_EXTENSIONS should opt .md back in")
+	}
+	if ShouldProcessContentForConfig(logo, []byte("\x89PNG\r\n"), config) {
+		t.Error(".png must stay excluded")
+	}
+	updated, result := ProcessContent("README.md", []byte("# Project\n"), config, ProcessOptions{})
+	if result.Action != "ADD" || !strings.HasPrefix(string(updated), "<!-- Copyright") || !strings.HasSuffix(string(updated), "-->\n\n# Project\n") {
+		t.Errorf("expected an HTML comment header on README.md, got %s (%s):\n%s", result.Action, result.Reason, updated)
+	}
+
+	// Binary formats can't be opted in, neither by config nor by flag
+	if err := os.WriteFile(tomlPath, []byte(base+"INCLUDE_EXTENSIONS = [\".png\"]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadExistingConfig(); err == nil || !strings.Contains(err.Error(), "binary") {
+		t.Errorf("INCLUDE_EXTENSIONS accepted .png: %v", err)
+	}
+	SetExtensionOverrides(nil, []string{".png"})
+	if !IsExcludedExtension(".png") {
+		t.Error("--include-ext must not opt .png in")
+	}
+
+	// EXCLUDE_EXTENSIONS skips a type that is processed by default
+	if err := os.WriteFile(tomlPath, []byte(base+"EXCLUDE_EXTENSIONS = [\".sql\"]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	excluding, err := LoadExistingConfig()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if !isExcludedExtension(".sql", excluding) || !isExcludedExtension(".md", excluding) {
+		t.Error("EXCLUDE_EXTENSIONS .sql should skip .sql, and .md is excluded again")
+	}
+
+	// Each config keeps its own extensions, in the same process
+	if _, result := ProcessContent("README.md", []byte("# Project\n"), config, ProcessOptions{}); result.Action != "ADD" {
+		t.Errorf("config with INCLUDE_EXTENSIONS .md after loading another: %s (%s)", result.Action, result.Reason)
+	}
+	if _, result := ProcessContent("dump.sql", []byte("SELECT 1;\n"), excluding, ProcessOptions{}); result.Action != "SKIP" {
+		t.Errorf("config with EXCLUDE_EXTENSIONS .sql: %s (%s)", result.Action, result.Reason)
+	}
+	if _, result := ProcessContent("dump.sql", []byte("SELECT 1;\n"), config, ProcessOptions{}); result.Action != "ADD" {
+		t.Errorf("EXCLUDE_EXTENSIONS of another config applied: %s (%s)", result.Action, result.Reason)
+	}
+}
+
+func TestUTF8BOMStaysFirst(t *testing.T) {
+	bom := "\xEF\xBB\xBF"
+	source := bom + "package main\n\nfunc main() {}\n"
+	path := writeTempFile(t, "bom.go", source)
+	config := testConfig()
+
+	result := ProcessFile(path, config, false, false, false)
+	if result.Action != "ADD" || !result.Modified {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(content), bom+"// Copyright") {
+		t.Errorf("expected BOM followed by the header, got %q", string(content[:20]))
+	}
+	if strings.Count(string(content), bom) != 1 {
+		t.Error("BOM duplicated or embedded in the header")
+	}
+
+	// The header after the BOM is detected, and removal keeps the BOM
+	if result = ProcessFile(path, config, false, false, false); result.Modified {
+		t.Errorf("header behind BOM not detected: %s", result.Reason)
+	}
+	ProcessFile(path, config, false, true, false)
+	content, _ = os.ReadFile(path)
+	if string(content) != source {
+		t.Errorf("remove did not restore the original BOM file, got %q", content)
+	}
+}
+
+func TestTrailingNewlineIsPreserved(t *testing.T) {
+	config := testConfig()
+
+	for _, source := range []string{"x = 1", "x = 1\n", "#!/usr/bin/env python3\nx = 1", "#!/usr/bin/env python3\nx = 1\n"} {
+		wantNewline := strings.HasSuffix(source, "\n")
+
+		added, result := ProcessContent("a.py", []byte(source), config, ProcessOptions{})
+		if !result.Modified {
+			t.Fatalf("expected %q to be modified, got %s (%s)", source, result.Action, result.Reason)
+		}
+		stale := strings.Replace(string(added), fmt.Sprint(time.Now().Year()), "2019", 1)
+		replaced, _ := ProcessContent("a.py", []byte(stale), config, ProcessOptions{ForceReplace: true})
+		removed, _ := ProcessContent("a.py", added, config, ProcessOptions{RemoveMode: true})
+
+		for name, out := range map[string][]byte{"add": added, "replace": replaced, "remove": removed} {
+			text := string(out)
+			if wantNewline && (!strings.HasSuffix(text, "\n") || strings.HasSuffix(text, "\n\n")) {
+				t.Errorf("%s: expected exactly one final newline for %q, got %q", name, source, text)
+			}
+			if !wantNewline && strings.HasSuffix(text, "\n") {
+				t.Errorf("%s: final newline introduced for %q, got %q", name, source, text)
+			}
+		}
+		if string(removed) != source {
+			t.Errorf("remove did not restore %q, got %q", source, removed)
+		}
+	}
+}
+
+func TestCopyrightOwnerOverride(t *testing.T) {
+	config := testConfig()
+	config.DefaultRole = "Student"
+
+	if header := GenerateHeader(config); !strings.Contains(header, "Copyright (c) ") || !strings.Contains(header, "Test User") {
+		t.Errorf("student header should default to the student as owner:\n%s", header)
+	}
+
+	config.CopyrightOwner = "Oregon State University"
+	header := GenerateHeader(config)
+	if !strings.Contains(header, "Oregon State University") || !strings.Contains(header, "SPDX-License-Identifier: MIT") {
+		t.Errorf("expected MIT header owned by the organization:\n%s", header)
+	}
+	template := GetHeaderTemplate(config)
+	if template.CopyrightOwner != "Oregon State University" || template.LicenseType != "MIT" {
+		t.Errorf("template did not honor COPYRIGHT_OWNER: %+v", template)
+	}
+}
+
+func TestOwnerOrgOnlyKeepsStudentLicense(t *testing.T) {
+	config := testConfig()
+	config.DefaultRole = "Student"
+	config.OwnerOrgOnly = true
+
+	header := GenerateHeader(config)
+	wantLine := fmt.Sprintf("Copyright (c) %d Oregon State University\n", time.Now().Year())
+	if !strings.HasPrefix(header, wantLine) || !strings.Contains(header, "SPDX-License-Identifier: MIT") || strings.Contains(header, "Test User") {
+		t.Errorf("expected MIT header owned by the organization:\n%s", header)
+	}
+	template := GetHeaderTemplate(config)
+	if template.CopyrightOwner != "Oregon State University" || template.LicenseType != "MIT" {
+		t.Errorf("template did not honor OWNER_ORG_ONLY: %+v", template)
+	}
+
+	// An explicit COPYRIGHT_OWNER still wins
+	config.CopyrightOwner = "Grant Office"
+	if owner := GetHeaderTemplate(config).CopyrightOwner; owner != "Grant Office" {
+		t.Errorf("expected COPYRIGHT_OWNER to win, got %q", owner)
+	}
+}
+
+func TestOrganizationIsNotHardcoded(t *testing.T) {
+	config := testConfig()
+	config.Organization = "Portland State University"
+
+	header := GenerateHeader(config)
+	if !strings.Contains(header, "Copyright ") || !strings.Contains(header, "Portland State University") {
+		t.Errorf("configured organization missing from header:\n%s", header)
+	}
+	if strings.Contains(header, "Oregon State University") {
+		t.Errorf("header still hardcodes Oregon State University:\n%s", header)
+	}
+	if owner := GetHeaderTemplate(config).CopyrightOwner; owner != "Portland State University" {
+		t.Errorf("expected template owner Portland State University, got %q", owner)
+	}
+
+	licensePath := filepath.Join(t.TempDir(), "LICENSE")
+	if err := createLicenseFile(licensePath, config); err != nil {
+		t.Fatal(err)
+	}
+	license, _ := os.ReadFile(licensePath)
+	if !strings.Contains(string(license), "Portland State University") || strings.Contains(string(license), "Oregon State University") {
+		t.Error("LICENSE file does not use the configured organization")
+	}
+}
+
+func TestMigrateLegacyHeaderKeepsYear(t *testing.T) {
+	patterns, err := CompileLegacyPatterns([]string{`\(C\) \d{4} OSU, all rights reserved`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := ProcessOptions{Migrate: true, LegacyPatterns: patterns}
+	config := testConfig()
+
+	legacy := "#!/usr/bin/env python3\n# (C) 2019 OSU, all rights reserved\n#\n\nimport os\n"
+	out, result := ProcessContent("tool.py", []byte(legacy), config, opts)
+	if result.Action != "REPLACE" || !result.Modified {
+		t.Fatalf("expected legacy header to be migrated, got %s (%s)", result.Action, result.Reason)
+	}
+	text := string(out)
+	if !strings.HasPrefix(text, "#!/usr/bin/env python3\n") {
+		t.Errorf("shebang lost during migration:\n%s", text)
+	}
+	if strings.Contains(text, "all rights reserved") {
+		t.Errorf("legacy wording not removed:\n%s", text)
+	}
+	if !strings.Contains(text, "# Copyright 2019 Oregon State University") || !strings.Contains(text, "SPDX-License-Identifier: Apache-2.0") {
+		t.Errorf("expected current template with the original year:\n%s", text)
+	}
+	if !strings.HasSuffix(text, "\n\nimport os\n") {
+		t.Errorf("code after the legacy header changed:\n%s", text)
+	}
+
+	// Third-party notices and unheadered files are left alone
+	for _, source := range []string{"# Copyright 2020 Other Corp\n\nimport os\n", "import os\n"} {
+		if _, result := ProcessContent("other.py", []byte(source), config, opts); result.Modified {
+			t.Errorf("migrate modified a file without a legacy header: %q", source)
+		}
+	}
+}
+
+func TestExtensionlessNodeScriptUsesSlashComments(t *testing.T) {
+	source := "#!/usr/bin/env node\nconsole.log('hi');\n"
+	path := writeTempFile(t, "serve", source)
+	config := testConfig()
+
+	style, ok := GetCommentStyle(path)
+	if !ok || style.Line != "//" {
+		t.Fatalf("expected // comment style for node script, got %+v (ok=%v)", style, ok)
+	}
+
+	result := ProcessFile(path, config, false, false, false)
+	if result.Action != "ADD" || !result.Modified {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(content), "#!/usr/bin/env node\n") || !strings.Contains(string(content), "\n// Copyright") {
+		t.Errorf("expected shebang followed by // header, got:\n%s", content)
+	}
+	if strings.Contains(string(content), "# SPDX") {
+		t.Errorf("header written with # comments:\n%s", content)
+	}
+
+	// Unknown interpreters keep the # default
+	other := writeTempFile(t, "tool", "#!/bin/bash\necho hi\n")
+	if style, _ := GetCommentStyle(other); style.Line != "#" {
+		t.Errorf("expected # for bash script, got %q", style.Line)
+	}
+}
+
+func TestShebangInterpreter(t *testing.T) {
+	cases := map[string]string{
+		"#!/bin/sh":                            "sh",
+		"#!/usr/bin/env python3":               "python",
+		"#!/usr/bin/python3.11 -u":             "python",
+		"#!/usr/bin/env -S node --no-warnings": "node",
+		"#!/usr/bin/env -u LANG LC_ALL=C perl": "perl",
+		"#! /usr/local/bin/lua5.4":             "lua",
+		"#!/usr/bin/tclsh8.6":                  "tclsh",
+		"#!":                                   "",
+		"// not a shebang":                     "",
+	}
+	for line, want := range cases {
+		if got := shebangInterpreter(line); got != want {
+			t.Errorf("shebangInterpreter(%q) = %q, want %q", line, got, want)
+		}
+	}
+
+	styles := map[string]string{
+		"#!/usr/bin/env lua\nprint(1)\n":  "--",
+		"#!/usr/bin/env tclsh\nputs hi\n": "#",
+		"#!/usr/bin/env ruby\nputs 1\n":   "#",
+		"#!/opt/bin/unknownsh\necho hi\n": "#", // unknown interpreter falls back to #
+	}
+	for source, want := range styles {
+		style, ok := getCommentStyleForContent("script", []byte(source), nil)
+		if !ok || style.Line != want {
+			t.Errorf("style for %q = %q (ok=%v), want %q", source, style.Line, ok, want)
+		}
+	}
+}
+
+func TestExtractLicenseID(t *testing.T) {
+	cases := map[string]string{
+		"// SPDX-License-Identifier: Apache-2.0":         "Apache-2.0",
+		"# SPDX-License-Identifier: MIT OR Apache-2.0":   "MIT OR Apache-2.0",
+		"/* SPDX-License-Identifier: GPL-2.0-only */":    "GPL-2.0-only",
+		"<!-- SPDX-License-Identifier: BSD-3-Clause -->": "BSD-3-Clause",
+		"// no identifier here":                          "",
+	}
+	for line, want := range cases {
+		if got := extractLicenseID(line); got != want {
+			t.Errorf("extractLicenseID(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestCopyrightLineDetection(t *testing.T) {
+	genuine := []string{
+		"// Copyright 2009 The Go Authors. All rights reserved.",     // Go
+		"# Copyright (c) Microsoft Corporation.",                     // Microsoft
+		" * Copyright (C) 1989, 1991 Free Software Foundation, Inc.", // GPL
+		"/* Copyright 2014 The Kubernetes Authors. */",               // Kubernetes
+		"// Copyright The Kubernetes Authors.",                       // newer Kubernetes style
+		"# Copyright Contributors to the OpenTelemetry project",      // CNCF
+		"Copyright © 2019 Apple Inc.",                                // ©
+		"(c) Copyright 2004 IBM Corp.",                               // IBM
+		"-- Copyright 2010-2020 PostgreSQL Global Development Group", // SQL
+		"<!-- Copyright 2022 Example Org -->",                        // HTML
+		`"""Copyright 2018 Google LLC"""`,                            // Python docstring
+		"Copyright-Holder: Jane Doe",                                 // metadata form
+		"Copyright: 2015 Debian Project",                             // DEP-5
+		"// Copyright by the Rust Project Developers",                // "by"
+	}
+	for _, line := range genuine {
+		if !isCopyrightLine(line) {
+			t.Errorf("copyright notice not detected: %q", line)
+		}
+	}
+
+	mentions := []string{
+		"// Copyright notice: do not remove",
+		"# see the COPYRIGHT file for details",
+		"// copyrights are checked below",
+		"const copyrightYear = 2024",
+		"printf(\"Copyright %d\\n\", year);",
+	}
+	for _, line := range mentions {
+		if isCopyrightLine(line) {
+			t.Errorf("mention misdetected as copyright notice: %q", line)
+		}
+	}
+
+	// An unrelated mention no longer blocks the header as third-party
+	info := DetectHeaderInContent([]byte("// Copyright notice: do not remove\npackage main\n"))
+	if info.HasThirdPartyCopyright {
+		t.Error("unrelated copyright mention flagged as third-party")
+	}
+}
+
+func TestOwnerAliasesAllowRemoval(t *testing.T) {
+	source := "# Copyright 2020 Jane  Doe\n# SPDX-License-Identifier: MIT\n\nprint(1)\n"
+	config := testConfig()
+	config.FullName = "Jane Q. Doe"
+	config.Organization = "Acme University"
+
+	headerInfo := DetectHeaderInContent([]byte(source))
+	if CanRemoveHeaderContent([]byte(source), headerInfo, config) {
+		t.Fatal("header under another name removable without aliases")
+	}
+
+	config.OwnerAliases = []string{"Jane Doe"}
+	if !CanRemoveHeaderContent([]byte(source), headerInfo, config) {
+		t.Error("alias did not satisfy the ownership check")
+	}
+
+	config.OwnerAliases = []string{"  "}
+	if CanRemoveHeaderContent([]byte(source), headerInfo, config) {
+		t.Error("blank alias matched every header")
+	}
+}
+
+func TestOwnershipIgnoresCommentMarkers(t *testing.T) {
+	config := testConfig()
+	config.FullName = "Jane Q. Doe"
+	config.Organization = "Acme Research Institute"
+
+	sources := map[string]string{
+		"wrapped //": "// Copyright 2024 Jane Q.\n// Doe\n// SPDX-License-Identifier: MIT\n\npackage main\n",
+		"spaced #":   "#   Copyright 2024   Jane   Q.  Doe\n# SPDX-License-Identifier: MIT\n\nprint(1)\n",
+		"block":      "/*\n * Copyright 2024 Acme Research\n *   Institute\n * SPDX-License-Identifier: Apache-2.0\n */\n\nbody {}\n",
+		"per line":   "/* Copyright 2024 Acme */\n/* Research Institute */\n/* SPDX-License-Identifier: Apache-2.0 */\n\nint x;\n",
+	}
+	for name, source := range sources {
+		headerInfo := DetectHeaderInContent([]byte(source))
+		if !CanRemoveHeaderContent([]byte(source), headerInfo, config) {
+			t.Errorf("%s: ownership not recognized in\n%s", name, source)
+		}
+	}
+
+	foreign := "// Copyright 2024 Jane Roe\n// SPDX-License-Identifier: MIT\n\npackage main\n"
+	if CanRemoveHeaderContent([]byte(foreign), DetectHeaderInContent([]byte(foreign)), config) {
+		t.Error("foreign header recognized as ours")
+	}
+
+	if got := stripCommentMarkers("<!-- Copyright 2024 Jane -->"); got != "Copyright 2024 Jane" {
+		t.Errorf("stripCommentMarkers left markers: %q", got)
+	}
+	if got := stripCommentMarkers("C Fortran comment by ABC"); got != "Fortran comment by ABC" {
+		t.Errorf("stripCommentMarkers mangled Fortran line: %q", got)
+	}
+}
+
+// assertOneBlankAfterHeader checks that the header in content ends with the
+// "Developed by" block and is followed by one blank line and then code
+func assertOneBlankAfterHeader(t *testing.T, name, content, code string) {
+	t.Helper()
+	lines := strings.Split(content, "\n")
+	last := -1
+	for i, line := range lines {
+		if strings.Contains(line, "Test Lab") {
+			last = i
+		}
+	}
+	if last == -1 || last+2 >= len(lines) {
+		t.Fatalf("%s: header end not found in\n%s", name, content)
+	}
+	if lines[last+1] != "" || lines[last+2] != code {
+		t.Errorf("%s: expected one blank line then %q after the header, got\n%s", name, code, content)
+	}
+}
+
+func TestOneBlankLineBetweenHeaderAndCode(t *testing.T) {
+	config := testConfig()
+	cases := []struct {
+		name, filename, source, code string
+		force                        bool
+	}{
+		{"add", "a.py", "print(1)\n", "print(1)", false},
+		{"add with leading blanks", "a.py", "\n\n\nprint(1)\n", "print(1)", false},
+		{"add after shebang", "a.sh", "#!/bin/sh\n\n\necho hi\n", "echo hi", false},
+		{"replace", "a.py", "# Copyright 2020 Other Corp\n# SPDX-License-Identifier: MIT\nprint(1)\n", "print(1)", true},
+		{"replace with blanks", "a.py", "# Copyright 2020 Other Corp\n# SPDX-License-Identifier: MIT\n\n\n\nprint(1)\n", "print(1)", true},
+	}
+	for _, tc := range cases {
+		out, result := ProcessContent(tc.filename, []byte(tc.source), config, ProcessOptions{ForceReplace: tc.force, ReplaceThirdParty: tc.force})
+		if !result.Modified {
+			t.Errorf("%s: not modified: %s (%s)", tc.name, result.Action, result.Reason)
+			continue
+		}
+		assertOneBlankAfterHeader(t, tc.name, string(out), tc.code)
+	}
+
+	// A file with only blank lines gets just the header, no trailing blanks
+	out, _ := ProcessContent("a.py", []byte("\n\n"), config, ProcessOptions{})
+	if strings.HasSuffix(string(out), "\n\n") {
+		t.Errorf("blank-only file kept trailing blank lines:\n%q", out)
+	}
+}
+
+func TestLoadConfigUpgradesVersion1(t *testing.T) {
+	v1 := "FULL_NAME: Jane Doe\nDEFAULT_ROLE: Staff\nDEPT_OR_LAB: Research Computing\nORGANIZATION: Oregon State University\n"
+	path := writeTempFile(t, "licer.yml", v1)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("v1 config rejected: %v", err)
+	}
+	if config.Version != configVersion || config.FullName != "Jane Doe" {
+		t.Errorf("unexpected upgraded config: %+v", config)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "VERSION: 2") || !strings.Contains(string(data), "FULL_NAME: Jane Doe") {
+		t.Errorf("config file not rewritten with VERSION:\n%s", data)
+	}
+
+	// Missing required fields are still an error, and the file is untouched
+	incomplete := writeTempFile(t, "licer.yml", "FULL_NAME: Jane Doe\n")
+	if _, err := loadConfig(incomplete); err == nil {
+		t.Error("incomplete config accepted")
+	}
+	if data, _ := os.ReadFile(incomplete); string(data) != "FULL_NAME: Jane Doe\n" {
+		t.Errorf("incomplete config was rewritten:\n%s", data)
+	}
+}
+
+func TestTOMLConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configDir := filepath.Join(home, ".config")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	tomlPath := filepath.Join(configDir, "licer.toml")
+	source := "FULL_NAME = \"Jane Doe\"\nDEFAULT_ROLE = \"Student\"\nDEPT_OR_LAB = \"Physics\"\nORGANIZATION = \"Oregon State University\"\nOWNER_ALIASES = [\"J. Doe\"]\n"
+	if err := os.WriteFile(tomlPath, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	configPath, err := getConfigPath()
+	if err != nil || configPath != tomlPath {
+		t.Fatalf("expected %s to be picked up, got %q (%v)", tomlPath, configPath, err)
+	}
+
+	config, err := LoadExistingConfig()
+	if err != nil {
+		t.Fatalf("failed to load TOML config: %v", err)
+	}
+	if config.FullName != "Jane Doe" || config.DefaultRole != "Student" || len(config.OwnerAliases) != 1 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	// The version upgrade rewrites the file as TOML, not YAML
+	data, _ := os.ReadFile(tomlPath)
+	if !strings.Contains(string(data), "VERSION = 2") || !strings.Contains(string(data), `FULL_NAME = "Jane Doe"`) {
+		t.Errorf("config not rewritten as TOML:\n%s", data)
+	}
+
+	// licer.yml stays the default when both exist
+	yamlPath := filepath.Join(configDir, "licer.yml")
+	os.WriteFile(yamlPath, []byte("FULL_NAME: A\n"), 0644)
+	if configPath, _ := getConfigPath(); configPath != yamlPath {
+		t.Errorf("expected licer.yml to take precedence, got %s", configPath)
+	}
+}
+
+// BenchmarkProcessLargeFilesWithHeader re-runs licer over a directory of
+// large generated sources that already carry a header, the common case
+// for repeated runs and the pre-commit hook
+func BenchmarkProcessLargeFilesWithHeader(b *testing.B) {
+	dir := b.TempDir()
+	config := testConfig()
+	body := strings.Repeat("var generated = []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07}\n", 64*1024)
+
+	var files []string
+	for i := 0; i < 8; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("gen%d.go", i))
+		if err := os.WriteFile(path, []byte("package gen\n\n"+body), 0644); err != nil {
+			b.Fatal(err)
+		}
+		if result := ProcessFile(path, config, false, false, false); !result.Modified {
+			b.Fatalf("setup failed: %s (%s)", result.Action, result.Reason)
+		}
+		files = append(files, path)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range files {
+			if result := ProcessFile(path, config, false, false, false); result.Action != "SKIP" {
+				b.Fatalf("expected SKIP, got %s (%s)", result.Action, result.Reason)
+			}
+		}
+	}
+}
+
+func TestLargeFilesAreProcessedWhole(t *testing.T) {
+	config := testConfig()
+	body := strings.Repeat("x = 1\n", 2*headerScanBytes/6) + "last_line = True\n"
+	path := writeTempFile(t, "big.py", body)
+
+	result := ProcessFile(path, config, false, false, false)
+	if result.Action != "ADD" || !result.Modified {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+	content, _ := os.ReadFile(path)
+	if !strings.HasSuffix(string(content), "\n\n"+body) {
+		t.Fatal("body of a large file was truncated")
+	}
+
+	// The second run is decided from the prefix, the file stays intact
+	if result := ProcessFile(path, config, false, false, false); result.Action != "SKIP" || result.Reason != "Header already exists" {
+		t.Errorf("expected SKIP, got %s (%s)", result.Action, result.Reason)
+	}
+	if again, _ := os.ReadFile(path); string(again) != string(content) {
+		t.Error("skipped large file was modified")
+	}
+
+	// --force on a large file still rewrites it in full
+	ProcessFile(path, config, true, false, false)
+	if forced, _ := os.ReadFile(path); !strings.HasSuffix(string(forced), "last_line = True\n") || strings.Count(string(forced), "SPDX") != 1 {
+		t.Error("force replace on a large file lost content or duplicated the header")
+	}
+
+	// A first line longer than the scan window has no complete line to decide on
+	long := writeTempFile(t, "long.js", strings.Repeat("a", headerScanBytes+10)+"\n")
+	if result := ProcessFile(long, config, false, false, false); result.Action != "ADD" {
+		t.Errorf("expected ADD for a file with a very long first line, got %s (%s)", result.Action, result.Reason)
+	}
+}
+
+func TestRemoveOnLargeFiles(t *testing.T) {
+	config := testConfig()
+	body := strings.Repeat("x = 1\n", 2*headerScanBytes/6)
+
+	// No header: decided from the prefix, file untouched
+	bare := writeTempFile(t, "bare.py", body)
+	if result := ProcessFile(bare, config, false, true, false); result.Action != "SKIP" || result.Reason != "No header found" {
+		t.Errorf("expected SKIP (No header found), got %s (%s)", result.Action, result.Reason)
+	}
+
+	// Our header: removed, and the whole body survives
+	ours := writeTempFile(t, "ours.py", body)
+	ProcessFile(ours, config, false, false, false)
+	if result := ProcessFile(ours, config, false, true, false); result.Action != "REMOVE" {
+		t.Fatalf("expected REMOVE, got %s (%s)", result.Action, result.Reason)
+	}
+	if content, _ := os.ReadFile(ours); string(content) != body {
+		t.Error("removing the header from a large file changed its body")
+	}
+}
+
+func TestFixLicenseRewritesOwnMismatchedHeader(t *testing.T) {
+	config := testConfig() // Staff: Apache-2.0
+	source := "# Copyright (c) 2019 Test User\n#\n# SPDX-License-Identifier: MIT\n\ndef main():\n    pass\n"
+	path := writeTempFile(t, "tool.py", source)
+	opts := ProcessOptions{FixLicense: true}
+
+	result := ProcessFileWithOptions(path, config, opts)
+	if result.Action != "REPLACE" || !result.Modified {
+		t.Fatalf("expected REPLACE, got %s (%s)", result.Action, result.Reason)
+	}
+
+	content, _ := os.ReadFile(path)
+	text := string(content)
+	if !strings.Contains(text, "SPDX-License-Identifier: Apache-2.0") || strings.Contains(text, "MIT") {
+		t.Errorf("license not corrected:\n%s", text)
+	}
+	if !strings.Contains(text, "Copyright 2019") || !strings.Contains(text, "Test User") {
+		t.Errorf("year or author not preserved:\n%s", text)
+	}
+	if !strings.HasSuffix(text, "\n\ndef main():\n    pass\n") {
+		t.Errorf("code not preserved:\n%s", text)
+	}
+
+	// A second run finds the license correct
+	if result := ProcessFileWithOptions(path, config, opts); result.Action != "SKIP" || result.Reason != "License already correct" {
+		t.Errorf("expected SKIP (License already correct), got %s (%s)", result.Action, result.Reason)
+	}
+
+	// Someone else's MIT header is left alone
+	foreign := "# Copyright (c) 2019 Someone Else\n# SPDX-License-Identifier: MIT\n\nprint(1)\n"
+	foreignPath := writeTempFile(t, "other.py", foreign)
+	if result := ProcessFileWithOptions(foreignPath, config, opts); result.Modified {
+		t.Errorf("foreign header modified: %s (%s)", result.Action, result.Reason)
+	}
+}
+
+func TestNormalizeRewritesMessyOwnHeaders(t *testing.T) {
+	config := testConfig()
+	opts := ProcessOptions{Normalize: true}
+	canonical := "Copyright 2019 Oregon State University\n\nLicensed under the Apache License, Version 2.0.\nSee the LICENSE file for details.\nSPDX-License-Identifier: Apache-2.0\n\nDeveloped by: Test User\n              Test Lab"
+
+	tests := []struct {
+		name, filename, source, code string
+	}{
+		{
+			"indentation and blank lines", "main.py",
+			"#!/usr/bin/env python3\n\n#   Copyright 2019 Oregon State University\n#\n#\n#  Licensed under the Apache License, Version 2.0.\n# See the LICENSE file for details.\n#    SPDX-License-Identifier: Apache-2.0\n#\n# Developed by: Test User\n#                 Test Lab\n#\n\nprint('hi')\n",
+			"print('hi')\n",
+		},
+		{
+			"block comment in a // language", "main.go",
+			"/*\n * Copyright 2019 Oregon State University\n *\n * Licensed under the Apache License, Version 2.0.\n * See the LICENSE file for details.\n * SPDX-License-Identifier: Apache-2.0\n *\n * Developed by: Test User\n *               Test Lab\n */\n\npackage main\n",
+			"package main\n",
+		},
+		{
+			"mixed // and /* */", "app.js",
+			"// Copyright 2019 Oregon State University\n//\n/* Licensed under the Apache License, Version 2.0.\n * See the LICENSE file for details. */\n// SPDX-License-Identifier: Apache-2.0\n//\n// Developed by: Test User\n//               Test Lab\n\nconsole.log(1)\n",
+			"console.log(1)\n",
+		},
+	}
+	for _, tt := range tests {
+		updated, result := ProcessContent(tt.filename, []byte(tt.source), config, opts)
+		if result.Action != "REPLACE" {
+			t.Errorf("%s: expected REPLACE, got %s (%s)", tt.name, result.Action, result.Reason)
+			continue
+		}
+		style, _ := getCommentStyleForContent(tt.filename, []byte(tt.source), nil)
+		want := FormatHeader(canonical, style) + "\n\n" + tt.code
+		if strings.HasPrefix(tt.source, "#!") {
+			want = "#!/usr/bin/env python3\n\n" + want
+		}
+		if string(updated) != want {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.name, want, updated)
+		}
+
+		// Normalizing is idempotent
+		if _, result := ProcessContent(tt.filename, updated, config, opts); result.Action != "SKIP" || result.Reason != "Header already normalized" {
+			t.Errorf("%s: second run: %s (%s)", tt.name, result.Action, result.Reason)
+		}
+	}
+
+	// Third-party headers are left alone, however messy
+	thirdParty := "#   Copyright 2019 Other Corp\n#\n#\n# SPDX-License-Identifier: MIT\n\nx = 1\n"
+	if _, result := ProcessContent("vendor.py", []byte(thirdParty), config, opts); result.Reason != "Header ownership mismatch (safety check)" {
+		t.Errorf("third-party header was normalized: %s (%s)", result.Action, result.Reason)
+	}
+}
+
+func TestReplaceOwnerRenamesDepartment(t *testing.T) {
+	config := testConfig()
+	config.DeptOrLab = "UIT/ARCS"
+	header := FormatHeader(generateHeaderForYears(config, "2019-2021"), CommentStyles[".py"])
+	source := "#!/usr/bin/env python3\n" + header + "\n\n# UIT/ARCS tooling\nprint(1)\n"
+	opts := ProcessOptions{ReplaceOwner: []OwnerReplacement{{Old: "UIT/ARCS", New: "University IT"}}}
+
+	updated, result := ProcessContent("tool.py", []byte(source), config, opts)
+	if result.Action != "REPLACE" || !result.Modified {
+		t.Fatalf("expected REPLACE, got %s (%s)", result.Action, result.Reason)
+	}
+	want := strings.Replace(source, "#               UIT/ARCS\n", "#               University IT\n", 1)
+	if string(updated) != want {
+		t.Errorf("unexpected rename, want:\n%s\ngot:\n%s", want, updated)
+	}
+
+	// Nothing left to rename, and third-party headers are left alone
+	if _, result := ProcessContent("tool.py", updated, config, opts); result.Modified {
+		t.Errorf("second run modified the file: %s (%s)", result.Action, result.Reason)
+	}
+	other := "# Copyright 2020 Other Corp, UIT/ARCS fork\n# SPDX-License-Identifier: MIT\n\nprint(1)\n"
+	if _, result := ProcessContent("other.py", []byte(other), config, opts); result.Modified {
+		t.Errorf("third-party header renamed: %s (%s)", result.Action, result.Reason)
+	}
+
+	for _, value := range []string{"UIT/ARCS", "=University IT", "UIT/ARCS=", "OSU=OSU"} {
+		if _, err := ParseOwnerReplacement(value); err == nil {
+			t.Errorf("--replace-owner %q accepted", value)
+		}
+	}
+	if r, err := ParseOwnerReplacement(" UIT/ARCS = University IT "); err != nil || r != (OwnerReplacement{"UIT/ARCS", "University IT"}) {
+		t.Errorf("parsed %+v, %v", r, err)
+	}
+}
+
+func TestDockerfileParserDirectivesStayFirst(t *testing.T) {
+	config := testConfig()
+
+	plain := writeTempFile(t, "Dockerfile", "FROM alpine:3.20\nRUN apk add git\n")
+	if result := ProcessFile(plain, config, false, false, false); result.Action != "ADD" {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+	content, _ := os.ReadFile(plain)
+	if !strings.HasPrefix(string(content), "# Copyright") || !strings.HasSuffix(string(content), "\n\nFROM alpine:3.20\nRUN apk add git\n") {
+		t.Errorf("unexpected Dockerfile layout:\n%s", content)
+	}
+
+	source := "# syntax=docker/dockerfile:1\n# escape=`\nFROM alpine:3.20\n"
+	withDirectives := writeTempFile(t, "Dockerfile", source)
+	if result := ProcessFile(withDirectives, config, false, false, false); result.Action != "ADD" {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+	content, _ = os.ReadFile(withDirectives)
+	lines := strings.Split(string(content), "\n")
+	if lines[0] != "# syntax=docker/dockerfile:1" || lines[1] != "# escape=`" || lines[2] != "" || !strings.HasPrefix(lines[3], "# Copyright") {
+		t.Errorf("parser directives not kept above the header:\n%s", content)
+	}
+	if firstStatement(string(content)) != "FROM alpine:3.20" {
+		t.Errorf("instructions displaced:\n%s", content)
+	}
+
+	// Force replace and remove keep the directives in place
+	ProcessFile(withDirectives, config, true, false, false)
+	content, _ = os.ReadFile(withDirectives)
+	if !strings.HasPrefix(string(content), "# syntax=docker/dockerfile:1\n# escape=`\n\n# Copyright") {
+		t.Errorf("directives moved by force replace:\n%s", content)
+	}
+	if result := ProcessFile(withDirectives, config, false, true, false); result.Action != "REMOVE" {
+		t.Fatalf("expected REMOVE, got %s (%s)", result.Action, result.Reason)
+	}
+	if content, _ = os.ReadFile(withDirectives); string(content) != source {
+		t.Errorf("remove did not restore the original Dockerfile:\n%s", content)
+	}
+}
+
+func TestWindowsScriptFirstLinesStayFirst(t *testing.T) {
+	config := testConfig()
+
+	bat := writeTempFile(t, "build.bat", "@echo off\r\nset X=1\r\n")
+	if result := ProcessFile(bat, config, false, false, false); result.Action != "ADD" {
+		t.Fatalf("expected ADD for .bat, got %s (%s)", result.Action, result.Reason)
+	}
+	content, _ := os.ReadFile(bat)
+	lines := strings.Split(string(content), "\n")
+	if strings.TrimSpace(lines[0]) != "@echo off" || !strings.HasPrefix(lines[2], "REM Copyright") {
+		t.Errorf("@echo off not kept above the header:\n%s", content)
+	}
+
+	source := "#Requires -Version 7.0\n#Requires -Modules Az\n[CmdletBinding()]\nparam(\n    [string]$Name\n)\nWrite-Output $Name\n"
+	ps1 := writeTempFile(t, "deploy.ps1", source)
+	if result := ProcessFile(ps1, config, false, false, false); result.Action != "ADD" {
+		t.Fatalf("expected ADD for .ps1, got %s (%s)", result.Action, result.Reason)
+	}
+	content, _ = os.ReadFile(ps1)
+	if !strings.HasPrefix(string(content), "#Requires -Version 7.0\n#Requires -Modules Az\n\n# Copyright") {
+		t.Errorf("#Requires statements not kept above the header:\n%s", content)
+	}
+	// Comments may precede the param block, so it stays the first statement
+	if firstStatement(string(content)) != "[CmdletBinding()]" {
+		t.Errorf("param block is no longer the first statement:\n%s", content)
+	}
+
+	if result := ProcessFile(ps1, config, false, true, false); result.Action != "REMOVE" {
+		t.Fatalf("expected REMOVE, got %s (%s)", result.Action, result.Reason)
+	}
+	if content, _ = os.ReadFile(ps1); string(content) != source {
+		t.Errorf("remove did not restore the original script:\n%s", content)
+	}
+}
+
+func TestGherkinFeatureFiles(t *testing.T) {
+	config := testConfig()
+	body := "Feature: Login\n  Scenario: Valid user\n    Given a registered user\n"
+	tests := []struct {
+		name, filename, source, prefix string
+	}{
+		{"plain", "login.feature", body, "# Copyright"},
+		{"language directive", "login.feature", "# language: de\n" + body, "# language: de\n\n# Copyright"},
+		{"cucumber", "login.cucumber", "#language:fr\n" + body, "#language:fr\n\n# Copyright"},
+	}
+	for _, tt := range tests {
+		path := writeTempFile(t, tt.filename, tt.source)
+		if result := ProcessFile(path, config, false, false, false); result.Action != "ADD" {
+			t.Fatalf("%s: expected ADD, got %s (%s)", tt.name, result.Action, result.Reason)
+		}
+		content, _ := os.ReadFile(path)
+		if !strings.HasPrefix(string(content), tt.prefix) || !strings.HasSuffix(string(content), "\n\n"+body) {
+			t.Errorf("%s: unexpected layout:\n%s", tt.name, content)
+		}
+
+		if result := ProcessFile(path, config, false, false, false); result.Action != "SKIP" {
+			t.Errorf("%s: header added twice: %s (%s)", tt.name, result.Action, result.Reason)
+		}
+		if result := ProcessFile(path, config, false, true, false); result.Action != "REMOVE" {
+			t.Fatalf("%s: expected REMOVE, got %s (%s)", tt.name, result.Action, result.Reason)
+		}
+		if content, _ = os.ReadFile(path); string(content) != tt.source {
+			t.Errorf("%s: remove did not restore the original:\n%s", tt.name, content)
+		}
+	}
+}
+
+func TestTclAwkSedScripts(t *testing.T) {
+	config := testConfig()
+	trampoline := "#!/bin/sh\n# the next line restarts using tclsh \\\nexec tclsh \"$0\" \"$@\"\n"
+	tests := []struct {
+		name, filename, preamble, body string
+	}{
+		{"tcl", "build.tcl", "", "puts hello\n"},
+		{"tcl env shebang", "build.tcl", "#!/usr/bin/env tclsh\n", "puts hello\n"},
+		{"tcl trampoline", "build.tcl", trampoline, "puts hello\n"},
+		{"extensionless tcl trampoline", "build", trampoline, "puts hello\n"},
+		{"extensionless tclsh", "build", "#!/usr/bin/tclsh8.6\n", "puts hello\n"},
+		{"awk", "sum.awk", "#!/usr/bin/awk -f\n", "{ s += $1 } END { print s }\n"},
+		{"extensionless gawk", "sum", "#!/usr/bin/env gawk -f\n", "{ s += $1 } END { print s }\n"},
+		{"sed", "fix.sed", "#!/bin/sed -f\n", "s/foo/bar/g\n"},
+		{"sed without shebang", "fix.sed", "", "s/foo/bar/g\n"},
+	}
+	for _, tt := range tests {
+		if filepath.Ext(tt.filename) != "" && !ShouldProcessFile(tt.filename) {
+			t.Errorf("%s: %s is not processed", tt.name, tt.filename)
+		}
+		source := tt.preamble + tt.body
+		updated, result := ProcessContent(tt.filename, []byte(source), config, ProcessOptions{})
+		if result.Action != "ADD" {
+			t.Errorf("%s: expected ADD, got %s (%s)", tt.name, result.Action, result.Reason)
+			continue
+		}
+		prefix := "# Copyright"
+		if tt.preamble != "" {
+			prefix = tt.preamble + "\n# Copyright"
+		}
+		if !strings.HasPrefix(string(updated), prefix) || !strings.HasSuffix(string(updated), "\n\n"+tt.body) {
+			t.Errorf("%s: unexpected layout:\n%s", tt.name, updated)
+		}
+
+		if _, result := ProcessContent(tt.filename, updated, config, ProcessOptions{}); result.Action != "SKIP" {
+			t.Errorf("%s: header added twice: %s (%s)", tt.name, result.Action, result.Reason)
+		}
+		removed, result := ProcessContent(tt.filename, updated, config, ProcessOptions{RemoveMode: true})
+		if result.Action != "REMOVE" || string(removed) != source {
+			t.Errorf("%s: remove gave %s (%s):\n%s", tt.name, result.Action, result.Reason, removed)
+		}
+	}
+}
+
+func TestAssemblySources(t *testing.T) {
+	tests := []struct {
+		name, filename, marker, body string
+		lineComments                 map[string]string
+	}{
+		{"nasm", "boot.asm", ";", "section .text\n\tmov eax, 1\n", nil},
+		{"gas", "start.s", "#", ".globl _start\n_start:\n\tmovl $1, %eax\n", nil},
+		{"gas with cpp", "entry.S", "#", "#include <asm/unistd.h>\n.globl _start\n", nil},
+		{"configured marker", "start.s", ";", ".globl _start\n", map[string]string{"s": ";"}},
+		{"configured marker with cpp", "entry.S", "//", ".globl _start\n", map[string]string{".s": "//"}},
+	}
+	for _, tt := range tests {
+		config := testConfig()
+		config.LineComments = tt.lineComments
+		if !ShouldProcessFile(tt.filename) {
+			t.Errorf("%s: %s is not processed", tt.name, tt.filename)
+		}
+		updated, result := ProcessContent(tt.filename, []byte(tt.body), config, ProcessOptions{})
+		if result.Action != "ADD" {
+			t.Errorf("%s: expected ADD, got %s (%s)", tt.name, result.Action, result.Reason)
+			continue
+		}
+		if !strings.HasPrefix(string(updated), tt.marker+" Copyright") || !strings.HasSuffix(string(updated), tt.marker+"               Test Lab\n\n"+tt.body) {
+			t.Errorf("%s: unexpected layout:\n%s", tt.name, updated)
+		}
+
+		if _, result := ProcessContent(tt.filename, updated, config, ProcessOptions{}); result.Action != "SKIP" {
+			t.Errorf("%s: header added twice: %s (%s)", tt.name, result.Action, result.Reason)
+		}
+		removed, result := ProcessContent(tt.filename, updated, config, ProcessOptions{RemoveMode: true})
+		if result.Action != "REMOVE" || string(removed) != tt.body {
+			t.Errorf("%s: remove gave %s (%s):\n%s", tt.name, result.Action, result.Reason, removed)
+		}
+	}
+
+	for _, comments := range []map[string]string{{".s": "@"}, {".nope": "#"}, {".py": "//"}} {
+		if err := validateLineComments(comments); err == nil {
+			t.Errorf("LINE_COMMENTS %v accepted", comments)
+		}
+	}
+}
+
+func TestCoffeeElmPureScriptSources(t *testing.T) {
+	config := testConfig()
+	tests := []struct {
+		name, filename, marker, preamble, body string
+	}{
+		{"coffeescript", "app.coffee", "#", "", "square = (x) -> x * x\n"},
+		{"coffeescript shebang", "serve", "#", "#!/usr/bin/env coffee\n", "http = require 'http'\n"},
+		{"elm", "Main.elm", "--", "", "module Main exposing (main)\n\nimport Html\n"},
+		{"purescript", "Main.purs", "--", "", "module Main where\n\nimport Prelude\n"},
+	}
+	for _, tt := range tests {
+		if filepath.Ext(tt.filename) != "" && !ShouldProcessFile(tt.filename) {
+			t.Errorf("%s: %s is not processed", tt.name, tt.filename)
+		}
+		source := tt.preamble + tt.body
+		updated, result := ProcessContent(tt.filename, []byte(source), config, ProcessOptions{})
+		if result.Action != "ADD" {
+			t.Errorf("%s: expected ADD, got %s (%s)", tt.name, result.Action, result.Reason)
+			continue
+		}
+		prefix := tt.marker + " Copyright"
+		if tt.preamble != "" {
+			prefix = tt.preamble + "\n" + prefix
+		}
+		if !strings.HasPrefix(string(updated), prefix) ||
+			!strings.Contains(string(updated), "\n"+tt.marker+" SPDX-License-Identifier: Apache-2.0\n") ||
+			!strings.HasSuffix(string(updated), tt.marker+"               Test Lab\n\n"+tt.body) {
+			t.Errorf("%s: unexpected layout:\n%s", tt.name, updated)
+		}
+
+		if _, result := ProcessContent(tt.filename, updated, config, ProcessOptions{}); result.Action != "SKIP" {
+			t.Errorf("%s: header added twice: %s (%s)", tt.name, result.Action, result.Reason)
+		}
+		removed, result := ProcessContent(tt.filename, updated, config, ProcessOptions{RemoveMode: true})
+		if result.Action != "REMOVE" || string(removed) != source {
+			t.Errorf("%s: remove gave %s (%s):\n%s", tt.name, result.Action, result.Reason, removed)
+		}
+	}
+
+	// Headers in the block form of each language are bounded by their
+	// delimiters, so they are removed and replaced as a whole
+	blocks := []struct {
+		name, filename, header, body string
+	}{
+		{"coffeescript", "app.coffee", "###\nCopyright 2020 Oregon State University\nSPDX-License-Identifier: Apache-2.0\n###\n", "\n###\nSquares a number\n###\nsquare = (x) -> x * x\n"},
+		{"elm", "Main.elm", "{-\nCopyright 2020 Oregon State University\nSPDX-License-Identifier: Apache-2.0\n-}\n", "\nmodule Main exposing (main)\n"},
+		{"purescript", "Main.purs", "{- Copyright 2020 Oregon State University\n   SPDX-License-Identifier: Apache-2.0\n-}\n", "\nmodule Main where\n"},
+	}
+	for _, tt := range blocks {
+		source := tt.header + tt.body
+		info := DetectHeaderInFile(tt.filename, []byte(source))
+		if want := strings.Count(tt.header, "\n") - 1; !info.HasHeader || info.StartLine != 0 || info.EndLine != want {
+			t.Errorf("%s block: bounded as lines %d-%d, want 0-%d", tt.name, info.StartLine, info.EndLine, want)
+			continue
+		}
+		removed, result := ProcessContent(tt.filename, []byte(source), config, ProcessOptions{RemoveMode: true})
+		if result.Action != "REMOVE" || strings.TrimLeft(string(removed), "\n") != strings.TrimLeft(tt.body, "\n") {
+			t.Errorf("%s block: remove gave %s (%s):\n%s", tt.name, result.Action, result.Reason, removed)
+		}
+		replaced, result := ProcessContent(tt.filename, []byte(source), config, ProcessOptions{ForceReplace: true})
+		if result.Action != "REPLACE" || strings.Contains(string(replaced), "2020") || !strings.HasSuffix(string(replaced), tt.body) {
+			t.Errorf("%s block: replace gave %s (%s):\n%s", tt.name, result.Action, result.Reason, replaced)
+		}
+	}
+
+	// Elsewhere a "###" line is an ordinary comment, not a block opener
+	source := "### helpers\n# Copyright 2020 Oregon State University\n# SPDX-License-Identifier: Apache-2.0\n\nimport os\n### more helpers\n"
+	if info := DetectHeaderInFile("tool.py", []byte(source)); info.BlockComment || info.EndLine != 2 {
+		t.Errorf("### bounded a block outside CoffeeScript: %+v", info)
+	}
+}
+
+func TestUTF16FilesKeepTheirEncoding(t *testing.T) {
+	config := testConfig()
+	code := "package main\n\nfunc main() { println(\"héllo\") }\n"
+
+	for _, enc := range []TextEncoding{EncodingUTF16LE, EncodingUTF16BE} {
+		encoded := EncodeText([]byte(code), enc)
+		if !isTextContent(encoded) {
+			t.Errorf("encoding %d: UTF-16 source classified as binary", enc)
+		}
+		if decoded, got := DecodeText(encoded); got != enc || string(decoded) != code {
+			t.Fatalf("encoding %d: round trip gave %d/%q", enc, got, decoded)
+		}
+
+		filename := filepath.Join(t.TempDir(), "main.go")
+		if err := os.WriteFile(filename, encoded, 0644); err != nil {
+			t.Fatal(err)
+		}
+		result := ProcessFile(filename, config, false, false, false)
+		if result.Action != "ADD" {
+			t.Fatalf("encoding %d: expected ADD, got %s (%s)", enc, result.Action, result.Reason)
+		}
+
+		written, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, got := DecodeText(written)
+		if got != enc {
+			t.Fatalf("encoding %d: file was rewritten as %d", enc, got)
+		}
+		if !strings.HasPrefix(string(decoded), "// Copyright") || !strings.HasSuffix(string(decoded), "\n\n"+code) {
+			t.Errorf("encoding %d: unexpected content:\n%s", enc, decoded)
+		}
+
+		if result := ProcessFile(filename, config, false, false, false); result.Action != "SKIP" {
+			t.Errorf("encoding %d: second run should skip, got %s (%s)", enc, result.Action, result.Reason)
+		}
+	}
+}
+
+func TestLatin1FilesKeepTheirEncoding(t *testing.T) {
+	config := testConfig()
+	config.Organization = "Universität Zürich"
+	code := "# Grüße aus Zürich\nprint('café')\n"
+	latin1 := []byte("# Gr\xfc\xdfe aus Z\xfcrich\nprint('caf\xe9')\n")
+
+	path := writeTempFile(t, "main.py", string(latin1))
+	opts := ProcessOptions{Encoding: EncodingLatin1}
+	if result := ProcessFileWithOptions(path, config, opts); result.Action != "ADD" {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(written, append([]byte("\n\n"), latin1...)) {
+		t.Errorf("original Latin-1 bytes were not preserved:\n%q", written)
+	}
+	if !bytes.Contains(written, []byte("Universit\xe4t Z\xfcrich")) || bytes.Contains(written, []byte("Universität")) {
+		t.Errorf("header was not written in Latin-1:\n%q", written)
+	}
+	if decoded := decodeLatin1(written); !strings.HasSuffix(string(decoded), code) {
+		t.Errorf("unexpected content:\n%s", decoded)
+	}
+	if result := ProcessFileWithOptions(path, config, opts); result.Action != "SKIP" {
+		t.Errorf("second run should skip, got %s (%s)", result.Action, result.Reason)
+	}
+
+	// A name Latin-1 has no bytes for is an error, not a UTF-8 header
+	config.FullName = "Łukasz"
+	config.DefaultRole = "Student"
+	path = writeTempFile(t, "other.py", string(latin1))
+	if result := ProcessFileWithOptions(path, config, opts); !strings.HasPrefix(result.Reason, "Error") {
+		t.Errorf("expected an encoding error, got %s (%s)", result.Action, result.Reason)
+	}
+	if content, _ := os.ReadFile(path); !bytes.Equal(content, latin1) {
+		t.Errorf("file was modified despite the error:\n%q", content)
+	}
+}
+
+func TestForceTextProcessesBinaryLookingScripts(t *testing.T) {
+	config := testConfig()
+	script := "#!/bin/sh\necho " + strings.Repeat("\x93quoted\x94 ", 2) + strings.Repeat("\x93\x94", 40) + "\n"
+
+	if _, result := ProcessContent("deploy", []byte(script), config, ProcessOptions{}); result.Action != "SKIP" {
+		t.Fatalf("expected the binary check to skip, got %s (%s)", result.Action, result.Reason)
+	}
+	updated, result := ProcessContent("deploy", []byte(script), config, ProcessOptions{ForceText: true})
+	if result.Action != "ADD" || !strings.HasPrefix(string(updated), "#!/bin/sh\n\n# Copyright") || !strings.HasSuffix(string(updated), script[len("#!/bin/sh\n"):]) {
+		t.Errorf("--force-text: %s (%s)\n%q", result.Action, result.Reason, updated)
+	}
+}
+
+func TestRoleLicensesOverrideStudentLicense(t *testing.T) {
+	config := testConfig()
+	config.DefaultRole = "Student"
+	config.RoleLicenses = map[string]string{"Student": "BSD-3-Clause"}
+
+	if got := GetLicenseType(config); got != "BSD-3-Clause" {
+		t.Fatalf("expected BSD-3-Clause for students, got %s", got)
+	}
+	if header := GenerateHeader(config); !strings.Contains(header, "SPDX-License-Identifier: BSD-3-Clause") {
+		t.Errorf("header does not declare BSD-3-Clause:\n%s", header)
+	}
+
+	licensePath := filepath.Join(t.TempDir(), "LICENSE")
+	if err := createLicenseFile(licensePath, config); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(licensePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(content), "BSD 3-Clause License") {
+		t.Errorf("LICENSE is not BSD 3-Clause:\n%s", content)
+	}
+
+	// Unmapped roles keep their default license
+	config.DefaultRole = "Staff"
+	header := GenerateHeader(config)
+	if !strings.Contains(header, "Licensed under the Apache License, Version 2.0.") || !strings.Contains(header, "SPDX-License-Identifier: Apache-2.0") {
+		t.Errorf("Staff header lost the default Apache license:\n%s", header)
+	}
+}
+
+func TestRoleLicensesAreValidated(t *testing.T) {
+	base := "VERSION: 2\nFULL_NAME: Test User\nDEFAULT_ROLE: Student\nDEPT_OR_LAB: Lab\nORGANIZATION: Org\n"
+	for mapping, wantErr := range map[string]bool{
+		"ROLE_LICENSES:\n  Student: BSD-3-Clause\n": false,
+		"ROLE_LICENSES:\n  Student: GPL-9.0\n":      true,
+		"ROLE_LICENSES:\n  Intern: MIT\n":           true,
+	} {
+		path := writeTempFile(t, "licer.yml", base+mapping)
+		_, err := loadConfig(path)
+		if (err != nil) != wantErr {
+			t.Errorf("%q: got error %v, want error %v", mapping, err, wantErr)
+		}
+	}
+}
+
+func TestLicenseFilesNeverGetHeaders(t *testing.T) {
+	config := testConfig()
+	text := "Permission is hereby granted, free of charge, to any person\n"
+
+	for _, name := range []string{"LICENSE", "COPYING", "NOTICE", "PATENTS", "LICENSE-MIT", "LICENSE.sh", "COPYING.LESSER", "UNLICENSE"} {
+		path := writeTempFile(t, name, text)
+		result := ProcessFile(path, config, true, false, false)
+		if result.Modified {
+			t.Errorf("%s was given a header: %s", name, result.Reason)
+		}
+		if content, _ := os.ReadFile(path); string(content) != text {
+			t.Errorf("%s was modified:\n%s", name, content)
+		}
+	}
+
+	// Source files that merely share the name are still processed
+	for _, name := range []string{"license.py", "notice.go"} {
+		if path := writeTempFile(t, name, "x = 1\n"); !ShouldProcessFile(path) {
+			t.Errorf("%s should still be processed", name)
+		}
+	}
+}
+
+func TestApacheHeaderIsBoundedAsOneBlock(t *testing.T) {
+	config := testConfig() // Staff: the Apache header with blank comment separators
+
+	// Cover the styles of types only processed when opted in, like .md
+	var optIn []string
+	for ext := range DefaultExcludedExtensions {
+		optIn = append(optIn, ext)
+	}
+	SetExtensionOverrides(nil, optIn)
+	defer SetExtensionOverrides(nil, nil)
+
+	for ext, style := range CommentStyles {
+		filename := "example" + ext
+		header := FormatHeader(GenerateHeaderForFile(config, filename), style)
+		last := len(strings.Split(header, "\n")) - 1
+		content := []byte(header + "\n\ncode line\n")
+
+		info := DetectHeaderInContent(content)
+		if !info.HasHeader || info.StartLine != 0 || info.EndLine != last {
+			t.Errorf("%q: header detected at %d-%d, want 0-%d", ext, info.StartLine, info.EndLine, last)
+			continue
+		}
+
+		// The current header is left alone, and replacing an outdated one
+		// must leave no orphaned header lines behind
+		if _, result := ProcessContent(filename, content, config, ProcessOptions{ForceReplace: true}); result.Action != "SKIP" || result.Reason != "Already current" {
+			t.Errorf("%q: --force rewrote the current header (%s, %s)", ext, result.Action, result.Reason)
+		}
+		stale := strings.Replace(string(content), fmt.Sprint(time.Now().Year()), "2019", 1)
+		forced, result := ProcessContent(filename, []byte(stale), config, ProcessOptions{ForceReplace: true})
+		if result.Action != "REPLACE" || string(forced) != string(content) {
+			t.Errorf("%q: --force changed the file (%s):\n%s", ext, result.Action, forced)
+		}
+	}
+}
+
+func TestDocCommentHeadersAreDetected(t *testing.T) {
+	config := testConfig()
+	tests := []struct {
+		name, filename, header, body string
+	}{
+		{"rust inner doc", "lib.rs", "//! Copyright 2020 Oregon State University\n//!\n//! SPDX-License-Identifier: Apache-2.0\n", "\nfn main() {}\n"},
+		{"rust outer doc", "lib.rs", "/// Copyright 2020 Oregon State University\n/// SPDX-License-Identifier: Apache-2.0\n", "\nfn main() {}\n"},
+		{"python docstring", "tool.py", "\"\"\"\nCopyright 2020 Oregon State University\n\nLicensed under the Apache License, Version 2.0.\nSPDX-License-Identifier: Apache-2.0\n\"\"\"\n", "\nimport os\n"},
+		{"python docstring after shebang", "tool.py", "#!/usr/bin/env python3\n'''Copyright 2020 Oregon State University\n\nSPDX-License-Identifier: Apache-2.0\n'''\n", "\nimport os\n"},
+		{"one-line docstring", "tool.py", "\"\"\"Copyright 2020 Oregon State University. SPDX-License-Identifier: Apache-2.0\"\"\"\n", "\nimport os\n"},
+		{"javadoc", "Main.java", "/**\n * Copyright 2020 Oregon State University\n *\n * SPDX-License-Identifier: Apache-2.0\n */\n", "\npackage main;\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := tt.header + tt.body
+			info := DetectHeaderInFile(tt.filename, []byte(source))
+			if !info.HasHeader || info.LicenseID != "Apache-2.0" {
+				t.Fatalf("header not detected: %+v", info)
+			}
+			lines := strings.Split(tt.header, "\n")
+			first := 0
+			if strings.HasPrefix(lines[0], "#!") {
+				first = 1
+			}
+			if info.StartLine != first || info.EndLine != len(lines)-2 {
+				t.Errorf("header bounded as lines %d-%d, want %d-%d", info.StartLine, info.EndLine, first, len(lines)-2)
+			}
+
+			// Not duplicated, and removed or replaced as a whole
+			if _, result := ProcessContent(tt.filename, []byte(source), config, ProcessOptions{}); result.Modified {
+				t.Errorf("header added twice: %s (%s)", result.Action, result.Reason)
+			}
+			removed, result := ProcessContent(tt.filename, []byte(source), config, ProcessOptions{RemoveMode: true})
+			if result.Action != "REMOVE" || strings.Contains(string(removed), "Oregon State") || strings.Contains(string(removed), "\"\"\"") || strings.Contains(string(removed), "*/") {
+				t.Errorf("remove gave %s (%s):\n%s", result.Action, result.Reason, removed)
+			}
+			replaced, result := ProcessContent(tt.filename, []byte(source), config, ProcessOptions{ForceReplace: true})
+			if result.Action != "REPLACE" || strings.Count(string(replaced), "SPDX-License-Identifier") != 1 || !strings.HasSuffix(string(replaced), tt.body) {
+				t.Errorf("replace gave %s (%s):\n%s", result.Action, result.Reason, replaced)
+			}
+		})
+	}
+}
+
+func TestDocumentedDocstringKeepsItsDocumentation(t *testing.T) {
+	config := testConfig()
+	source := "\"\"\"Tool that does X.\n\nCopyright 2020 Oregon State University\nSPDX-License-Identifier: Apache-2.0\n\"\"\"\n\nimport os\n"
+
+	// The header is found, so none is added, but rewriting or removing it
+	// would delete the module's documentation
+	info := DetectHeaderInFile("tool.py", []byte(source))
+	if !info.HasHeader || !info.Docstring || info.StartLine == 0 {
+		t.Fatalf("unexpected detection: %+v", info)
+	}
+	if _, result := ProcessContent("tool.py", []byte(source), config, ProcessOptions{}); result.Modified {
+		t.Errorf("header added twice: %s (%s)", result.Action, result.Reason)
+	}
+	for name, opts := range map[string]ProcessOptions{
+		"force":               {ForceReplace: true},
+		"remove":              {RemoveMode: true},
+		"replace-third-party": {ReplaceThirdParty: true},
+		"normalize":           {Normalize: true},
+	} {
+		if _, result := ProcessContent("tool.py", []byte(source), config, opts); result.Modified || result.Reason != sharedDocstringReason {
+			t.Errorf("%s: %s (%s)", name, result.Action, result.Reason)
+		}
+	}
+
+	// Only Python has module docstrings
+	license := "\"\"\"\nCopyright 2020 Oregon State University\nSPDX-License-Identifier: Apache-2.0\n\"\"\"\n"
+	if info := DetectHeaderInFile("notes.jl", []byte(license)); info.BlockComment || info.StartLine != 2 {
+		t.Errorf("triple quotes bound a header outside Python: %+v", info)
+	}
+}
+
+func TestCurrentHeaderIsNotRewritten(t *testing.T) {
+	config := testConfig()
+	header := FormatHeader(GenerateHeaderForFile(config, "main.go"), CommentStyles[".go"])
+
+	for name, source := range map[string]string{
+		"identical":           header + "\n\npackage main\n",
+		"trailing whitespace": strings.ReplaceAll(header, "\n", " \t\n") + "\n\npackage main\n",
+	} {
+		path := writeTempFile(t, "main.go", source)
+		past := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatal(err)
+		}
+
+		result := ProcessFileWithOptions(path, config, ProcessOptions{ForceReplace: true})
+		if result.Action != "SKIP" || result.Reason != "Already current" || result.Modified {
+			t.Errorf("%s: got %s (%s), want SKIP (Already current)", name, result.Action, result.Reason)
+		}
+		content, _ := os.ReadFile(path)
+		info, _ := os.Stat(path)
+		if string(content) != source || !info.ModTime().Equal(past) {
+			t.Errorf("%s: file was rewritten:\n%s", name, content)
+		}
+	}
+}
+
+func TestHeaderBlockAroundMidBlockSPDX(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		start, end int
+	}{
+		{
+			name:    "hash comments",
+			content: "# Copyright 2024 Someone Else\n# SPDX-License-Identifier: MIT\n# Developed by: A Person\n#               A Lab\n#\n# Notes on the license\n\nimport os\n",
+			start:   0, end: 5,
+		},
+		{
+			name:    "code right after the block",
+			content: "// Header text\n// More header text\n// SPDX-License-Identifier: BSD-3-Clause\n// Maintained by: Nobody In Particular\npackage main\n",
+			start:   0, end: 3,
+		},
+		{
+			name:    "block comment",
+			content: "/*\n * Copyright 2024 Someone Else\n * SPDX-License-Identifier: MIT\n * Developed by: A Person\n */\nint x;\n",
+			start:   0, end: 4,
+		},
+		{
+			name:    "after shebang and code",
+			content: "#!/bin/sh\nset -e\n# Written by: Someone\n# SPDX-License-Identifier: MIT\n# Contact: someone@example.com\necho hi\n",
+			start:   2, end: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		info := DetectHeaderInContent([]byte(tt.content))
+		if !info.HasHeader || info.StartLine != tt.start || info.EndLine != tt.end {
+			t.Errorf("%s: header detected at %d-%d, want %d-%d", tt.name, info.StartLine, info.EndLine, tt.start, tt.end)
+		}
+	}
+}
+
+func TestGoHeaderAfterPackageClause(t *testing.T) {
+	config := testConfig()
+	config.HeaderPositions = map[string]string{"go": positionAfterPackage}
+	original := "//go:build linux\n\n// Package foo does things.\npackage foo\n\nimport \"fmt\"\n"
+	filename := "foo.go"
+
+	content, result := ProcessContent(filename, []byte(original), config, ProcessOptions{})
+	if result.Action != "ADD" {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
+	}
+	header := FormatHeader(GenerateHeaderForFile(config, filename), CommentStyles[".go"])
+	want := "//go:build linux\n\n// Package foo does things.\npackage foo\n\n" + header + "\n\nimport \"fmt\"\n"
+	if string(content) != want {
+		t.Fatalf("unexpected content:\n%s\nwant:\n%s", content, want)
+	}
+
+	if _, result := ProcessContent(filename, content, config, ProcessOptions{}); result.Action != "SKIP" {
+		t.Errorf("second run should find the header, got %s (%s)", result.Action, result.Reason)
+	}
+	removed, result := ProcessContent(filename, content, config, ProcessOptions{RemoveMode: true})
+	if result.Action != "REMOVE" || string(removed) != original {
+		t.Errorf("--remove did not restore the file (%s):\n%s", result.Action, removed)
+	}
+
+	// Other languages keep the header on top
+	if content, _ := ProcessContent("main.py", []byte("import os\n"), config, ProcessOptions{}); !strings.HasPrefix(string(content), "# Copyright") {
+		t.Errorf("Python header moved:\n%s", content)
+	}
+}
+
+func TestHeaderPositionsAreValidated(t *testing.T) {
+	if err := validateHeaderPositions(map[string]string{".go": "after-package", ".py": "top"}); err != nil {
+		t.Errorf("valid positions rejected: %v", err)
+	}
+	for _, positions := range []map[string]string{
+		{".py": "after-package"},
+		{".go": "bottom"},
+	} {
+		if err := validateHeaderPositions(positions); err == nil {
+			t.Errorf("%v should be rejected", positions)
+		}
+	}
+}
+
+func TestHeaderAfterLineAnchors(t *testing.T) {
+	config := testConfig()
+	config.HeaderAfterLines = map[string][]string{
+		"js": {`^['"]use strict['"];?$`},
+	}
+	config = WithHeaderAfterLines(config, []string{`^set -euo pipefail$`})
+
+	tests := []struct {
+		name, filename, above, below string
+	}{
+		{"php default", "index.php", "<?php\n", "echo 'hi';\n"},
+		{"python coding after shebang", "run.py", "#!/usr/bin/env python\n# -*- coding: latin-1 -*-\n", "print('hi')\n"},
+		{"html doctype", "index.html", "<!DOCTYPE html>\n", "<html></html>\n"},
+		{"configured per extension", "app.js", "'use strict';\n", "console.log(1)\n"},
+		{"--header-after-line for every type", "deploy.sh", "set -euo pipefail\n", "echo hi\n"},
+		{"anchor only on leading lines", "main.py", "", "import os\n# coding: utf-8\n"},
+	}
+	for _, tt := range tests {
+		original := tt.above + tt.below
+		content, result := ProcessContent(tt.filename, []byte(original), config, ProcessOptions{})
+		if result.Action != "ADD" {
+			t.Errorf("%s: expected ADD, got %s (%s)", tt.name, result.Action, result.Reason)
+			continue
+		}
+		style, _ := getCommentStyleForContent(tt.filename, []byte(original), nil)
+		want := FormatHeader(GenerateHeaderForFile(config, tt.filename), style) + "\n\n" + tt.below
+		if tt.above != "" {
+			want = tt.above + "\n" + want
+		}
+		if string(content) != want {
+			t.Errorf("%s: unexpected content:\n%s\nwant:\n%s", tt.name, content, want)
+			continue
+		}
+
+		if _, result := ProcessContent(tt.filename, content, config, ProcessOptions{}); result.Action != "SKIP" {
+			t.Errorf("%s: second run should find the header, got %s (%s)", tt.name, result.Action, result.Reason)
+		}
+		removed, result := ProcessContent(tt.filename, content, config, ProcessOptions{RemoveMode: true})
+		if result.Action != "REMOVE" || string(removed) != original {
+			t.Errorf("%s: --remove did not restore the file (%s):\n%s", tt.name, result.Action, removed)
+		}
+	}
+
+	if err := ValidateHeaderAfterLines(map[string][]string{".php": {"^<?php("}}); err == nil {
+		t.Error("an invalid HEADER_AFTER_LINES pattern was accepted")
+	}
+}
+
+func TestForceSparesThirdPartyHeaders(t *testing.T) {
+	config := testConfig()
+	stale := "# Copyright 2019 Oregon State University\n#\n# SPDX-License-Identifier: MIT\n\nprint('ours')\n"
+	theirs := "# Copyright 2019 Example Corp\n# SPDX-License-Identifier: MIT\n\nprint('theirs')\n"
+	notice := "# Copyright (c) 2019 Example Corp. All rights reserved.\n\nprint('notice')\n"
+
+	tests := []struct {
+		content           string
+		force             string // expected action with --force
+		replaceThirdParty string // expected action with --force --replace-third-party
+	}{
+		{stale, "REPLACE", "REPLACE"},
+		{theirs, "SKIP", "REPLACE"},
+		{notice, "SKIP", "REPLACE"},
+	}
+	for _, tt := range tests {
+		content, result := ProcessContent("x.py", []byte(tt.content), config, ProcessOptions{ForceReplace: true})
+		if result.Action != tt.force {
+			t.Errorf("--force: got %s (%s), want %s for:\n%s", result.Action, result.Reason, tt.force, tt.content)
+		}
+		if result.Modified && !strings.Contains(string(content), "SPDX-License-Identifier: Apache-2.0") {
+			t.Errorf("--force did not refresh the header:\n%s", content)
+		}
+		_, result = ProcessContent("x.py", []byte(tt.content), config, ProcessOptions{ForceReplace: true, ReplaceThirdParty: true})
+		if result.Action != tt.replaceThirdParty {
+			t.Errorf("--force --replace-third-party: got %s (%s), want %s for:\n%s", result.Action, result.Reason, tt.replaceThirdParty, tt.content)
+		}
+	}
+}
+
+func TestBlockCommentHeaderIsRemovedWhole(t *testing.T) {
+	config := testConfig()
+	tests := []struct {
+		name    string
+		content string
+		end     int
+	}{
+		{
+			name:    "bare block body",
+			content: "/*\n   Copyright 2024 Oregon State University\n   SPDX-License-Identifier: Apache-2.0\n   Developed by: Test User\n*/\n\nint x;\n",
+			end:     4,
+		},
+		{
+			name:    "starred block body",
+			content: "/*\n * Copyright 2024 Oregon State University\n *\n * SPDX-License-Identifier: Apache-2.0\n */\n\nint x;\n",
+			end:     4,
+		},
+	}
+
+	for _, tt := range tests {
+		info := DetectHeaderInContent([]byte(tt.content))
+		if !info.HasHeader || !info.BlockComment || info.StartLine != 0 || info.EndLine != tt.end {
+			t.Errorf("%s: detected %+v, want block 0-%d", tt.name, info, tt.end)
+			continue
+		}
+
+		removed, result := ProcessContent("x.c", []byte(tt.content), config, ProcessOptions{RemoveMode: true})
+		if result.Action != "REMOVE" || string(removed) != "int x;\n" {
+			t.Errorf("%s: --remove left (%s):\n%s", tt.name, result.Action, removed)
+		}
+
+		forced, _ := ProcessContent("x.c", []byte(tt.content), config, ProcessOptions{ForceReplace: true})
+		if strings.Count(string(forced), "/*") != 0 || strings.Count(string(forced), "*/") != 0 {
+			t.Errorf("%s: --force left a dangling delimiter:\n%s", tt.name, forced)
+		}
+	}
+
+	// A third-party block loses its opening line too
+	thirdParty := "/*\n * Copyright (c) 2019 Example Corp. All rights reserved.\n */\n\nint x;\n"
+	forced, _ := ProcessContent("x.c", []byte(thirdParty), config, ProcessOptions{ForceReplace: true})
+	if strings.Contains(string(forced), "/*") || strings.Contains(string(forced), "Example Corp") {
+		t.Errorf("--force left part of the third-party block:\n%s", forced)
+	}
+}
+
+func TestExistingCopyingIsNotDuplicated(t *testing.T) {
+	config := testConfig()
+	repo := t.TempDir()
+	copying := "GNU GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007\n"
+	if err := os.WriteFile(filepath.Join(repo, "COPYING"), []byte(copying), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ManageLicenseFile(repo, config, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "LICENSE")); !os.IsNotExist(err) {
+		t.Errorf("LICENSE created next to COPYING")
+	}
+	if content, _ := os.ReadFile(filepath.Join(repo, "COPYING")); string(content) != copying {
+		t.Errorf("COPYING was modified:\n%s", content)
+	}
+
+	// An empty COPYING doesn't license anything
+	empty := t.TempDir()
+	if err := os.WriteFile(filepath.Join(empty, "COPYING"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ManageLicenseFile(empty, config, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(empty, "LICENSE")); err != nil {
+		t.Errorf("no LICENSE created next to an empty COPYING: %v", err)
+	}
+}
+
+func TestLicenseFileName(t *testing.T) {
+	config := testConfig()
+	config.LicenseFile = "LICENSE.md"
+	repo := t.TempDir()
+
+	if err := ManageLicenseFile(repo, config, false); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(repo, "LICENSE.md"))
+	if err != nil || !strings.Contains(string(content), "Apache License") {
+		t.Fatalf("LICENSE.md not created: %v\n%s", err, content)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "LICENSE")); !os.IsNotExist(err) {
+		t.Errorf("LICENSE created although LICENSE_FILE is LICENSE.md")
+	}
+
+	base := "VERSION: 2\nFULL_NAME: Test User\nDEFAULT_ROLE: Staff\nDEPT_OR_LAB: Lab\nORGANIZATION: Org\n"
+	for setting, wantErr := range map[string]bool{
+		"LICENSE_FILE: COPYING\n":      false,
+		"LICENSE_FILE: docs/LICENSE\n": true,
+		"LICENSE_FILE: ..\n":           true,
+	} {
+		path := writeTempFile(t, "licer.yml", base+setting)
+		_, err := loadConfig(path)
+		if (err != nil) != wantErr {
+			t.Errorf("%q: got error %v, want error %v", setting, err, wantErr)
+		}
+	}
+}
+
+func TestManageLicenseFileIsIdempotent(t *testing.T) {
+	config := testConfig()
+	year := time.Now().Year()
+	repo := t.TempDir()
+	licensePath := filepath.Join(repo, "LICENSE")
+
+	if err := ManageLicenseFile(repo, config, false); err != nil {
+		t.Fatal(err)
+	}
+	created, err := os.ReadFile(licensePath)
+	if err != nil || !strings.Contains(string(created), fmt.Sprintf("Copyright %d Oregon State University", year)) {
+		t.Fatalf("LICENSE not created with the headers' year %d: %v\n%s", year, err, created)
+	}
+
+	// A correct LICENSE, also with CRLF line endings, is not rewritten or
+	// renamed on the next run
+	crlf := strings.ReplaceAll(string(created), "\n", "\r\n")
+	for _, content := range []string{string(created), crlf} {
+		if err := os.WriteFile(licensePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		past := time.Now().Add(-time.Hour)
+		if err := os.Chtimes(licensePath, past, past); err != nil {
+			t.Fatal(err)
+		}
+		if err := ManageLicenseFile(repo, config, false); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(licensePath)
+		if err != nil || !info.ModTime().Equal(past) {
+			t.Errorf("an up-to-date LICENSE was rewritten: %v", err)
+		}
+		if _, err := os.Stat(licensePath + ".orig"); !os.IsNotExist(err) {
+			t.Fatalf("an up-to-date LICENSE was renamed to LICENSE.orig")
+		}
+	}
+
+	// One licer wrote in an earlier year is left alone by default...
+	older := strings.Replace(string(created), fmt.Sprintf("Copyright %d ", year), "Copyright 2023 ", 1)
+	if err := os.WriteFile(licensePath, []byte(older), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ManageLicenseFile(repo, config, false); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(licensePath); string(content) != older {
+		t.Errorf("the year of an older LICENSE was changed without UPDATE_LICENSE_YEAR")
+	}
+	if _, err := os.Stat(licensePath + ".orig"); !os.IsNotExist(err) {
+		t.Fatalf("an older LICENSE of licer was renamed to LICENSE.orig")
+	}
+
+	// ...and with UPDATE_LICENSE_YEAR only its year line changes
+	config.UpdateLicenseYear = true
+	if err := ManageLicenseFile(repo, config, false); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(older, "Copyright 2023 ", fmt.Sprintf("Copyright 2023-%d ", year), 1)
+	if content, _ := os.ReadFile(licensePath); string(content) != want {
+		t.Errorf("expected only the year updated to 2023-%d, got:\n%s", year, content)
+	}
+	if err := ManageLicenseFile(repo, config, false); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(licensePath); string(content) != want {
+		t.Errorf("an updated LICENSE changed again on the next run:\n%s", content)
+	}
+
+	// A third-party license is still replaced
+	if err := os.WriteFile(licensePath, []byte("Copyright 2010 Example Corp. All rights reserved.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ManageLicenseFile(repo, config, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(licensePath + ".orig"); err != nil {
+		t.Errorf("third-party LICENSE was not renamed to LICENSE.orig: %v", err)
+	}
+}
+
+func TestManageNoticeFile(t *testing.T) {
+	year := time.Now().Year()
+
+	// Apache-2.0 (Staff) with CREATE_NOTICE gets a NOTICE
+	config := testConfig()
+	config.CreateNotice = true
+	repo := t.TempDir()
+	if err := ManageNoticeFile(repo, config, false); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(repo, "NOTICE"))
+	want := fmt.Sprintf("Copyright %d Oregon State University\n\nThis product includes software developed at\nTest Lab, Oregon State University.\n", year)
+	if err != nil || string(content) != want {
+		t.Errorf("unexpected NOTICE (%v):\n%s", err, content)
+	}
+
+	// A non-empty NOTICE is kept as it is
+	existing := "Example Project\nCopyright 2015 Someone\n"
+	if err := os.WriteFile(filepath.Join(repo, "NOTICE"), []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ManageNoticeFile(repo, config, false); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(repo, "NOTICE")); string(content) != existing {
+		t.Errorf("existing NOTICE was rewritten:\n%s", content)
+	}
+
+	// MIT (Student) never gets one, and Apache-2.0 only when asked for
+	student := testConfig()
+	student.DefaultRole = "Student"
+	student.CreateNotice = true
+	for name, c := range map[string]*Config{"MIT": student, "without CREATE_NOTICE": testConfig()} {
+		repo := t.TempDir()
+		if err := ManageNoticeFile(repo, c, false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(repo, "NOTICE")); !os.IsNotExist(err) {
+			t.Errorf("%s: NOTICE created", name)
+		}
+	}
+}
+
+func TestReplaceIfOlderThan(t *testing.T) {
+	config := testConfig()
+	ours := func(year int) string {
+		return FormatHeader(generateHeaderForFileYear(config, "x.py", year), CommentStyles[".py"]) + "\nprint('hi')\n"
+	}
+	theirs := "# Copyright 2018 Example Corp\n# SPDX-License-Identifier: MIT\n\nprint('theirs')\n"
+	notice := "# Copyright (c) 2018 Example Corp. All rights reserved.\n\nprint('notice')\n"
+	ranged := "# Copyright 2018-2024 Oregon State University\n#\n# SPDX-License-Identifier: Apache-2.0\n\nprint('hi')\n"
+
+	tests := []struct {
+		name    string
+		content string
+		own     string // expected action with --replace-if-older-than 2022
+		force   string // expected action when combined with --replace-third-party
+	}{
+		{"ours from 2018", ours(2018), "REPLACE", "REPLACE"},
+		{"ours from 2023", ours(2023), "SKIP", "SKIP"},
+		{"ours from 2025", ours(2025), "SKIP", "SKIP"},
+		{"range ending 2024", ranged, "SKIP", "SKIP"},
+		{"third-party from 2018", theirs, "SKIP", "REPLACE"},
+		{"notice from 2018", notice, "SKIP", "REPLACE"},
+		{"no header", "print('new')\n", "ADD", "ADD"},
+	}
+	for _, tt := range tests {
+		content, result := ProcessContent("x.py", []byte(tt.content), config, ProcessOptions{ReplaceOlderThan: 2022})
+		if result.Action != tt.own {
+			t.Errorf("%s: got %s (%s), want %s", tt.name, result.Action, result.Reason, tt.own)
+		}
+		if result.Action == "REPLACE" && !strings.Contains(string(content), fmt.Sprintf("Copyright %d", time.Now().Year())) {
+			t.Errorf("%s: header not refreshed:\n%s", tt.name, content)
+		}
+		_, result = ProcessContent("x.py", []byte(tt.content), config, ProcessOptions{ReplaceOlderThan: 2022, ReplaceThirdParty: true})
+		if result.Action != tt.force {
+			t.Errorf("%s with --replace-third-party: got %s (%s), want %s", tt.name, result.Action, result.Reason, tt.force)
+		}
+	}
+
+	// Files are read in full so old headers are not skipped from the prefix
+	path := writeTempFile(t, "old.py", ours(2018))
+	if result := ProcessFileWithOptions(path, config, ProcessOptions{ReplaceOlderThan: 2022}); result.Action != "REPLACE" {
+		t.Errorf("file: got %s (%s), want REPLACE", result.Action, result.Reason)
+	}
+}
+
+func TestForeignToolHeaders(t *testing.T) {
+	config := testConfig()
+	tests := []struct {
+		name    string
+		file    string
+		header  string
+		license string
+		format  string
+		ours    bool
+	}{
+		{
+			name: "addlicense apache",
+			file: "main.go",
+			header: "// Copyright 2021 Oregon State University\n//\n" +
+				"// Licensed under the Apache License, Version 2.0 (the \"License\");\n" +
+				"// you may not use this file except in compliance with the License.\n" +
+				"// You may obtain a copy of the License at\n//\n" +
+				"//      http://www.apache.org/licenses/LICENSE-2.0\n//\n" +
+				"// Unless required by applicable law or agreed to in writing, software\n" +
+				"// distributed under the License is distributed on an \"AS IS\" BASIS,\n" +
+				"// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n" +
+				"// See the License for the specific language governing permissions and\n" +
+				"// limitations under the License.\n",
+			license: "Apache-2.0",
+			format:  "addlicense-apache",
+			ours:    true,
+		},
+		{
+			name: "addlicense mit",
+			file: "tool.py",
+			header: "# Copyright (c) 2021 Example Corp\n#\n" +
+				"# Use of this source code is governed by an MIT-style\n" +
+				"# license that can be found in the LICENSE file or at\n" +
+				"# https://opensource.org/licenses/MIT.\n",
+			license: "MIT",
+			format:  "addlicense-mit",
+		},
+		{
+			name: "addlicense bsd block",
+			file: "lib.c",
+			header: "/*\n * Copyright 2021 Example Corp. All rights reserved.\n" +
+				" * Use of this source code is governed by a BSD-style\n" +
+				" * license that can be found in the LICENSE file.\n */\n",
+			license: "BSD-3-Clause",
+			format:  "addlicense-bsd",
+		},
+		{
+			name: "reuse",
+			file: "tool.py",
+			header: "# SPDX-FileCopyrightText: 2021 Test User <test@example.org>\n" +
+				"# SPDX-FileContributor: Jane Roe\n#\n" +
+				"# SPDX-License-Identifier: GPL-3.0-or-later\n",
+			license: "GPL-3.0-or-later",
+			ours:    true,
+		},
+	}
+	for _, tt := range tests {
+		body := "\nx = 1\n"
+		content := []byte(tt.header + body)
+		info := DetectHeaderInContent(content)
+		if !info.HasHeader || info.HasThirdPartyCopyright || info.LicenseID != tt.license || info.Format != tt.format {
+			t.Errorf("%s: detected %+v", tt.name, info)
+			continue
+		}
+		if got := CanRemoveHeaderContent(content, info, config); got != tt.ours {
+			t.Errorf("%s: ours = %v, want %v", tt.name, got, tt.ours)
+		}
+
+		// Not duplicated by a normal run
+		if _, result := ProcessContent(tt.file, content, config, ProcessOptions{}); result.Action != "SKIP" {
+			t.Errorf("%s: got %s (%s), want SKIP", tt.name, result.Action, result.Reason)
+		}
+
+		// --force --replace-third-party normalizes the whole header to our template
+		replaced, result := ProcessContent(tt.file, content, config, ProcessOptions{ForceReplace: true, ReplaceThirdParty: true})
+		if result.Action != "REPLACE" {
+			t.Fatalf("%s: --force got %s (%s)", tt.name, result.Action, result.Reason)
+		}
+		text := string(replaced)
+		if strings.Contains(text, "Example Corp") || strings.Contains(text, "governed by") || strings.Contains(text, "(the \"License\")") ||
+			strings.Contains(text, "SPDX-FileCopyrightText") || strings.Contains(text, "*/\n */") {
+			t.Errorf("%s: foreign header left behind:\n%s", tt.name, text)
+		}
+		if !strings.HasSuffix(text, body) || strings.Count(text, "SPDX-License-Identifier") != 1 {
+			t.Errorf("%s: unexpected result:\n%s", tt.name, text)
+		}
+	}
+}
+
+func TestHeaderFormatsFromConfig(t *testing.T) {
+	content := []byte("# (C) 2020 Lab of Test User\n# Distributed under the terms of the ISC license.\n\nx = 1\n")
+	if info := DetectHeaderForConfig("x.py", content, testConfig()); info.HasHeader {
+		t.Fatalf("detected before configuring: %+v", info)
+	}
+
+	base := "VERSION: 2\nFULL_NAME: Test User\nDEFAULT_ROLE: Staff\nDEPT_OR_LAB: Lab\nORGANIZATION: Org\n"
+	if _, err := loadConfig(writeTempFile(t, "licer.yml", base+"HEADER_FORMATS:\n  '([': ISC\n")); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+	if _, err := loadConfig(writeTempFile(t, "licer.yml", base+"HEADER_FORMATS:\n  'ISC license': ISCL\n")); err == nil {
+		t.Error("expected an error for a license that is not an SPDX id")
+	}
+	config, err := loadConfig(writeTempFile(t, "licer.yml", base+"HEADER_FORMATS:\n  'Distributed under the terms of the ISC license': ISC\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := DetectHeaderForConfig("x.py", content, config)
+	if !info.HasHeader || info.LicenseID != "ISC" || info.StartLine != 0 || info.EndLine != 1 {
+		t.Errorf("configured format not detected: %+v", info)
+	}
+	if _, result := ProcessContent("x.py", content, config, ProcessOptions{}); result.Action != "SKIP" {
+		t.Errorf("header of a configured format duplicated: %s (%s)", result.Action, result.Reason)
+	}
+
+	// Only for files processed with that config
+	if info := DetectHeaderInContent(content); info.HasHeader {
+		t.Errorf("configured format detected without the config: %+v", info)
+	}
+}
+
+func TestSPDXExpressionValidation(t *testing.T) {
+	for _, expr := range []string{
+		"MIT", "Apache-2.0", "apache-2.0", "GPL-2.0", "GPL-2.0-or-later", "LGPL-2.1+",
+		"MIT OR Apache-2.0", "(MIT AND BSD-3-Clause) OR GPL-3.0-only",
+		"GPL-2.0-only WITH Classpath-exception-2.0", "LicenseRef-Proprietary",
+	} {
+		if err := ValidateSPDXExpression(expr); err != nil {
+			t.Errorf("%q rejected: %v", expr, err)
+		}
+	}
+	for _, expr := range []string{
+		"", "Apache2", "Apache 2.0", "MIT License", "MIT OR", "(MIT", "MIT AND AND BSD-3-Clause",
+		"GPL-2.0-only WITH Apache-2.0", "MIT BSD-3-Clause",
+	} {
+		if err := ValidateSPDXExpression(expr); err == nil {
+			t.Errorf("%q accepted", expr)
+		}
+	}
+
+	// Every license licer writes is on the list
+	for id := range knownLicenses {
+		if err := ValidateSPDXExpression(id); err != nil {
+			t.Errorf("licer writes an invalid id: %v", err)
+		}
+	}
+
+	// --check reports a typo'd id as an error, otherwise it is left alone
+	typo := "# Copyright 2020 Example Corp\n# SPDX-License-Identifier: Apache2\n\nx = 1\n"
+	if _, result := ProcessContent("main.py", []byte(typo), testConfig(), ProcessOptions{ValidateSPDX: true}); !strings.HasPrefix(result.Reason, "Error") || !strings.Contains(result.Reason, `"Apache2"`) {
+		t.Errorf("typo not flagged: %s (%s)", result.Action, result.Reason)
+	}
+	if _, result := ProcessContent("main.py", []byte(typo), testConfig(), ProcessOptions{}); result.Action != "SKIP" || strings.HasPrefix(result.Reason, "Error") {
+		t.Errorf("typo flagged without validation: %s (%s)", result.Action, result.Reason)
+	}
+}
+
+func TestSaveConfigKeepsCommentsAndUnknownKeys(t *testing.T) {
+	v1 := `# licer settings for the lab
+FULL_NAME: Jane Doe # as in the directory
+DEFAULT_ROLE: Staff
+DEPT_OR_LAB: &lab Research Computing
+ORGANIZATION: Oregon State University
+
+# read by our release scripts, not by licer
+RELEASE_TEAM: *lab
+OWNER_ALIASES:
+  - OSU # short form
+`
+	path := writeTempFile(t, "licer.yml", v1)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("config rejected: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	text := string(data)
+	for _, want := range []string{
+		"# licer settings for the lab\nVERSION: 2\nFULL_NAME: Jane Doe # as in the directory\n",
+		"DEPT_OR_LAB: &lab Research Computing\n",
+		"# read by our release scripts, not by licer\nRELEASE_TEAM: *lab\n",
+		"- OSU # short form\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("upgraded config lost %q:\n%s", want, text)
+		}
+	}
+
+	// Changed and emptied fields are rewritten, the rest stays as it was
+	config.FullName = "Jane Q. Doe"
+	config.OwnerAliases = nil
+	if err := saveConfig(config, path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	text = string(data)
+	if !strings.Contains(text, "FULL_NAME: Jane Q. Doe # as in the directory\n") {
+		t.Errorf("changed field not rewritten with its comment:\n%s", text)
+	}
+	if strings.Contains(text, "OWNER_ALIASES") {
+		t.Errorf("emptied field kept:\n%s", text)
+	}
+	if !strings.Contains(text, "RELEASE_TEAM: *lab\n") {
+		t.Errorf("unknown key lost:\n%s", text)
+	}
+	if reloaded, err := loadConfig(path); err != nil || reloaded.FullName != "Jane Q. Doe" {
+		t.Errorf("rewritten config does not load: %+v, %v", reloaded, err)
+	}
+
+	// A changed anchored value keeps its anchor, so aliases of it still load
+	config.DeptOrLab = "Research Computing Services"
+	if err := saveConfig(config, path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	text = string(data)
+	if !strings.Contains(text, "DEPT_OR_LAB: &lab Research Computing Services\n") || !strings.Contains(text, "RELEASE_TEAM: *lab\n") {
+		t.Errorf("anchor or alias lost:\n%s", text)
+	}
+	if reloaded, err := loadConfig(path); err != nil || reloaded.DeptOrLab != "Research Computing Services" {
+		t.Errorf("config with a changed anchor does not load: %+v, %v", reloaded, err)
+	}
+}
+
+func TestInitConfigCreatesAndUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "licer.yml")
+
+	config, err := initConfig(path, strings.NewReader("Jane Doe\n3\nResearch Computing\n\n"))
+	if err != nil {
+		t.Fatalf("creating config failed: %v", err)
+	}
+	if config.FullName != "Jane Doe" || config.DefaultRole != "Staff" || config.Organization != "Oregon State University" || config.Version != configVersion {
+		t.Errorf("unexpected new config: %+v", config)
+	}
+
+	// Updating offers the current values: only the role changes here, and
+	// settings without a prompt are kept
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, append([]byte("# my settings\n"), append(data, "OWNER_ALIASES:\n    - OSU\n"...)...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err = initConfig(path, strings.NewReader("\n1\n\n\n"))
+	if err != nil {
+		t.Fatalf("updating config failed: %v", err)
+	}
+	if config.FullName != "Jane Doe" || config.DefaultRole != "Student" || config.DeptOrLab != "Research Computing" || len(config.OwnerAliases) != 1 {
+		t.Errorf("unexpected updated config: %+v", config)
+	}
+	if data, _ := os.ReadFile(path); !strings.HasPrefix(string(data), "# my settings\n") {
+		t.Errorf("update lost the comment:\n%s", data)
+	}
+
+	// An incomplete file is completed rather than rejected
+	incomplete := writeTempFile(t, "licer.yml", "FULL_NAME: Jane Doe\n")
+	if config, err := initConfig(incomplete, strings.NewReader("\n2\nPhysics\n\n")); err != nil || config.DefaultRole != "Faculty" {
+		t.Errorf("incomplete config not completed: %+v, %v", config, err)
+	}
+}
+
+func TestHeaderTemplatesPerExtension(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"header.go.txt":         "Copyright {year} {owner}. All rights reserved.\nSPDX-License-Identifier: {spdx}\n",
+		"header.py.txt":         "Approved by legal: {owner}, {year}\r\n",
+		"header.dockerfile.txt": "Container recipe of {owner}\n",
+		"notes.txt":             "not a header file\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	templates, err := LoadHeaderTemplates(dir)
+	if err != nil {
+		t.Fatalf("LoadHeaderTemplates failed: %v", err)
+	}
+	if len(templates) != 3 {
+		t.Errorf("unexpected templates: %v", templates)
+	}
+
+	config := testConfig()
+	opts := ProcessOptions{HeaderTemplates: templates}
+	year := time.Now().Year()
+	tests := []struct {
+		filename string
+		content  string
+		want     string
+	}{
+		{"main.go", "package main\n", fmt.Sprintf("// Copyright %d Oregon State University. All rights reserved.\n// SPDX-License-Identifier: Apache-2.0\n\npackage main\n", year)},
+		{"app.py", "x = 1\n", fmt.Sprintf("# Approved by legal: Oregon State University, %d\n#\n# SPDX-License-Identifier: Apache-2.0\n\nx = 1\n", year)},
+		{"Dockerfile", "FROM alpine\n", "# Container recipe of Oregon State University\n#\n# SPDX-License-Identifier: Apache-2.0\n\nFROM alpine\n"},
+	}
+	for _, tt := range tests {
+		updated, result := ProcessContent(tt.filename, []byte(tt.content), config, opts)
+		if result.Action != "ADD" || string(updated) != tt.want {
+			t.Errorf("%s: %s (%s), got:\n%s\nwant:\n%s", tt.filename, result.Action, result.Reason, updated, tt.want)
+			continue
+		}
+		if _, result := ProcessContent(tt.filename, updated, config, opts); result.Action != "SKIP" {
+			t.Errorf("%s: header added twice: %s (%s)", tt.filename, result.Action, result.Reason)
+		}
+		if removed, result := ProcessContent(tt.filename, updated, config, ProcessOptions{RemoveMode: true}); string(removed) != tt.content {
+			t.Errorf("%s: custom header not removed: %s (%s)\n%s", tt.filename, result.Action, result.Reason, removed)
+		}
+	}
+
+	// Extensions without a header file get the generated header
+	updated, _ := ProcessContent("app.js", []byte("let x = 1;\n"), config, opts)
+	if !strings.Contains(string(updated), "Licensed under the Apache License") {
+		t.Errorf("generated header not used without a header file:\n%s", updated)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "header.sh.txt"), []byte("\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHeaderTemplates(dir); err == nil {
+		t.Error("empty header file accepted")
+	}
+}
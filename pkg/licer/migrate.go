@@ -0,0 +1,159 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Legacy headers are only looked for near the top of a file
+const maxLegacyHeaderLines = 20
+
+var copyrightYearPattern = regexp.MustCompile(`\b(19|20)\d{2}\b`)
+
+func CompileLegacyPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LEGACY_PATTERNS entry %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// migrateContent rewrites a header matching one of the legacy patterns to
+// the current template, keeping the year of the legacy header. Unlike
+// --force it never touches headers that no legacy pattern claims as ours.
+func migrateContent(filename string, content []byte, config *Config, patterns []*regexp.Regexp, templates map[string]string) ([]byte, ProcessResult) {
+	if !ShouldProcessContentForConfig(filename, content, config) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: skipReason(filename, func() bool { return isTextContent(content) }, config),
+		}
+	}
+
+	commentStyle, ok := getCommentStyleForContent(filename, content, config)
+	if !ok {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "No comment style available",
+		}
+	}
+	
+	headerInfo := DetectHeaderForConfig(filename, content, config)
+	if headerInfo.HasHeader {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "Header already exists",
+		}
+	}
+
+	_, body := SplitBOM(content)
+	lines := SplitLines(body)
+	start, end, year, found := findLegacyHeader(lines, patterns)
+	if !found {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "No legacy header found",
+		}
+	}
+
+	headerText := headerTextForFile(config, filename, strconv.Itoa(year), templates)
+	formattedHeader := formatHeaderForConfig(headerText, commentStyle, config)
+
+	legacyInfo := HeaderInfo{
+		HasHeader:     true,
+		StartLine:     start,
+		EndLine:       end,
+		HasShebang:    headerInfo.HasShebang,
+		PreambleLines: headerInfo.PreambleLines,
+	}
+
+	return modifyContent(content, formattedHeader, legacyInfo), ProcessResult{
+		Action:    "REPLACE",
+		Reason:    fmt.Sprintf("Migrated legacy header to %s header (year %d kept)", GetLicenseType(config), year),
+		Modified:  true,
+		LicenseID: GetLicenseType(config),
+	}
+}
+
+// findLegacyHeader locates the comment block around the first line matching
+// a legacy pattern and returns its bounds and copyright year
+func findLegacyHeader(lines []string, patterns []*regexp.Regexp) (int, int, int, bool) {
+	match := -1
+	for i := 0; i < len(lines) && i < maxLegacyHeaderLines && match == -1; i++ {
+		if !isCommentLine(lines[i]) {
+			continue
+		}
+		for _, re := range patterns {
+			if re.MatchString(lines[i]) {
+				match = i
+				break
+			}
+		}
+	}
+	if match == -1 {
+		return -1, -1, 0, false
+	}
+
+	// Grow the block over neighbouring comment lines that are part of the
+	// notice, never over ordinary comments such as package documentation
+	start, end := match, match
+	preamble := preambleLines(lines)
+	for start > preamble && isLegacyHeaderLine(lines[start-1], patterns) {
+		start--
+	}
+	for end+1 < len(lines) && isLegacyHeaderLine(lines[end+1], patterns) {
+		end++
+	}
+
+	year := time.Now().Year()
+	for i := start; i <= end; i++ {
+		if found := copyrightYearPattern.FindString(lines[i]); found != "" {
+			year, _ = strconv.Atoi(found)
+			break
+		}
+	}
+
+	return start, end, year, true
+}
+
+func isLegacyHeaderLine(line string, patterns []*regexp.Regexp) bool {
+	if !isCommentLine(line) && !isBlankComment(line) {
+		return false
+	}
+	if isBlankComment(line) {
+		return true
+	}
+
+	for _, re := range patterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+
+	lower := strings.ToLower(line)
+	return strings.Contains(lower, "copyright") ||
+		strings.Contains(lower, "rights reserved") ||
+		strings.Contains(lower, "licens")
+}
+
+// isBlankComment reports whether line holds only comment markers, like the
+// "//" or " *" separators inside a header
+func isBlankComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" && strings.Trim(trimmed, "/*#;-%!\"<>(){}") == ""
+}
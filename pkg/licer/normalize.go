@@ -0,0 +1,171 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// continuationIndent is how much deeper than the rest of a header a line
+// must be indented to count as the continuation of the line above, like
+// the lab under "Developed by:"
+const continuationIndent = 4
+
+// normalizeContent re-renders a header that is ours (ownership match) in
+// the canonical comment style of the file type, dropping stray indentation
+// and repeated blank comment lines. Its text, and so its license and year,
+// is kept; third-party headers are never touched.
+func normalizeContent(filename string, content []byte, config *Config) ([]byte, ProcessResult) {
+	if !ShouldProcessContentForConfig(filename, content, config) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: skipReason(filename, func() bool { return isTextContent(content) }, config),
+		}
+	}
+
+	commentStyle, ok := getCommentStyleForContent(filename, content, config)
+	if !ok {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "No comment style available",
+		}
+	}
+
+	headerInfo := DetectHeaderForConfig(filename, content, config)
+	if !headerInfo.HasHeader {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "No header found",
+		}
+	}
+
+	if headerInfo.Docstring {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: sharedDocstringReason,
+		}
+	}
+
+	if !CanRemoveHeaderContent(content, headerInfo, config) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "Header ownership mismatch (safety check)",
+		}
+	}
+
+	_, body := SplitBOM(content)
+	lines := SplitLines(body)
+	headerText := headerTextFromLines(lines[headerInfo.StartLine : headerInfo.EndLine+1])
+	formattedHeader := formatHeaderForConfig(headerText, commentStyle, config)
+	if headerIsCurrent(content, headerInfo, formattedHeader) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "Header already normalized",
+		}
+	}
+
+	return modifyContent(content, formattedHeader, headerInfo), ProcessResult{
+		Action:    "REPLACE",
+		Reason:    "Normalized header formatting",
+		Modified:  true,
+		LicenseID: headerInfo.LicenseID,
+	}
+}
+
+// headerTextFromLines undoes FormatHeader for a header in any comment
+// style: markers and stray indentation are removed, runs of blank lines
+// collapsed and continuation lines aligned under the value of the line
+// above them, as generated headers have it
+func headerTextFromLines(lines []string) string {
+	type headerLine struct {
+		text   string
+		indent int
+	}
+
+	var parsed []headerLine
+	minIndent := -1
+	for _, line := range lines {
+		text := uncommentLine(line)
+		trimmed := strings.TrimLeft(text, " \t")
+		if trimmed == "" {
+			parsed = append(parsed, headerLine{})
+			continue
+		}
+		indent := len(text) - len(trimmed)
+		if minIndent < 0 || indent < minIndent {
+			minIndent = indent
+		}
+		parsed = append(parsed, headerLine{text: trimmed, indent: indent})
+	}
+
+	var result []string
+	label := ""
+	for _, line := range parsed {
+		if line.text == "" {
+			if len(result) > 0 && result[len(result)-1] != "" {
+				result = append(result, "")
+			}
+			continue
+		}
+
+		text := line.text
+		if line.indent-minIndent >= continuationIndent {
+			if label != "" {
+				text = strings.Repeat(" ", len(label)) + text
+			} else {
+				text = strings.Repeat(" ", line.indent-minIndent) + text
+			}
+		} else if i := strings.Index(text, ": "); i >= 0 {
+			label = text[:i+2]
+		} else {
+			label = ""
+		}
+		result = append(result, text)
+	}
+
+	for len(result) > 0 && result[len(result)-1] == "" {
+		result = result[:len(result)-1]
+	}
+	return strings.Join(result, "\n")
+}
+
+// uncommentLine removes the comment opener and closer of one header line,
+// keeping the indentation of its text. Alphabetic markers (Fortran "C",
+// batch "REM") only count when followed by whitespace.
+func uncommentLine(line string) string {
+	text := strings.TrimRight(strings.TrimLeft(line, " \t"), " \t")
+	for _, marker := range commentMarkers {
+		if !strings.HasPrefix(text, marker) {
+			continue
+		}
+		rest := text[len(marker):]
+		if unicode.IsLetter(rune(marker[0])) && rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			continue
+		}
+		text = rest
+		if strings.HasPrefix(text, " ") {
+			text = text[1:]
+		}
+		break
+	}
+
+	for _, marker := range commentMarkers {
+		if unicode.IsLetter(rune(marker[0])) {
+			continue
+		}
+		if strings.TrimSpace(text) == marker || strings.HasSuffix(text, " "+marker) {
+			text = strings.TrimRight(strings.TrimSuffix(text, marker), " \t")
+			break
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,206 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Header positions for HEADER_POSITIONS
+const (
+	positionTop          = "top"
+	positionAfterPackage = "after-package"
+)
+
+// headerPositionExtensions lists the extensions each non-default position
+// is supported for
+var headerPositionExtensions = map[string][]string{
+	positionAfterPackage: {".go"},
+}
+
+// headerAfterLinesAll is the HEADER_AFTER_LINES key for every extension,
+// where --header-after-line adds its patterns
+const headerAfterLinesAll = "*"
+
+// defaultHeaderAfterLines are the leading lines of a file type that must
+// stay above the header, besides the preamble every file type has
+// (shebang, Dockerfile directives, ...; see preambleLines). Users extend
+// them with HEADER_AFTER_LINES.
+var defaultHeaderAfterLines = map[string][]string{
+	".php":  {`^<\?php\b`},
+	".py":   {`^#.*coding[:=]\s*[-\w.]+`},
+	".rb":   {`^#.*coding[:=]\s*[-\w.]+`, rubyMagicComment},
+	".html": {`(?i)^<!doctype\s`},
+	".htm":  {`(?i)^<!doctype\s`},
+}
+
+// rubyMagicComment matches the magic comments Ruby reads from the top of a
+// file, also in their Emacs form (# -*- frozen_string_literal: true -*-)
+const rubyMagicComment = `^#\s*(?:-\*-\s*)?(?:frozen_string_literal|warn_indent|shareable_constant_value|warn_past_scope)\s*:`
+
+// headerAfterPatterns caches compiled HEADER_AFTER_LINES patterns
+var headerAfterPatterns sync.Map // pattern -> *regexp.Regexp
+
+// ValidateHeaderAfterLines checks that every HEADER_AFTER_LINES pattern is
+// a valid regular expression
+func ValidateHeaderAfterLines(anchors map[string][]string) error {
+	for ext, patterns := range anchors {
+		for _, pattern := range patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid HEADER_AFTER_LINES entry %q for %s: %w", pattern, ext, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WithHeaderAfterLines returns config with patterns added to the
+// HEADER_AFTER_LINES of every extension (--header-after-line)
+func WithHeaderAfterLines(config *Config, patterns []string) *Config {
+	if len(patterns) == 0 {
+		return config
+	}
+	updated := *config
+	updated.HeaderAfterLines = make(map[string][]string, len(config.HeaderAfterLines)+1)
+	for ext, existing := range config.HeaderAfterLines {
+		updated.HeaderAfterLines[ext] = existing
+	}
+	all := updated.HeaderAfterLines[headerAfterLinesAll]
+	updated.HeaderAfterLines[headerAfterLinesAll] = append(all[:len(all):len(all)], patterns...)
+	return &updated
+}
+
+// headerAfterLinePatterns returns the compiled anchors for filename: the
+// defaults of its extension, then HEADER_AFTER_LINES for it and for "*"
+func headerAfterLinePatterns(config *Config, filename string) []*regexp.Regexp {
+	ext := strings.ToLower(filepath.Ext(filename))
+	patterns := slices.Clone(defaultHeaderAfterLines[ext])
+	for configured, extra := range config.HeaderAfterLines {
+		if configured == headerAfterLinesAll || NormalizeExtension(configured) == ext {
+			patterns = append(patterns, extra...)
+		}
+	}
+
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		if re, ok := headerAfterPatterns.Load(pattern); ok {
+			compiled = append(compiled, re.(*regexp.Regexp))
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue // rejected when the config is loaded
+		}
+		headerAfterPatterns.Store(pattern, re)
+		compiled = append(compiled, re)
+	}
+	return compiled
+}
+
+// anchoredPreamble extends preamble by the run of lines right after it
+// that match one of the anchors of filename, so the header goes after the
+// last of them
+func anchoredPreamble(config *Config, filename string, lines []string, preamble int) int {
+	patterns := headerAfterLinePatterns(config, filename)
+	for preamble < len(lines) && slices.ContainsFunc(patterns, func(re *regexp.Regexp) bool {
+		return re.MatchString(strings.TrimRight(lines[preamble], " \t\r"))
+	}) {
+		preamble++
+	}
+	return preamble
+}
+
+// validateHeaderPositions checks that HEADER_POSITIONS only uses positions
+// that are supported for the given extensions
+func validateHeaderPositions(positions map[string]string) error {
+	for ext, position := range positions {
+		if position == positionTop {
+			continue
+		}
+		supported, ok := headerPositionExtensions[position]
+		if !ok {
+			return fmt.Errorf("invalid header position '%s' for %s in HEADER_POSITIONS, must be %s or %s", position, ext, positionTop, positionAfterPackage)
+		}
+		if !slices.Contains(supported, NormalizeExtension(ext)) {
+			return fmt.Errorf("header position '%s' is only supported for %s, not %s", position, strings.Join(supported, ", "), ext)
+		}
+	}
+	return nil
+}
+
+// headerInsertLine returns how many lines of content stay above a new
+// header: the preamble and the anchored lines after it by default, or
+// everything up to and including the Go package clause for after-package.
+// The header is kept at the top when the package clause sits too far down
+// for the header to be detected again.
+func headerInsertLine(config *Config, filename string, content []byte, header string, preamble int) int {
+	_, body := SplitBOM(content)
+	lines := SplitLines(body)
+	if headerPosition(config, filename) != positionAfterPackage {
+		return anchoredPreamble(config, filename, lines, preamble)
+	}
+
+	pkg, ok := goPackageLine(lines)
+	if !ok {
+		return preamble
+	}
+
+	// The header starts after the package clause and a blank line
+	spdxOffset := 0
+	for i, line := range strings.Split(header, "\n") {
+		if containsSPDXIdentifier(line) {
+			spdxOffset = i
+			break
+		}
+	}
+	if pkg+2+spdxOffset >= headerSearchLines {
+		return preamble
+	}
+	return pkg + 1
+}
+
+func headerPosition(config *Config, filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for configured, position := range config.HeaderPositions {
+		if NormalizeExtension(configured) == ext {
+			return position
+		}
+	}
+	return positionTop
+}
+
+// goPackageLine returns the index of the package clause of a Go file,
+// which may only be preceded by comments and blank lines
+func goPackageLine(lines []string) (int, bool) {
+	inBlock := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if inBlock {
+			inBlock = !strings.Contains(trimmed, "*/")
+			continue
+		}
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "//"):
+			continue
+		case strings.HasPrefix(trimmed, "/*"):
+			inBlock = !strings.Contains(trimmed[2:], "*/")
+		case strings.HasPrefix(trimmed, "package "):
+			return i, true
+		default:
+			return -1, false
+		}
+	}
+	return -1, false
+}
@@ -0,0 +1,570 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ProcessResult struct {
+	Action   string // "ADD", "REPLACE", "REMOVE", "SKIP"; "ERROR" for a recovered panic
+	Reason   string
+	Modified bool
+
+	// Stack is the goroutine stack of a panic that ended processing the
+	// file, for results with Action "ERROR" set by a caller that recovers
+	Stack string
+
+	// LicenseID is the license of the header a modification leaves in the
+	// file, empty for removals and headers without an SPDX identifier
+	LicenseID string
+
+	// SHA-256 of the file before and after a modification, as used by the
+	// --undo manifest
+	OriginalSum string
+	WrittenSum  string
+}
+
+// ContentSum returns the hex SHA-256 of content
+func ContentSum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// ProcessOptions selects how ProcessContent treats a file
+type ProcessOptions struct {
+	// ForceReplace replaces existing headers that are ours; third-party
+	// ones only together with ReplaceThirdParty (--force)
+	ForceReplace bool
+	RemoveMode   bool
+
+	// ReplaceThirdParty replaces headers and copyright notices that are
+	// not ours (--replace-third-party)
+	ReplaceThirdParty bool
+
+	// Migrate rewrites headers matching LegacyPatterns to the current template
+	Migrate        bool
+	LegacyPatterns []*regexp.Regexp
+
+	// FixLicense rewrites our own headers that declare the wrong license
+	FixLicense bool
+
+	// ValidateSPDX reports headers whose SPDX-License-Identifier is not a
+	// valid expression of the SPDX License List as errors (--check)
+	ValidateSPDX bool
+
+	// Normalize re-renders our own headers in the canonical comment style
+	// of their file type, keeping their text (--normalize)
+	Normalize bool
+
+	// ReplaceOwner renames owners, organizations or departments inside our
+	// own headers, changing nothing else (--replace-owner)
+	ReplaceOwner []OwnerReplacement
+
+	// ReplaceOlderThan, when set, replaces existing headers whose latest
+	// copyright year is before it (--replace-if-older-than): only our own
+	// unless ReplaceThirdParty is set too. Newer headers are left alone.
+	ReplaceOlderThan int
+
+	// FirstYear, when set, dates new headers from a file's first commit
+	// (--git-dates) as a year range ending this year
+	FirstYear func(filename string) (int, bool)
+
+	// HeaderTemplates, when set, replaces the generated header of the file
+	// types it has (--header-dir); see LoadHeaderTemplates
+	HeaderTemplates map[string]string
+
+	// ForceText processes files the binary sniff would skip (--force-text)
+	ForceText bool
+
+	// Encoding EncodingLatin1 reads and writes every file without a UTF-16
+	// byte order mark as Latin-1 and implies ForceText (--encoding latin1)
+	Encoding TextEncoding
+
+	// Preview, when set, receives each change instead of it being written
+	// (--diff); LICENSE management and the --undo record are skipped too
+	Preview func(filename string, original, modified []byte)
+}
+
+func ProcessFile(filename string, config *Config, forceReplace bool, removeMode bool, verbose bool) ProcessResult {
+	return ProcessFileWithOptions(filename, config, ProcessOptions{
+		ForceReplace: forceReplace,
+		RemoveMode:   removeMode,
+	})
+}
+
+// ProcessFileWithOptions is a thin filesystem wrapper around ProcessContent:
+// the file is read once, processed in memory and written back only when
+// modified.
+func ProcessFileWithOptions(filename string, config *Config, opts ProcessOptions) ProcessResult {
+	// Check the extension before opening the file; extensionless files are
+	// sniffed from the prefix read below instead of being opened twice
+	if !shouldProcess(filename, func() bool { return true }, config) {
+		return ProcessResult{
+			Action: "SKIP",
+			Reason: skipReason(filename, func() bool { return isTextFile(filename) }, config),
+		}
+	}
+	
+	content, err := ReadFileForProcessing(filename, func(prefix []byte) bool {
+		if detectEncoding(prefix) != EncodingUTF8 {
+			return false // UTF-16 is decoded in full below
+		}
+		headerInfo := DetectHeaderForConfig(filename, prefix, config)
+		if opts.RemoveMode || len(opts.ReplaceOwner) > 0 {
+			// Nothing to remove or rename: no header in a prefix that
+			// covers every line an SPDX identifier is searched in
+			return !headerInfo.HasHeader && bytes.Count(prefix, []byte("\n")) >= headerSearchLines
+		}
+		// A header in the first lines is enough to skip the file unless it
+		// is going to be replaced
+		return !opts.ForceReplace && !opts.ReplaceThirdParty && !opts.FixLicense && !opts.Normalize && len(opts.ReplaceOwner) == 0 && opts.ReplaceOlderThan == 0 && headerInfo.HasHeader
+	})
+	if err != nil {
+		return ProcessResult{
+			Action: "SKIP",
+			Reason: fmt.Sprintf("Error reading file: %v", err),
+		}
+	}
+	
+	// UTF-16 and Latin-1 files are processed as UTF-8 and written back in
+	// their encoding
+	original := content
+	content, encoding := DecodeText(content)
+	if encoding == EncodingUTF8 && opts.Encoding == EncodingLatin1 {
+		content, encoding = decodeLatin1(content), EncodingLatin1
+	}
+
+	newContent, result := ProcessContent(filename, content, config, opts)
+	if !result.Modified {
+		return result
+	}
+
+	if opts.Preview != nil {
+		opts.Preview(filename, content, newContent)
+		return result
+	}
+
+	// Write the modified content back
+	if encoding == EncodingLatin1 {
+		if newContent, err = encodeLatin1(newContent); err != nil {
+			return ProcessResult{
+				Action: "SKIP",
+				Reason: fmt.Sprintf("Error encoding file: %v", err),
+			}
+		}
+	} else {
+		newContent = EncodeText(newContent, encoding)
+	}
+	if err := writeFile(filename, newContent, 0644); err != nil {
+		return ProcessResult{
+			Action: "SKIP",
+			Reason: fmt.Sprintf("Error writing file: %v", err),
+		}
+	}
+
+	result.OriginalSum = ContentSum(original)
+	result.WrittenSum = ContentSum(newContent)
+	return result
+}
+
+// headerScanBytes bounds how much of a file is read to detect its header.
+// Headers sit in the first lines, so large generated sources that already
+// have one are skipped without being read in full.
+const headerScanBytes = 64 * 1024
+
+// ReadFileForProcessing reads the first headerScanBytes of filename, cut
+// back to the last complete line. If that is the whole file, or prefixDecides
+// reports that the prefix alone settles the outcome, the prefix is
+// returned; otherwise the rest of the file is read from the same handle.
+func ReadFileForProcessing(filename string, prefixDecides func(prefix []byte) bool) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, headerScanBytes)
+	n, err := io.ReadFull(file, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return buf[:n], nil // The whole file fit in the prefix
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if prefix := completeLines(buf); prefix != nil && prefixDecides(prefix) {
+		return prefix, nil
+	}
+
+	rest, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, rest...), nil
+}
+
+// completeLines cuts data back to its last newline, or returns nil if it
+// holds no complete line
+func completeLines(data []byte) []byte {
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		return data[:i+1]
+	}
+	return nil
+}
+
+// sharedDocstringReason skips a header that shares the module docstring
+// with the module's documentation: rewriting or removing it would take
+// part of __doc__ with it
+const sharedDocstringReason = "Header shares the module docstring with its documentation (edit it by hand)"
+
+func ProcessContent(filename string, content []byte, config *Config, opts ProcessOptions) ([]byte, ProcessResult) {
+	// Handle remove mode
+	if opts.RemoveMode {
+		return removeContent(filename, content, config)
+	}
+
+	// Handle fix-license mode
+	if opts.FixLicense {
+		return fixLicenseContent(filename, content, config, opts.HeaderTemplates)
+	}
+
+	// Handle normalize mode
+	if opts.Normalize {
+		return normalizeContent(filename, content, config)
+	}
+
+	// Handle replace-owner mode
+	if len(opts.ReplaceOwner) > 0 {
+		return replaceOwnerContent(filename, content, config, opts.ReplaceOwner)
+	}
+
+	// Handle migrate mode
+	if opts.Migrate {
+		return migrateContent(filename, content, config, opts.LegacyPatterns, opts.HeaderTemplates)
+	}
+
+	// Check if we should process this file type
+	forceText := opts.ForceText || opts.Encoding == EncodingLatin1
+	if !shouldProcess(filename, func() bool { return forceText || isTextContent(content) }, config) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: skipReason(filename, func() bool { return forceText || isTextContent(content) }, config),
+		}
+	}
+	
+	// Get comment style for this file
+	commentStyle, ok := getCommentStyleForContent(filename, content, config)
+	if !ok {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "No comment style available",
+		}
+	}
+	
+	// Detect existing header
+	headerInfo := DetectHeaderForConfig(filename, content, config)
+	if opts.ValidateSPDX && headerInfo.LicenseID != "" {
+		if err := ValidateSPDXExpression(headerInfo.LicenseID); err != nil {
+			return nil, ProcessResult{
+				Action: "SKIP",
+				Reason: fmt.Sprintf("Error: %v", err),
+			}
+		}
+	}
+
+	// --replace-if-older-than leaves headers from its year on alone
+	if (headerInfo.HasHeader || headerInfo.HasThirdPartyCopyright) && opts.ReplaceOlderThan > 0 {
+		if year, ok := headerLatestYear(content, headerInfo); !ok || year >= opts.ReplaceOlderThan {
+			return nil, ProcessResult{
+				Action: "SKIP",
+				Reason: fmt.Sprintf("Header not older than %d", opts.ReplaceOlderThan),
+			}
+		}
+	}
+
+	// A header in a docstring that documents the module is left alone
+	if headerInfo.Docstring && (opts.ForceReplace || opts.ReplaceThirdParty || opts.ReplaceOlderThan > 0) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: sharedDocstringReason,
+		}
+	}
+
+	// Check if file already has header and we're not forcing. --force and
+	// --replace-if-older-than only replace headers that are ours,
+	// --replace-third-party only those that are not.
+	replaceOwn := opts.ForceReplace || opts.ReplaceOlderThan > 0
+	thirdPartyHeader := false
+	if headerInfo.HasHeader {
+		if !replaceOwn && !opts.ReplaceThirdParty {
+			return nil, ProcessResult{
+				Action: "SKIP",
+				Reason: "Header already exists",
+			}
+		}
+		if CanRemoveHeaderContent(content, headerInfo, config) {
+			if !replaceOwn {
+				return nil, ProcessResult{
+					Action: "SKIP",
+					Reason: "Header already exists",
+				}
+			}
+		} else if !opts.ReplaceThirdParty {
+			return nil, ProcessResult{
+				Action: "SKIP",
+				Reason: "Header ownership mismatch (use --replace-third-party to overwrite)",
+			}
+		} else {
+			thirdPartyHeader = true
+		}
+	}
+	
+	// Check for third-party copyright - only overwrite with --replace-third-party
+	if headerInfo.HasThirdPartyCopyright && !opts.ReplaceThirdParty {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "Third-party copyright found (use --replace-third-party to overwrite)",
+		}
+	}
+	
+	// Generate new header
+	years := strconv.Itoa(time.Now().Year())
+	if opts.FirstYear != nil {
+		if first, ok := opts.FirstYear(filename); ok {
+			years = yearRange(first, time.Now().Year())
+		}
+	}
+	headerText := headerTextForFile(config, filename, years, opts.HeaderTemplates)
+	formattedHeader := formatHeaderForConfig(headerText, commentStyle, config)
+	
+	// Rewriting a header that is already the one we would write only
+	// touches the file (and its git history), even under --force
+	if headerIsCurrent(content, headerInfo, formattedHeader) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "Already current",
+		}
+	}
+
+	// Process the file
+	action := "ADD"
+	if headerInfo.HasHeader {
+		action = "REPLACE"
+	} else if headerInfo.HasThirdPartyCopyright {
+		action = "REPLACE"
+	}
+	
+	// A new header goes after the preamble, or where HEADER_POSITIONS says
+	if !headerInfo.HasHeader && !headerInfo.HasThirdPartyCopyright {
+		headerInfo.PreambleLines = headerInsertLine(config, filename, content, formattedHeader, headerInfo.PreambleLines)
+	}
+
+	newContent := modifyContent(content, formattedHeader, headerInfo)
+	
+	reason := fmt.Sprintf("Added %s header", GetLicenseType(config))
+	if headerInfo.HasThirdPartyCopyright {
+		reason = fmt.Sprintf("Replaced third-party copyright with %s header", GetLicenseType(config))
+	} else if thirdPartyHeader {
+		reason = fmt.Sprintf("Replaced third-party header with %s header", GetLicenseType(config))
+	}
+	
+	return newContent, ProcessResult{
+		Action:    action,
+		Reason:    reason,
+		Modified:  true,
+		LicenseID: GetLicenseType(config),
+	}
+}
+
+// headerIsCurrent reports whether the header headerInfo found in content is
+// formattedHeader, ignoring trailing whitespace
+func headerIsCurrent(content []byte, headerInfo HeaderInfo, formattedHeader string) bool {
+	if !headerInfo.HasHeader {
+		return false
+	}
+
+	_, body := SplitBOM(content)
+	lines := SplitLines(body)
+	want := strings.Split(formattedHeader, "\n")
+	start, end := headerInfo.StartLine, headerInfo.EndLine
+	if start < 0 || end >= len(lines) || end-start+1 != len(want) {
+		return false
+	}
+	for i, line := range want {
+		if strings.TrimRight(lines[start+i], " \t") != strings.TrimRight(line, " \t") {
+			return false
+		}
+	}
+	return true
+}
+
+func modifyContent(content []byte, newHeader string, headerInfo HeaderInfo) []byte {
+	// A byte order mark must stay the very first bytes, ahead of the header
+	bom, content := SplitBOM(content)
+	lines, trailingNewline := splitContentLines(content)
+	headerLines := strings.Split(newHeader, "\n")
+	
+	var newContent []string
+	
+	if headerInfo.HasHeader || headerInfo.HasThirdPartyCopyright {
+		// Replace existing header or third-party copyright, preserving any
+		// content before it (shebang, blank lines, unrelated code)
+		start := headerInfo.StartLine
+		if start < 0 {
+			start = 0
+		}
+		if start > len(lines) {
+			start = len(lines)
+		}
+		end := headerInfo.EndLine
+		if end < start-1 {
+			end = start - 1
+		}
+
+		newContent = append(newContent, lines[:start]...)
+		newContent = append(newContent, headerLines...)
+
+		newContent = appendBody(newContent, lines[end+1:])
+	} else {
+		// Add new header
+		if preamble := headerInfo.PreambleLines; preamble > 0 && preamble <= len(lines) {
+			// Keep shebang or parser directives first, add header after
+			newContent = append(newContent, lines[:preamble]...)
+			newContent = append(newContent, "")
+			newContent = append(newContent, headerLines...)
+			newContent = appendBody(newContent, lines[preamble:])
+		} else {
+			// Add header at beginning
+			newContent = append(newContent, headerLines...)
+			newContent = appendBody(newContent, lines)
+		}
+	}
+
+	return append(bom, joinContentLines(newContent, trailingNewline)...)
+}
+
+// appendBody appends the code that follows a header, separated from it by
+// exactly one blank line. Blank lines at the start of body are dropped so
+// files never end up with two or more, and repeated --force runs don't
+// accumulate them.
+func appendBody(newContent []string, body []string) []string {
+	for len(body) > 0 && strings.TrimSpace(body[0]) == "" {
+		body = body[1:]
+	}
+	if len(body) == 0 {
+		return newContent
+	}
+	newContent = append(newContent, "")
+	return append(newContent, body...)
+}
+
+// splitContentLines splits file content into lines for rewriting and
+// reports whether it ended with a newline. An empty file counts as
+// newline-terminated so a header added to it ends with one.
+func splitContentLines(content []byte) ([]string, bool) {
+	text := string(content)
+	if text == "" {
+		return nil, true
+	}
+
+	trailingNewline := strings.HasSuffix(text, "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n"), trailingNewline
+}
+
+// joinContentLines is the inverse of splitContentLines, restoring exactly
+// the original final newline (or its absence)
+func joinContentLines(lines []string, trailingNewline bool) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	
+	text := strings.Join(lines, "\n")
+	if trailingNewline {
+		text += "\n"
+	}
+	return text
+}
+
+func GetLicenseType(config *Config) string {
+	template := GetHeaderTemplate(config)
+	return template.LicenseType
+}
+
+func removeContent(filename string, content []byte, config *Config) ([]byte, ProcessResult) {
+	// Check if we should process this file type
+	if !ShouldProcessContentForConfig(filename, content, config) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: skipReason(filename, func() bool { return isTextContent(content) }, config),
+		}
+	}
+	
+	headerInfo := DetectHeaderForConfig(filename, content, config)
+	if !headerInfo.HasHeader {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "No header found",
+		}
+	}
+	
+	if headerInfo.Docstring {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: sharedDocstringReason,
+		}
+	}
+
+	// Check if we can safely remove the header
+	if !CanRemoveHeaderContent(content, headerInfo, config) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "Header ownership mismatch (safety check)",
+		}
+	}
+	
+	// Anchored lines above the header are followed directly, like the preamble
+	_, body := SplitBOM(content)
+	headerInfo.PreambleLines = anchoredPreamble(config, filename, SplitLines(body), headerInfo.PreambleLines)
+
+	return removeHeaderContent(content, headerInfo), ProcessResult{
+		Action:   "REMOVE",
+		Reason:   "Removed header (ownership match)",
+		Modified: true,
+	}
+}
+
+func LogResult(filename string, result ProcessResult, verbose bool) {
+	if !verbose {
+		return
+	}
+	
+	switch result.Action {
+	case "ADD":
+		fmt.Fprintf(os.Stderr, "[ADD] %s - %s\n", filename, result.Reason)
+	case "REPLACE":
+		fmt.Fprintf(os.Stderr, "[REPLACE] %s - %s\n", filename, result.Reason)
+	case "REMOVE":
+		fmt.Fprintf(os.Stderr, "[REMOVE] %s - %s\n", filename, result.Reason)
+	case "SKIP":
+		fmt.Fprintf(os.Stderr, "[SKIP] %s - %s\n", filename, result.Reason)
+	case "ERROR":
+		fmt.Fprintf(os.Stderr, "[ERROR] %s - %s\n%s", filename, result.Reason, result.Stack)
+	}
+}
\ No newline at end of file
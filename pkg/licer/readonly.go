@@ -0,0 +1,51 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// ErrReadOnly is returned by every write of this package in read-only mode
+var ErrReadOnly = errors.New("read-only mode, no files are written")
+
+// readOnly turns every file write of this package into ErrReadOnly, as a
+// last line of defense behind callers that should not write at all
+var readOnly atomic.Bool
+
+// SetReadOnly makes this package refuse to write files (--read-only):
+// headers, LICENSE files and the config are left as they are
+func SetReadOnly(on bool) {
+	readOnly.Store(on)
+}
+
+// IsReadOnly reports whether SetReadOnly is in effect
+func IsReadOnly() bool {
+	return readOnly.Load()
+}
+
+// writeFile is os.WriteFile unless in read-only mode
+func writeFile(name string, data []byte, perm os.FileMode) error {
+	if readOnly.Load() {
+		return fmt.Errorf("%s: %w", name, ErrReadOnly)
+	}
+	return os.WriteFile(name, data, perm)
+}
+
+// renameFile is os.Rename unless in read-only mode
+func renameFile(oldpath, newpath string) error {
+	if readOnly.Load() {
+		return fmt.Errorf("%s: %w", oldpath, ErrReadOnly)
+	}
+	return os.Rename(oldpath, newpath)
+}
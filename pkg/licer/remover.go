@@ -0,0 +1,207 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+func CanRemoveHeader(filename string, config *Config) (bool, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+	
+	return CanRemoveHeaderContent(content, DetectHeaderForConfig(filename, content, config), config), nil
+}
+
+func CanRemoveHeaderContent(content []byte, headerInfo HeaderInfo, config *Config) bool {
+	// First, check if there's a header with SPDX identifier
+	if !headerInfo.HasHeader {
+		return false // No header to remove
+	}
+	
+	_, content = SplitBOM(content)
+	lines := strings.Split(string(content), "\n")
+	
+	// Extract header lines
+	var headerLines []string
+	start := headerInfo.StartLine
+	end := headerInfo.EndLine
+	
+	if start < len(lines) && end < len(lines) {
+		headerLines = lines[start:end+1]
+	}
+	
+	headerText := strings.Join(headerLines, "\n")
+	headerLower := strings.ToLower(headerText)
+	
+	// Check for SPDX identifier (case-insensitive), or a header format
+	// recognized without one
+	hasSPDX := strings.Contains(headerLower, "spdx-license-identifier")
+	if !hasSPDX && headerInfo.Format == "" {
+		return false // No SPDX identifier, not safe to remove
+	}
+	
+	// Check ownership - must contain user's name, organization name or one
+	// of the configured aliases. Comment markers are stripped and whitespace
+	// collapsed first, so a name wrapped onto the next comment line or
+	// written with extra spaces still matches.
+	var plain []string
+	for _, line := range headerLines {
+		plain = append(plain, stripCommentMarkers(line))
+	}
+	headerText = collapseWhitespace(strings.Join(plain, " "))
+	for _, owner := range ownerNames(config) {
+		if strings.Contains(headerText, owner) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ownerNames returns the whitespace-normalized names that identify a header
+// as ours: the full name, the organization, the copyright owner when it is
+// overridden and any OWNER_ALIASES
+func ownerNames(config *Config) []string {
+	names := []string{collapseWhitespace(config.FullName), collapseWhitespace(config.Organization)}
+	if owner := collapseWhitespace(config.CopyrightOwner); owner != "" {
+		names = append(names, owner)
+	}
+	for _, alias := range config.OwnerAliases {
+		if alias = collapseWhitespace(alias); alias != "" {
+			names = append(names, alias)
+		}
+	}
+	return names
+}
+
+// commentMarkers lists every comment opener and closer licer writes, plus
+// the " * " continuation of block comments, longest first
+var commentMarkers = collectCommentMarkers()
+
+func collectCommentMarkers() []string {
+	seen := map[string]bool{"*": true}
+	for _, style := range CommentStyles {
+		for _, marker := range []string{style.Line, style.BlockStart, style.BlockEnd} {
+			if marker != "" {
+				seen[marker] = true
+			}
+		}
+	}
+
+	markers := make([]string, 0, len(seen))
+	for marker := range seen {
+		markers = append(markers, marker)
+	}
+	sort.Slice(markers, func(i, j int) bool {
+		if len(markers[i]) != len(markers[j]) {
+			return len(markers[i]) > len(markers[j])
+		}
+		return markers[i] < markers[j]
+	})
+	return markers
+}
+
+// stripCommentMarkers undoes FormatHeader for one line: it removes a
+// leading marker followed by whitespace and a trailing block closer, so
+// only the header text is left. Alphabetic closers (Fortran "C") are not
+// stripped from the end, where they would eat the last letter of a name.
+func stripCommentMarkers(line string) string {
+	text := strings.TrimSpace(line)
+	for _, marker := range commentMarkers {
+		if text == marker {
+			return ""
+		}
+		if strings.HasPrefix(text, marker+" ") || strings.HasPrefix(text, marker+"\t") {
+			text = strings.TrimSpace(text[len(marker):])
+			break
+		}
+	}
+
+	for _, marker := range commentMarkers {
+		if unicode.IsLetter(rune(marker[0])) {
+			continue
+		}
+		if strings.HasSuffix(text, " "+marker) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, marker))
+			break
+		}
+	}
+
+	return text
+}
+
+// collapseWhitespace trims s and replaces every run of whitespace with a
+// single space
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// RemoveHeader deletes the detected header of filename together with the
+// blank lines around it, so the file starts directly with its shebang or
+// other preamble, or with its code
+func RemoveHeader(filename string) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	
+	// Detect the header
+	headerInfo := DetectHeaderInFile(filename, content)
+	if !headerInfo.HasHeader {
+		return nil // Nothing to remove
+	}
+	
+	return writeFile(filename, removeHeaderContent(content, headerInfo), 0644)
+}
+
+func removeHeaderContent(content []byte, headerInfo HeaderInfo) []byte {
+	bom, content := SplitBOM(content)
+	lines, trailingNewline := splitContentLines(content)
+	var newContent []string
+	
+	// Keep everything above the header (shebang, parser directives, or the
+	// Go package clause of an after-package header) but not the blank lines
+	// that separated it from the header
+	start := headerInfo.StartLine
+	if start < 0 {
+		start = 0
+	}
+	if start > len(lines) {
+		start = len(lines)
+	}
+	kept := lines[:start]
+	for len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+		kept = kept[:len(kept)-1]
+	}
+	newContent = append(newContent, kept...)
+	
+	// Skip header lines and any blank lines immediately following
+	skipIndex := headerInfo.EndLine + 1
+	for skipIndex < len(lines) && strings.TrimSpace(lines[skipIndex]) == "" {
+		skipIndex++
+	}
+	
+	// Add remaining content; code above the header stays separated from it
+	// by one blank line, while a preamble is followed directly
+	if skipIndex < len(lines) {
+		if len(kept) > headerInfo.PreambleLines {
+			newContent = append(newContent, "")
+		}
+		newContent = append(newContent, lines[skipIndex:]...)
+	}
+	
+	return append(bom, joinContentLines(newContent, trailingNewline)...)
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OwnerReplacement renames an owner, organization or department in
+// existing headers (--replace-owner old=new)
+type OwnerReplacement struct {
+	Old string
+	New string
+}
+
+// ParseOwnerReplacement parses the old=new argument of --replace-owner.
+// Only the first "=" separates the two, so the new name may contain one.
+func ParseOwnerReplacement(value string) (OwnerReplacement, error) {
+	old, new, ok := strings.Cut(value, "=")
+	old, new = strings.TrimSpace(old), strings.TrimSpace(new)
+	if !ok || old == "" || new == "" {
+		return OwnerReplacement{}, fmt.Errorf("invalid replacement %q, must be old=new", value)
+	}
+	if old == new {
+		return OwnerReplacement{}, fmt.Errorf("invalid replacement %q, old and new are the same", value)
+	}
+	return OwnerReplacement{Old: old, New: new}, nil
+}
+
+// replaceOwnerContent applies replacements to the lines of a header that is
+// ours (ownership match), leaving everything else, including its license,
+// year and layout, as it is. Third-party headers are never touched.
+func replaceOwnerContent(filename string, content []byte, config *Config, replacements []OwnerReplacement) ([]byte, ProcessResult) {
+	if !ShouldProcessContentForConfig(filename, content, config) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: skipReason(filename, func() bool { return isTextContent(content) }, config),
+		}
+	}
+
+	headerInfo := DetectHeaderForConfig(filename, content, config)
+	if !headerInfo.HasHeader {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "No header found",
+		}
+	}
+
+	if !CanRemoveHeaderContent(content, headerInfo, config) {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "Header ownership mismatch (safety check)",
+		}
+	}
+
+	// Line numbers of the header are those of SplitLines; splitting on
+	// "\n" alone keeps any "\r" and the final newline as they are
+	bom, body := SplitBOM(content)
+	lines := strings.Split(string(body), "\n")
+	var renamed []string
+	for i := headerInfo.StartLine; i >= 0 && i <= headerInfo.EndLine && i < len(lines); i++ {
+		for _, r := range replacements {
+			if strings.Contains(lines[i], r.Old) {
+				lines[i] = strings.ReplaceAll(lines[i], r.Old, r.New)
+				renamed = append(renamed, fmt.Sprintf("%s -> %s", r.Old, r.New))
+			}
+		}
+	}
+	if len(renamed) == 0 {
+		return nil, ProcessResult{
+			Action: "SKIP",
+			Reason: "No owner to replace in header",
+		}
+	}
+
+	return append(bom, strings.Join(lines, "\n")...), ProcessResult{
+		Action:    "REPLACE",
+		Reason:    "Renamed " + strings.Join(renamed, ", "),
+		Modified:  true,
+		LicenseID: headerInfo.LicenseID,
+	}
+}
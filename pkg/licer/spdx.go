@@ -0,0 +1,150 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package licer
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+)
+
+// The SPDX License List is embedded so ids are checked without network
+// access. Update the files from https://github.com/spdx/license-list-data.
+var (
+	//go:embed spdx_licenses.txt
+	spdxLicenseData string
+
+	//go:embed spdx_exceptions.txt
+	spdxExceptionData string
+
+	spdxLicenses   = parseSPDXList(spdxLicenseData)
+	spdxExceptions = parseSPDXList(spdxExceptionData)
+)
+
+// parseSPDXList reads one id per line, skipping comments, into a set keyed
+// by the lowercase id, as SPDX ids are matched case-insensitively
+func parseSPDXList(data string) map[string]bool {
+	ids := map[string]bool{}
+	for _, line := range strings.Split(data, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			ids[strings.ToLower(line)] = true
+		}
+	}
+	return ids
+}
+
+// ValidateSPDXExpression checks that expr is a valid SPDX license
+// expression: ids of the SPDX License List (optionally with "+"), or
+// LicenseRef-/DocumentRef- references, combined with AND, OR, WITH an
+// exception id, and parentheses
+func ValidateSPDXExpression(expr string) error {
+	p := spdxParser{tokens: tokenizeSPDX(expr)}
+	if len(p.tokens) == 0 {
+		return fmt.Errorf("empty SPDX license expression")
+	}
+	if err := p.parseOr(); err != nil {
+		return fmt.Errorf("invalid SPDX license expression %q: %w", expr, err)
+	}
+	if p.pos < len(p.tokens) {
+		return fmt.Errorf("invalid SPDX license expression %q: unexpected %q", expr, p.tokens[p.pos])
+	}
+	return nil
+}
+
+// tokenizeSPDX splits expr into ids, operators and parentheses
+func tokenizeSPDX(expr string) []string {
+	expr = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expr)
+	return strings.Fields(expr)
+}
+
+// spdxParser is a recursive-descent parser of the SPDX expression grammar,
+// where WITH binds tighter than AND, and AND tighter than OR
+type spdxParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *spdxParser) next() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *spdxParser) parseOr() error {
+	if err := p.parseAnd(); err != nil {
+		return err
+	}
+	for p.next() == "OR" {
+		p.pos++
+		if err := p.parseAnd(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *spdxParser) parseAnd() error {
+	if err := p.parseWith(); err != nil {
+		return err
+	}
+	for p.next() == "AND" {
+		p.pos++
+		if err := p.parseWith(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *spdxParser) parseWith() error {
+	token := p.next()
+	if token == "(" {
+		p.pos++
+		if err := p.parseOr(); err != nil {
+			return err
+		}
+		if p.next() != ")" {
+			return fmt.Errorf("missing )")
+		}
+		p.pos++
+		return nil
+	}
+
+	if err := validateSPDXLicenseID(token); err != nil {
+		return err
+	}
+	p.pos++
+	if p.next() != "WITH" {
+		return nil
+	}
+	p.pos++
+	exception := p.next()
+	if !spdxExceptions[strings.ToLower(exception)] {
+		return fmt.Errorf("unknown license exception %q", exception)
+	}
+	p.pos++
+	return nil
+}
+
+// validateSPDXLicenseID checks a single license id of an expression
+func validateSPDXLicenseID(id string) error {
+	switch {
+	case id == "":
+		return fmt.Errorf("missing license id")
+	case id == "AND" || id == "OR" || id == "WITH" || id == "(" || id == ")":
+		return fmt.Errorf("unexpected %q", id)
+	case strings.HasPrefix(id, "LicenseRef-") || strings.HasPrefix(id, "DocumentRef-"):
+		return nil
+	case spdxLicenses[strings.ToLower(strings.TrimSuffix(id, "+"))]:
+		return nil
+	}
+	return fmt.Errorf("unknown license id %q", id)
+}
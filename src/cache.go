@@ -0,0 +1,143 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/licer/licer/pkg/licer"
+)
+
+// resultCacheName is the file in .git that --cache keeps its entries in
+const resultCacheName = "licer-cache.json"
+
+// ResultCache remembers the files that already had a header, so repeated
+// --cache runs skip reading them while their size and modification time
+// are unchanged. Entries are only trusted for the config they were
+// recorded with.
+type ResultCache struct {
+	ConfigHash string                `json:"config_hash"`
+	Files      map[string]CachedFile `json:"files"` // relative to the repository root
+
+	repoRoot string
+	mu       sync.Mutex
+	seen     map[string]bool
+}
+
+// CachedFile is the state of a file when it was last seen with a header
+type CachedFile struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mtime_ns"`
+}
+
+// cacheConfigHash identifies everything besides a file's content that
+// decides whether it gets a header: the effective config and the per-run
+// extension overrides
+func cacheConfigHash(config *licer.Config, excludeExt, includeExt []string) string {
+	data, _ := json.Marshal(struct {
+		Config     *licer.Config
+		ExcludeExt []string
+		IncludeExt []string
+	}{config, excludeExt, includeExt})
+	return licer.ContentSum(data)
+}
+
+// loadResultCache reads the cache of repoRoot. A missing or unreadable
+// cache, or one recorded with a different config, starts out empty.
+func loadResultCache(repoRoot, configHash string) *ResultCache {
+	cache := &ResultCache{}
+	if data, err := os.ReadFile(filepath.Join(repoRoot, ".git", resultCacheName)); err == nil {
+		json.Unmarshal(data, cache)
+	}
+	if cache.ConfigHash != configHash || cache.Files == nil {
+		cache.Files = map[string]CachedFile{}
+	}
+	cache.ConfigHash = configHash
+	cache.repoRoot = repoRoot
+	cache.seen = map[string]bool{}
+	return cache
+}
+
+// HasHeader reports whether filename had a header when it was last seen
+// and has not changed since
+func (c *ResultCache) HasHeader(filename string, info os.FileInfo) bool {
+	key, ok := c.key(filename)
+	if !ok {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[key] = true
+	cached, ok := c.Files[key]
+	return ok && cached.Size == info.Size() && cached.ModTime == info.ModTime().UnixNano()
+}
+
+// Update records the result of processing filename, which had the given
+// info beforehand. Files that had or were given a header are remembered;
+// anything else is forgotten. written tells whether modified files were
+// actually written, as opposed to previewed.
+func (c *ResultCache) Update(filename string, info os.FileInfo, result licer.ProcessResult, written bool) {
+	key, ok := c.key(filename)
+	if !ok {
+		return
+	}
+
+	switch {
+	case result.Action == "SKIP" && result.Reason == "Header already exists":
+	case result.Modified && written:
+		var err error
+		if info, err = os.Stat(filename); err != nil {
+			info = nil
+		}
+	default:
+		info = nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[key] = true
+	if info == nil {
+		delete(c.Files, key)
+		return
+	}
+	c.Files[key] = CachedFile{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+}
+
+// Save writes the cache back to .git. With prune, after a run over the
+// whole repository, entries of files that were not seen are dropped.
+func (c *ResultCache) Save(prune bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if prune {
+		for key := range c.Files {
+			if !c.seen[key] {
+				delete(c.Files, key)
+			}
+		}
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.repoRoot, ".git", resultCacheName), append(data, '\n'), 0644)
+}
+
+func (c *ResultCache) key(filename string) (string, bool) {
+	rel, err := filepath.Rel(c.repoRoot, filename)
+	if err != nil {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
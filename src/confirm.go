@@ -0,0 +1,141 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/licer/licer/pkg/licer"
+)
+
+// changePlan counts the changes a run would make, from a pass that writes
+// nothing, for the confirmation before --force or --remove rewrite files
+// and before the first run in a repository
+type changePlan struct {
+	Files        int64
+	Replacements int64
+	ThirdParty   int64 // third-party headers overwritten, counted in Replacements
+	Removals     int64
+
+	listMu sync.Mutex
+	list   io.Writer // when set, receives a line for every file that would change
+}
+
+// count records the result of filename, a file the run would modify
+func (p *changePlan) count(filename string, result licer.ProcessResult) {
+	if p.list != nil {
+		p.listMu.Lock()
+		fmt.Fprintf(p.list, "  [%s] %s - %s\n", result.Action, filename, result.Reason)
+		p.listMu.Unlock()
+	}
+
+	atomic.AddInt64(&p.Files, 1)
+	switch result.Action {
+	case "REPLACE":
+		atomic.AddInt64(&p.Replacements, 1)
+		if strings.HasPrefix(result.Reason, "Replaced third-party") {
+			atomic.AddInt64(&p.ThirdParty, 1)
+		}
+	case "REMOVE":
+		atomic.AddInt64(&p.Removals, 1)
+	}
+}
+
+// needsConfirmation reports whether this run rewrites or removes existing
+// headers and should ask first
+func needsConfirmation() bool {
+	return (force || replaceThirdParty || remove) && !yes && !diff && !check && !readOnly
+}
+
+// needsFirstRunConfirmation reports whether the first run in a repository
+// should ask before writing. Like the hook prompt, it is never asked in the
+// unattended mode of --git-folder, nor with --yes.
+func needsFirstRunConfirmation() bool {
+	return len(gitFolders) == 0 && !yes && !diff && !check && !readOnly && !needsConfirmation()
+}
+
+// confirmChanges shows plan on out and reads the answer from in; anything
+// but y or yes declines
+func confirmChanges(in io.Reader, out io.Writer, plan *changePlan) bool {
+	fmt.Fprintf(out, "About to modify %d files (%d replacements, %d third-party overwrites, %d removals); proceed? (y/N): ",
+		plan.Files, plan.Replacements, plan.ThirdParty, plan.Removals)
+
+	response, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && response == "" {
+		fmt.Fprintln(out)
+		return false
+	}
+	
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
+
+// firstRunMarkerName is the file in .git that marks a repository licer has
+// written to. Unlike the run manifest, --undo leaves it in place.
+const firstRunMarkerName = "licer-initialized"
+
+func firstRunMarkerPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".git", firstRunMarkerName)
+}
+
+// writeFirstRunMarker records that licer has written to repoRoot, so later
+// runs don't ask again
+func writeFirstRunMarker(repoRoot string) error {
+	if _, err := os.Stat(firstRunMarkerPath(repoRoot)); err == nil {
+		return nil
+	}
+	return os.WriteFile(firstRunMarkerPath(repoRoot), nil, 0644)
+}
+
+// firstRunRepos returns the repositories among repoRoots that licer has
+// never written to: those with neither the first-run marker nor a run
+// manifest from before the marker existed
+func firstRunRepos(repoRoots []string) []string {
+	var repos []string
+	for _, repoRoot := range repoRoots {
+		_, markerErr := os.Stat(firstRunMarkerPath(repoRoot))
+		_, manifestErr := os.Stat(manifestPath(repoRoot))
+		if errors.Is(markerErr, os.ErrNotExist) && errors.Is(manifestErr, os.ErrNotExist) {
+			repos = append(repos, repoRoot)
+		}
+	}
+	return repos
+}
+
+// confirmFirstRun lists the files the first run in repos would change, as
+// scan counts them into plan without writing anything, and asks whether to
+// go ahead. It guards against running licer in the wrong directory. When
+// there is nothing to change it doesn't ask.
+func confirmFirstRun(in io.Reader, out io.Writer, repos []string, plan *changePlan, scan func(repoRoot string) error) bool {
+	var list bytes.Buffer
+	plan.list = &list
+	for _, repoRoot := range repos {
+		if err := scan(repoRoot); err != nil {
+			fmt.Fprintf(out, "[ERROR] %s: %v\n", repoRoot, err)
+			return false
+		}
+	}
+	if plan.Files == 0 {
+		return true
+	}
+	fmt.Fprintf(out, "First licer run in %s. It would change:\n", strings.Join(repos, ", "))
+	list.WriteTo(out)
+	fmt.Fprintln(out, "The LICENSE file is created or updated as needed. Later runs don't ask; --yes skips this question.")
+	return confirmChanges(in, out, plan)
+}
@@ -13,107 +13,222 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+
+	"github.com/licer/licer/pkg/licer"
 )
 
 type Crawler struct {
-	config      *Config
-	forceReplace bool
-	removeMode  bool
-	verbose     bool
-	stats       *ProcessingStats
+	config  *licer.Config
+	opts    licer.ProcessOptions
+	verbose bool
+	summary bool         // print the final summary and errors even when not verbose
+	logger  ResultLogger // per-file results and summaries, text or --log-json
+	stats   *ProcessingStats
+	slots   chan struct{} // global worker pool, bounds concurrent file opens
+
+	modifiedMu sync.Mutex
+	modified   []ModifiedFile // files rewritten by this crawler, for --undo
+
+	unsupportedMu sync.Mutex
+	unsupported   []string // text files with no known comment style, for --strict
+
+	skippedDirsMu sync.Mutex
+	skippedDirs   []string // directories that could not be read, so were not scanned
+
+	sortResults bool // --sorted: hold back the per-file results and log them by path at the end
+	resultsMu   sync.Mutex
+	results     []fileResult
+
+	cache *ResultCache // files known to have a header, for --cache
+
+	skipLicenseFile bool // --no-license-file: leave LICENSE and NOTICE alone
+	licenseFileOnly bool // --license-file-only: manage LICENSE and NOTICE, not headers
+
+	root string // real path of the repository; files resolving outside it are refused
+
+	dirConfigsMu sync.Mutex
+	dirConfigs   map[string]*licer.Config // config per directory, for files processed without the walk
+
+	plan  *changePlan  // counts the changes of a pass that writes nothing, for the confirmation
+	edits *editPlanner // collects the edits of --diff --format=json
+}
+
+// fileResult is a per-file result held back until the end of a --sorted run
+type fileResult struct {
+	filename string
+	result   licer.ProcessResult
 }
 
 type ProcessingStats struct {
-	FilesProcessed int64
-	FilesModified  int64
-	FilesSkipped   int64
-	FilesErrored   int64
+	FilesProcessed   int64
+	FilesModified    int64
+	FilesSkipped     int64
+	FilesErrored     int64
+	FilesUnsupported int64 // skipped for having no known comment style, counted in FilesSkipped
+	DirsSkipped      int64 // directories that could not be read, with everything below them
+
+	licenses sync.Map // license id -> *int64, headers written per license
+}
+
+// addLicense counts a header written with license
+func (s *ProcessingStats) addLicense(license string, n int64) {
+	counter, _ := s.licenses.LoadOrStore(license, new(int64))
+	atomic.AddInt64(counter.(*int64), n)
 }
 
-func NewCrawler(config *Config, forceReplace, removeMode, verbose bool) *Crawler {
+// LicenseCounts returns the number of headers written per license id
+func (s *ProcessingStats) LicenseCounts() map[string]int64 {
+	counts := map[string]int64{}
+	s.licenses.Range(func(license, counter interface{}) bool {
+		counts[license.(string)] = atomic.LoadInt64(counter.(*int64))
+		return true
+	})
+	return counts
+}
+
+func NewCrawler(config *licer.Config, opts licer.ProcessOptions, verbose, summary bool, jobs int) *Crawler {
+	if jobs < 1 {
+		jobs = 1
+	}
 	return &Crawler{
-		config:      config,
-		forceReplace: forceReplace,
-		removeMode:  removeMode,
-		verbose:     verbose,
-		stats:       &ProcessingStats{},
+		config:  config,
+		opts:    opts,
+		verbose: verbose,
+		summary: summary,
+		logger:  newResultLogger(verbose, summary),
+		stats:   &ProcessingStats{},
+		slots:   make(chan struct{}, jobs),
 	}
 }
 
 func (c *Crawler) ProcessRepository(repoRoot string) error {
 	if c.verbose {
-		fmt.Printf("Starting parallel processing of repository: %s\n", repoRoot)
+		fmt.Fprintf(os.Stderr, "Starting parallel processing of repository: %s\n", repoRoot)
 	}
+	c.root = realPath(repoRoot)
 	
-	// Manage LICENSE file first (only if not in remove mode)
-	if !c.removeMode {
-		err := ManageLicenseFile(repoRoot, c.config, c.verbose)
+	// Manage LICENSE file first (only if not in remove or preview mode)
+	if !c.skipLicenseFile && !c.opts.RemoveMode && c.opts.Preview == nil {
+		err := licer.ManageLicenseFile(repoRoot, c.config, c.verbose)
 		if err != nil {
-			if c.verbose {
-				fmt.Printf("[LICENSE] Error managing LICENSE file: %v\n", err)
+			if c.verbose || c.summary {
+				fmt.Fprintf(os.Stderr, "[LICENSE] Error managing LICENSE file: %v\n", err)
+			}
+		}
+		if err := licer.ManageNoticeFile(repoRoot, c.config, c.verbose); err != nil {
+			if c.verbose || c.summary {
+				fmt.Fprintf(os.Stderr, "[LICENSE] Error managing NOTICE file: %v\n", err)
 			}
 		}
 	}
+	if c.licenseFileOnly {
+		return nil
+	}
 	
-	err := c.processDirectoryRecursive(repoRoot)
+	err := c.processDirectoryRecursive(repoRoot, c.config)
+	c.flushResults()
 	if err != nil {
 		return err
 	}
 	
+	if c.verbose || c.summary {
+		c.printStats()
+	}
+	
+	return nil
+}
+
+// ProcessFiles processes an explicit list of paths relative to repoRoot
+// instead of crawling it, e.g. the files changed since a git ref. Paths
+// that no longer exist are skipped. The LICENSE file is left alone since
+// this is a partial run.
+func (c *Crawler) ProcessFiles(repoRoot string, files []string) error {
 	if c.verbose {
+		fmt.Fprintf(os.Stderr, "Processing %d changed files in repository: %s\n", len(files), repoRoot)
+	}
+	c.root = realPath(repoRoot)
+
+	var wg sync.WaitGroup
+	for _, name := range files {
+		filename := filepath.Join(repoRoot, name)
+		if info, err := os.Stat(filename); err != nil || !info.Mode().IsRegular() {
+			continue // Deleted since the ref, or not a regular file
+		}
+
+		c.acquire()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer c.release()
+			c.processInRepo(repoRoot, filename)
+		}()
+	}
+	wg.Wait()
+	c.flushResults()
+	
+	if c.verbose || c.summary {
 		c.printStats()
 	}
 	
 	return nil
 }
 
-func (c *Crawler) processDirectoryRecursive(dir string) error {
+// processDirectoryRecursive processes the files below dir with config, the
+// effective config of its parent directory with the .licer.yml of every
+// directory on the way down merged over it
+func (c *Crawler) processDirectoryRecursive(dir string, config *licer.Config) error {
 	// Check if this is the .git directory (skip it)
 	if filepath.Base(dir) == ".git" {
 		return nil
 	}
 	
+	config, err := repoConfigFor(config, dir)
+	if err != nil {
+		if c.verbose || c.summary {
+			c.logErrorSafe("[ERROR] Skipping directory %s: %v\n", dir, err)
+		}
+		c.recordSkippedDir(dir)
+		return nil // Never fall back to the parent's copyright holder
+	}
+
+	c.acquire()
 	entries, err := os.ReadDir(dir)
+	c.release()
 	if err != nil {
-		if c.verbose {
-			fmt.Printf("[ERROR] Failed to read directory %s: %v\n", dir, err)
+		if c.verbose || c.summary {
+			c.logErrorSafe("[ERROR] Failed to read directory %s: %v\n", dir, err)
 		}
+		c.recordSkippedDir(dir)
 		return nil // Don't fail completely, just skip this directory
 	}
 	
 	var wg sync.WaitGroup
 	
-	// Process files in current directory sequentially
+	// Process files in current directory; each file holds a worker slot
+	// while it is open so --jobs bounds the I/O pressure on slow storage
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
 		
 		filename := filepath.Join(dir, entry.Name())
-		result := ProcessFile(filename, c.config, c.forceReplace, c.removeMode, false) // Don't log here to avoid race conditions
-
-		// Update statistics
-		atomic.AddInt64(&c.stats.FilesProcessed, 1)
-		if result.Modified {
-			atomic.AddInt64(&c.stats.FilesModified, 1)
-		} else if strings.HasPrefix(result.Reason, "Error") {
-			atomic.AddInt64(&c.stats.FilesErrored, 1)
-		} else if result.Action == "SKIP" {
-			atomic.AddInt64(&c.stats.FilesSkipped, 1)
-		}
-		
-		// Log result in thread-safe way
-		if c.verbose {
-			c.logResultSafe(filename, result)
-		}
+		c.acquire()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer c.release()
+			c.processFile(filename, config)
+		}()
 	}
 	
-	// Launch workers for subdirectories with per-directory concurrency limit
-	semaphore := make(chan struct{}, 10) // Limit to 10 concurrent subdirs per directory level
-	
+	// Launch workers for subdirectories. Directory goroutines don't hold a
+	// slot while waiting on their children, otherwise a deep tree could
+	// exhaust the pool and deadlock.
 	for _, entry := range entries {
 		if !entry.IsDir() || entry.Name() == ".git" {
 			continue
@@ -123,37 +238,354 @@ func (c *Crawler) processDirectoryRecursive(dir string) error {
 		go func(subdirName string) {
 			defer wg.Done()
 			
-			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-			
 			subdirPath := filepath.Join(dir, subdirName)
-			if err := c.processDirectoryRecursive(subdirPath); err != nil {
-				if c.verbose {
-					fmt.Printf("[ERROR] Failed processing directory %s: %v\n", subdirPath, err)
+			if err := c.processDirectoryRecursive(subdirPath, config); err != nil {
+				if c.verbose || c.summary {
+					c.logErrorSafe("[ERROR] Failed processing directory %s: %v\n", subdirPath, err)
 				}
 			}
 		}(entry.Name())
 	}
 	
-	// Wait for all subdirectory workers to complete
+	// Wait for all file and subdirectory workers to complete
 	wg.Wait()
 	return nil
 }
 
+func (c *Crawler) acquire() {
+	c.slots <- struct{}{}
+}
+
+func (c *Crawler) release() {
+	<-c.slots
+}
+
+// processInRepo processes filename of repoRoot, found without walking down
+// to it, with the config of its directory
+func (c *Crawler) processInRepo(repoRoot, filename string) licer.ProcessResult {
+	config, err := c.configForDir(filepath.Clean(repoRoot), filepath.Dir(filename))
+	if err != nil {
+		return c.recordResult(filename, licer.ProcessResult{
+			Action: "SKIP",
+			Reason: fmt.Sprintf("Error: %v", err),
+		})
+	}
+	return c.processFile(filename, config)
+}
+
+// configForDir returns the config of dir inside repoRoot, the crawler's
+// config with the .licer.yml files from repoRoot down to dir merged in
+// order, as processDirectoryRecursive would have it
+func (c *Crawler) configForDir(repoRoot, dir string) (*licer.Config, error) {
+	c.dirConfigsMu.Lock()
+	config, ok := c.dirConfigs[dir]
+	c.dirConfigsMu.Unlock()
+	if ok {
+		return config, nil
+	}
+
+	parent := c.config
+	if dir != repoRoot && filepath.Dir(dir) != dir {
+		var err error
+		if parent, err = c.configForDir(repoRoot, filepath.Dir(dir)); err != nil {
+			return nil, err
+		}
+	}
+	config, err := repoConfigFor(parent, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c.dirConfigsMu.Lock()
+	if c.dirConfigs == nil {
+		c.dirConfigs = map[string]*licer.Config{}
+	}
+	c.dirConfigs[dir] = config
+	c.dirConfigsMu.Unlock()
+	return config, nil
+}
+
+func (c *Crawler) processFile(filename string, config *licer.Config) licer.ProcessResult {
+	result := c.recordResult(filename, c.safeProcess(filename, config)) // Don't log here to avoid race conditions
+	if result.Modified && result.LicenseID != "" {
+		c.stats.addLicense(result.LicenseID, 1)
+	}
+	return result
+}
+
+// recordResult counts result of filename in the statistics and logs it
+func (c *Crawler) recordResult(filename string, result licer.ProcessResult) licer.ProcessResult {
+	if c.plan != nil && result.Modified {
+		c.plan.count(filename, result)
+	}
+	if c.edits != nil && result.Modified {
+		c.edits.record(filename, result)
+	}
+
+	// Update statistics
+	atomic.AddInt64(&c.stats.FilesProcessed, 1)
+	if result.Modified {
+		atomic.AddInt64(&c.stats.FilesModified, 1)
+		c.modifiedMu.Lock()
+		c.modified = append(c.modified, ModifiedFile{
+			Path:           filename,
+			OriginalSHA256: result.OriginalSum,
+			WrittenSHA256:  result.WrittenSum,
+		})
+		c.modifiedMu.Unlock()
+	} else if strings.HasPrefix(result.Reason, "Error") {
+		atomic.AddInt64(&c.stats.FilesErrored, 1)
+	} else if result.Action == "SKIP" {
+		atomic.AddInt64(&c.stats.FilesSkipped, 1)
+		if result.Reason == "No comment style available" {
+			atomic.AddInt64(&c.stats.FilesUnsupported, 1)
+			c.unsupportedMu.Lock()
+			c.unsupported = append(c.unsupported, filename)
+			c.unsupportedMu.Unlock()
+		}
+	}
+
+	// Log result in thread-safe way; the logger decides what to report
+	c.logResultSafe(filename, result)
+
+	return result
+}
+
+// safeProcess is process with a panic turned into an ERROR result for
+// filename that carries the stack, so one pathological file doesn't end the
+// run and its summary
+func (c *Crawler) safeProcess(filename string, config *licer.Config) (result licer.ProcessResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = licer.ProcessResult{
+				Action: "ERROR",
+				Reason: fmt.Sprintf("Error: panic while processing: %v", r),
+				Stack:  string(debug.Stack()),
+			}
+		}
+	}()
+	return c.process(filename, config)
+}
+
+// process runs licer on filename with config. With --cache, files that are unchanged
+// since they were last seen with a header are skipped without reading them,
+// except with --check, which has to read every header to validate its SPDX id.
+func (c *Crawler) process(filename string, config *licer.Config) licer.ProcessResult {
+	if c.root != "" {
+		if err := checkWithinRoot(c.root, filename); err != nil {
+			return licer.ProcessResult{
+				Action: "SKIP",
+				Reason: fmt.Sprintf("Error: %v", err),
+			}
+		}
+	}
+
+	if c.cache == nil {
+		return licer.ProcessFileWithOptions(filename, config, c.opts)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil || !info.Mode().IsRegular() {
+		return licer.ProcessFileWithOptions(filename, config, c.opts)
+	}
+	if !c.opts.ValidateSPDX && c.cache.HasHeader(filename, info) {
+		return licer.ProcessResult{
+			Action: "SKIP",
+			Reason: "Header already exists (cached)",
+		}
+	}
+
+	result := licer.ProcessFileWithOptions(filename, config, c.opts)
+	c.cache.Update(filename, info, result, c.opts.Preview == nil)
+	return result
+}
+
+// realPath returns path with symlinks resolved, or path itself when it
+// can't be resolved
+func realPath(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	return path
+}
+
+// checkWithinRoot returns an error when filename is a regular file whose
+// real path is outside root, which must be a real path itself. Files are
+// written through symlinks, so a link in the repository would otherwise
+// let a run modify a file anywhere the user can write.
+func checkWithinRoot(root, filename string) error {
+	real, err := filepath.EvalSymlinks(filename)
+	if err != nil {
+		return nil // Unreadable files are reported when they are opened
+	}
+	if info, err := os.Stat(real); err != nil || !info.Mode().IsRegular() {
+		return nil // Only regular files are ever written
+	}
+
+	rel, err := filepath.Rel(root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("resolves to %s, outside the repository %s", real, root)
+	}
+	return nil
+}
+
+// ModifiedFiles returns the files this crawler rewrote, with absolute paths
+func (c *Crawler) ModifiedFiles() []ModifiedFile {
+	c.modifiedMu.Lock()
+	defer c.modifiedMu.Unlock()
+	return append([]ModifiedFile(nil), c.modified...)
+}
+
+// recordSkippedDir counts dir, which was not scanned, for the summary
+func (c *Crawler) recordSkippedDir(dir string) {
+	atomic.AddInt64(&c.stats.DirsSkipped, 1)
+	c.skippedDirsMu.Lock()
+	c.skippedDirs = append(c.skippedDirs, dir)
+	c.skippedDirsMu.Unlock()
+}
+
+// SkippedDirs returns the directories this crawler could not read, and so
+// did not scan
+func (c *Crawler) SkippedDirs() []string {
+	c.skippedDirsMu.Lock()
+	defer c.skippedDirsMu.Unlock()
+	return append([]string(nil), c.skippedDirs...)
+}
+
+// UnsupportedFiles returns the text files this crawler skipped because no
+// comment style is known for them, with absolute paths
+func (c *Crawler) UnsupportedFiles() []string {
+	c.unsupportedMu.Lock()
+	defer c.unsupportedMu.Unlock()
+	return append([]string(nil), c.unsupported...)
+}
+
 var logMutex sync.Mutex
 
-func (c *Crawler) logResultSafe(filename string, result ProcessResult) {
+func (c *Crawler) logResultSafe(filename string, result licer.ProcessResult) {
+	// With --sorted nothing prints during the crawl, results are only collected
+	if c.sortResults {
+		c.resultsMu.Lock()
+		c.results = append(c.results, fileResult{filename, result})
+		c.resultsMu.Unlock()
+		return
+	}
+
 	logMutex.Lock()
 	defer logMutex.Unlock()
-	LogResult(filename, result, true)
+	c.logger.LogResult(filename, result)
+}
+
+// flushResults logs the results held back by --sorted, ordered by path, so
+// two runs over the same tree print them the same way
+func (c *Crawler) flushResults() {
+	c.resultsMu.Lock()
+	results := c.results
+	c.results = nil
+	c.resultsMu.Unlock()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].filename < results[j].filename })
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	for _, r := range results {
+		c.logger.LogResult(r.filename, r.result)
+	}
+}
+
+func (c *Crawler) logErrorSafe(format string, args ...interface{}) {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	fmt.Fprintf(os.Stderr, format, args...)
 }
 
 func (c *Crawler) printStats() {
-	fmt.Printf("\n=== Processing Summary ===\n")
-	fmt.Printf("Files processed: %d\n", c.stats.FilesProcessed)
-	fmt.Printf("Files modified:  %d\n", c.stats.FilesModified)
-	fmt.Printf("Files skipped:   %d\n", c.stats.FilesSkipped)
-	fmt.Printf("Files errored:   %d\n", c.stats.FilesErrored)
-	fmt.Printf("=========================\n")
-}
\ No newline at end of file
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	c.logger.LogStats("Processing Summary", c.stats)
+}
+
+func printStats(title string, stats *ProcessingStats) {
+	fmt.Fprintf(os.Stderr, "\n=== %s ===\n", title)
+	fmt.Fprintf(os.Stderr, "Files processed: %d\n", stats.FilesProcessed)
+	fmt.Fprintf(os.Stderr, "Files modified:  %d\n", stats.FilesModified)
+	fmt.Fprintf(os.Stderr, "Files skipped:   %d\n", stats.FilesSkipped)
+	fmt.Fprintf(os.Stderr, "Files errored:   %d\n", stats.FilesErrored)
+	if stats.DirsSkipped > 0 {
+		fmt.Fprintf(os.Stderr, "Dirs skipped:    %d (unreadable, not scanned)\n", stats.DirsSkipped)
+	}
+	if counts := stats.LicenseCounts(); len(counts) > 0 {
+		licenses := make([]string, 0, len(counts))
+		for license := range counts {
+			licenses = append(licenses, license)
+		}
+		sort.Strings(licenses)
+		fmt.Fprintf(os.Stderr, "Headers by license:\n")
+		for _, license := range licenses {
+			fmt.Fprintf(os.Stderr, "  %-14s %d\n", license+":", counts[license])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "=========================\n")
+}
+
+// printSkippedDirs lists the directories that were not scanned, so a
+// clean summary is not mistaken for full coverage
+func printSkippedDirs(dirs []string) {
+	sort.Strings(dirs)
+	fmt.Fprintf(os.Stderr, "\n=== Directories not scanned ===\n")
+	for _, dir := range dirs {
+		fmt.Fprintln(os.Stderr, dir)
+	}
+	fmt.Fprintf(os.Stderr, "Check their permissions; files below them have no header check\n")
+}
+
+// printUnsupportedFiles lists the files --strict fails on, so a missing
+// comment style can be added or the extension excluded on purpose
+func printUnsupportedFiles(files []string) {
+	sort.Strings(files)
+	fmt.Fprintf(os.Stderr, "\n=== No comment style available (--strict) ===\n")
+	for _, file := range files {
+		fmt.Fprintln(os.Stderr, file)
+	}
+	fmt.Fprintf(os.Stderr, "Skip extensions that need no header with --exclude-ext\n")
+}
+
+// processRepositories runs process on each of gitFolders in turn. A folder
+// that is not a git repository or fails to process is reported and
+// skipped. With printSummary, a combined summary follows the per-repository
+// ones. It returns the combined stats and the number of failed folders.
+func processRepositories(gitFolders []string, process func(repoRoot string) (*ProcessingStats, error), printSummary bool) (*ProcessingStats, int) {
+	total := &ProcessingStats{}
+	failed := 0
+
+	for _, folder := range gitFolders {
+		repoRoot, err := resolveRepoRoot(folder)
+		if err == nil {
+			if printSummary && !logJSON {
+				fmt.Fprintf(os.Stderr, "\n=== Repository: %s ===\n", repoRoot)
+			}
+			var stats *ProcessingStats
+			stats, err = process(repoRoot)
+			if stats != nil {
+				total.FilesProcessed += stats.FilesProcessed
+				total.FilesModified += stats.FilesModified
+				total.FilesSkipped += stats.FilesSkipped
+				total.FilesErrored += stats.FilesErrored
+				total.FilesUnsupported += stats.FilesUnsupported
+				total.DirsSkipped += stats.DirsSkipped
+				for license, n := range stats.LicenseCounts() {
+					total.addLicense(license, n)
+				}
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %s: %v\n", folder, err)
+			failed++
+		}
+	}
+
+	if printSummary {
+		newResultLogger(true, true).LogStats(fmt.Sprintf("Combined Summary (%d repositories, %d failed)", len(gitFolders), failed), total)
+	}
+
+	return total, failed
+}
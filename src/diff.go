@@ -0,0 +1,317 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/licer/licer/pkg/licer"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// maxDiffCells bounds the LCS table for the changed middle of a file; beyond
+// it the middle is shown as removed and re-added rather than aligned
+const maxDiffCells = 4 << 20
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
+)
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// diffPreview returns a ProcessOptions.Preview that writes a unified diff of
+// each change to w, with paths relative to repoRoot. Diffs from concurrent
+// workers are written whole, one at a time.
+func diffPreview(w io.Writer, repoRoot string, color bool) func(filename string, original, modified []byte) {
+	var mu sync.Mutex
+	return func(filename string, original, modified []byte) {
+		name := filename
+		if rel, err := filepath.Rel(repoRoot, filename); err == nil {
+			name = filepath.ToSlash(rel)
+		}
+		diff := unifiedDiff(name, original, modified, color)
+
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(w, diff)
+	}
+}
+
+// isTerminal reports whether f is a character device other than the null
+// device, i.e. it is not redirected to a file, pipe or /dev/null
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+// unifiedDiff returns the unified diff between a and b, or "" if they are
+// equal
+func unifiedDiff(name string, a, b []byte, color bool) string {
+	ops := diffLines(strings.SplitAfter(string(a), "\n"), strings.SplitAfter(string(b), "\n"))
+
+	paint := func(code, text string) string {
+		if !color {
+			return text
+		}
+		return code + text + ansiReset
+	}
+
+	// Position of each op in a and b, for the hunk headers
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	for k, op := range ops {
+		aPos[k+1], bPos[k+1] = aPos[k], bPos[k]
+		if op.kind != '+' {
+			aPos[k+1]++
+		}
+		if op.kind != '-' {
+			bPos[k+1]++
+		}
+	}
+
+	var out strings.Builder
+	prevEnd := 0
+	for k := 0; k < len(ops); k++ {
+		if ops[k].kind == ' ' {
+			continue
+		}
+		if out.Len() == 0 {
+			out.WriteString(paint(ansiBold, fmt.Sprintf("--- a/%s\n+++ b/%s", name, name)) + "\n")
+		}
+
+		// Extend the hunk over changes separated by little enough context
+		start := max(k-diffContext, prevEnd)
+		last := k
+		for m := k + 1; m < len(ops) && m <= last+2*diffContext+1; m++ {
+			if ops[m].kind != ' ' {
+				last = m
+			}
+		}
+		end := min(last+diffContext+1, len(ops))
+
+		header := fmt.Sprintf("@@ -%s +%s @@", hunkRange(aPos[start], aPos[end]), hunkRange(bPos[start], bPos[end]))
+		out.WriteString(paint(ansiCyan, header) + "\n")
+		for _, op := range ops[start:end] {
+			line := string(op.kind) + strings.TrimSuffix(op.line, "\n")
+			switch op.kind {
+			case '-':
+				line = paint(ansiRed, line)
+			case '+':
+				line = paint(ansiGreen, line)
+			}
+			out.WriteString(line + "\n")
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\\ No newline at end of file\n")
+			}
+		}
+
+		prevEnd = end
+		k = end - 1
+	}
+	return out.String()
+}
+
+// hunkRange formats the start,length of a hunk side covering lines
+// [from, to) counted from zero
+func hunkRange(from, to int) string {
+	if to == from {
+		return fmt.Sprintf("%d,0", from)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
+
+// diffLines aligns a and b line by line. Header changes are near the top,
+// so the common prefix and suffix are split off before the LCS.
+func diffLines(a, b []string) []diffOp {
+	// SplitAfter leaves an empty last element after a final newline
+	if len(a) > 0 && a[len(a)-1] == "" {
+		a = a[:len(a)-1]
+	}
+	if len(b) > 0 && b[len(b)-1] == "" {
+		b = b[:len(b)-1]
+	}
+
+	prefix, suffix := commonEnds(a, b)
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// commonEnds returns the number of leading and trailing lines a and b
+// share, not overlapping
+func commonEnds(a, b []string) (prefix, suffix int) {
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return prefix, suffix
+}
+
+func diffMiddle(a, b []string) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// EditPlan is the --diff --format=json output: the edit a run would make to
+// each file, for review tooling to summarize before anything is written
+type EditPlan struct {
+	Root  string     `json:"root"`
+	Files []FileEdit `json:"files"`
+}
+
+// FileEdit replaces lines StartLine to EndLine of a file, counted from one
+// and inclusive, with Inserted. When nothing is removed EndLine is
+// StartLine-1 and Inserted goes before line StartLine.
+type FileEdit struct {
+	Path      string `json:"path"` // relative to Root, with forward slashes
+	Action    string `json:"action"`
+	Reason    string `json:"reason"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Removed   string `json:"removed"`
+	Inserted  string `json:"inserted"`
+}
+
+// editPlanner collects the FileEdits of a run that writes nothing. Its
+// preview is the run's ProcessOptions.Preview; the crawler adds each
+// modified file's action with record.
+type editPlanner struct {
+	root  string
+	mu    sync.Mutex
+	edits map[string]*FileEdit // by filename
+}
+
+func newEditPlanner(root string) *editPlanner {
+	return &editPlanner{root: root, edits: make(map[string]*FileEdit)}
+}
+
+// preview computes the edit that turns original into modified: the lines
+// between their common beginning and end
+func (p *editPlanner) preview(filename string, original, modified []byte) {
+	a := strings.SplitAfter(string(original), "\n")
+	b := strings.SplitAfter(string(modified), "\n")
+	prefix, suffix := commonEnds(a, b)
+
+	edit := &FileEdit{
+		Path:      relativeSlashPath(p.root, filename),
+		StartLine: prefix + 1,
+		EndLine:   len(a) - suffix,
+		Removed:   strings.Join(a[prefix:len(a)-suffix], ""),
+		Inserted:  strings.Join(b[prefix:len(b)-suffix], ""),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.edits[filename] = edit
+}
+
+// record adds the action and reason of filename, a file the run would modify
+func (p *editPlanner) record(filename string, result licer.ProcessResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if edit, ok := p.edits[filename]; ok {
+		edit.Action = result.Action
+		edit.Reason = result.Reason
+	}
+}
+
+// Plan returns the collected edits sorted by path
+func (p *editPlanner) Plan() *EditPlan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	plan := &EditPlan{Root: p.root, Files: []FileEdit{}}
+	for _, edit := range p.edits {
+		plan.Files = append(plan.Files, *edit)
+	}
+	sort.Slice(plan.Files, func(i, j int) bool { return plan.Files[i].Path < plan.Files[j].Path })
+	return plan
+}
+
+func writeEditPlanJSON(w io.Writer, plan *EditPlan) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// GitYears looks up the year each file was first committed, for --git-dates.
+// The history is read with a single git log on the first lookup and cached,
+// so the crawl doesn't run git once per file.
+type GitYears struct {
+	repoRoot string
+	once     sync.Once
+	years    map[string]int
+}
+
+func NewGitYears(repoRoot string) *GitYears {
+	return &GitYears{repoRoot: repoRoot}
+}
+
+// FirstYear returns the year of the earliest commit touching filename, an
+// absolute path in the repository. Files without history (new or untracked)
+// and a failing git report false.
+func (g *GitYears) FirstYear(filename string) (int, bool) {
+	g.once.Do(func() {
+		years, err := loadFirstCommitYears(g.repoRoot)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: --git-dates could not read the git history: %v\n", err)
+		}
+		g.years = years
+	})
+
+	rel, err := filepath.Rel(g.repoRoot, filename)
+	if err != nil {
+		return 0, false
+	}
+	year, ok := g.years[filepath.ToSlash(rel)]
+	return year, ok
+}
+
+// loadFirstCommitYears maps every path in the history of repoRoot to the
+// year of its earliest commit. Renames are not followed, so a moved file
+// dates from its move.
+func loadFirstCommitYears(repoRoot string) (map[string]int, error) {
+	out, err := exec.Command("git", "-C", repoRoot, "log", "--format=%x01%ad", "--date=format:%Y", "--name-only", "-z").Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+
+	// Each commit is "\x01YEAR\0" followed by "\n" and NUL-terminated paths
+	years := make(map[string]int)
+	year := 0
+	for _, field := range bytes.Split(out, []byte{0}) {
+		field = bytes.TrimPrefix(field, []byte("\n"))
+		if len(field) == 0 {
+			continue
+		}
+		if field[0] == 1 {
+			year, _ = strconv.Atoi(string(field[1:]))
+			continue
+		}
+		path := string(field)
+		if first, ok := years[path]; year > 0 && (!ok || year < first) {
+			years[path] = year
+		}
+	}
+	return years, nil
+}
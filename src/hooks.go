@@ -17,14 +17,26 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/licer/licer/pkg/licer"
 )
 
-const preCommitHookScript = `#!/bin/bash
+// preCommitHookTemplate is the hook script; %s is the quoted path of the
+// licer binary that installed it
+const preCommitHookTemplate = `#!/bin/bash
 
 # Licer pre-commit hook - Automatically add license headers to new files
 
-# Get the directory where licer binary is located
-LICER_PATH="$(which licer)"
+# LICER_BIN overrides the licer binary, then the one that installed this hook
+# is used, then licer from PATH or a few common locations
+LICER_PATH="$LICER_BIN"
+INSTALLED_PATH=%s
+if [ -z "$LICER_PATH" ] && [ -x "$INSTALLED_PATH" ]; then
+    LICER_PATH="$INSTALLED_PATH"
+fi
+if [ -z "$LICER_PATH" ]; then
+    LICER_PATH="$(which licer)"
+fi
 if [ -z "$LICER_PATH" ]; then
     # Try to find licer in common locations
     REPO_ROOT="$(git rev-parse --show-toplevel)"
@@ -47,16 +59,31 @@ fi
 exit 0
 `
 
-func handleHookManagement(removeMode bool, verbose bool) {
-	repoRoot, err := os.Getwd()
+// preCommitHookScript returns the hook script, pointing it at the running
+// licer binary so the hook works even when licer is not on the PATH git
+// hooks run with
+func preCommitHookScript() string {
+	licerPath, err := os.Executable()
+	if err == nil {
+		licerPath, err = filepath.Abs(licerPath)
+	}
 	if err != nil {
-		log.Fatalf("Failed to get current directory: %v", err)
+		licerPath = ""
 	}
-	
-	// Verify it's a git repository
-	gitDir := filepath.Join(repoRoot, ".git")
-	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
-		log.Fatalf("Not a git repository: %s", repoRoot)
+	return fmt.Sprintf(preCommitHookTemplate, shellQuote(licerPath))
+}
+
+// shellQuote quotes s as a single word for bash
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// handleHookManagement installs or removes the hook in the repository at
+// gitFolder, or the current directory when gitFolder is empty
+func handleHookManagement(gitFolder string, removeMode bool, verbose bool) {
+	repoRoot, err := resolveRepoRoot(gitFolder)
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
 	
 	if removeMode {
@@ -65,7 +92,7 @@ func handleHookManagement(removeMode bool, verbose bool) {
 			log.Fatalf("Failed to uninstall hook: %v", err)
 		}
 		if verbose {
-			fmt.Println("Pre-commit hook uninstalled successfully")
+			fmt.Fprintln(os.Stderr, "Pre-commit hook uninstalled successfully")
 		}
 	} else {
 		err := installPreCommitHook(repoRoot, verbose)
@@ -73,7 +100,7 @@ func handleHookManagement(removeMode bool, verbose bool) {
 			log.Fatalf("Failed to install hook: %v", err)
 		}
 		if verbose {
-			fmt.Println("Pre-commit hook installed successfully")
+			fmt.Fprintln(os.Stderr, "Pre-commit hook installed successfully")
 		}
 	}
 }
@@ -86,15 +113,18 @@ func handlePreCommitMode() {
 		os.Exit(1)
 	}
 	
-	// Load configuration
-	config, err := LoadOrCreateConfig()
+	// Load configuration, with the repository's overrides
+	config, err := licer.LoadOrCreateConfig()
+	if err == nil {
+		config, err = repoConfigFor(config, repoRoot)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 	
 	// Get newly staged files
-	newFiles, err := getStagedNewFiles()
+	newFiles, err := getStagedNewFiles(repoRoot)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error getting staged files: %v\n", err)
 		os.Exit(1)
@@ -106,8 +136,46 @@ func handlePreCommitMode() {
 	}
 	
 	// Process each new file
+	hasErrors := processStagedFiles(repoRoot, newFiles, func(fullPath string) licer.ProcessResult {
+		return licer.ProcessFile(fullPath, config, false, false, false) // Never force in pre-commit mode
+	})
+
+	if hasErrors {
+		os.Exit(exitFileErrors)
+	}
+	os.Exit(exitOK)
+}
+
+// handleStagedMode is the pre-commit behavior on demand: it adds headers to
+// newly staged files of repoRoot and re-stages them, printing the usual
+// per-file lines and summary.
+func handleStagedMode(repoRoot string, config *licer.Config, headerTemplates map[string]string, verbose, summary bool) {
+	newFiles, err := getStagedNewFiles(repoRoot)
+	if err != nil {
+		log.Fatalf("Failed to get staged files: %v", err)
+	}
+
+	crawler := NewCrawler(config, licer.ProcessOptions{HeaderTemplates: headerTemplates}, verbose, summary, 1)
+	hasErrors := processStagedFiles(repoRoot, newFiles, func(fullPath string) licer.ProcessResult {
+		return crawler.processInRepo(repoRoot, fullPath)
+	})
+
+	if verbose || summary {
+		crawler.printStats()
+	}
+	if hasErrors {
+		os.Exit(exitFileErrors)
+	}
+}
+
+// processStagedFiles runs process on every staged file that still exists
+// and re-stages the ones it modified. It reports whether a file resolved
+// outside repoRoot or re-staging failed.
+func processStagedFiles(repoRoot string, files []string, process func(fullPath string) licer.ProcessResult) bool {
 	hasErrors := false
-	for _, filename := range newFiles {
+	var modified []string
+	root := realPath(repoRoot)
+	for _, filename := range files {
 		fullPath := filepath.Join(repoRoot, filename)
 		
 		// Check if file exists (might have been deleted after staging)
@@ -115,46 +183,103 @@ func handlePreCommitMode() {
 			continue
 		}
 		
-		result := ProcessFile(fullPath, config, false, false, false) // Never force in pre-commit mode
+		if err := checkWithinRoot(root, fullPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s %v\n", filename, err)
+			hasErrors = true
+			continue
+		}
+
+		result := process(fullPath)
 		if result.Modified {
-			// Re-stage the modified file
-			cmd := exec.Command("git", "add", filename)
-			if err := cmd.Run(); err != nil {
+			modified = append(modified, filename)
+		}
+	}
+
+	if !restageFiles(repoRoot, modified) {
+		hasErrors = true
+	}
+	return hasErrors
+}
+
+// restageArgBytes caps the paths of one git add, well below the command
+// line limits of every platform (32K characters on Windows)
+const restageArgBytes = 24 * 1024
+
+// restageFiles re-stages files with as few git add calls as the argument
+// limit allows. When a batch fails, its files are added one by one so each
+// failure is reported with its file name. It reports whether all succeeded.
+func restageFiles(repoRoot string, files []string) bool {
+	ok := true
+	for len(files) > 0 {
+		n, size := 0, 0
+		for n < len(files) && (n == 0 || size+len(files[n])+1 <= restageArgBytes) {
+			size += len(files[n]) + 1
+			n++
+		}
+		batch := files[:n]
+		files = files[n:]
+
+		if gitAdd(repoRoot, batch...) == nil {
+			continue
+		}
+		for _, filename := range batch {
+			if err := gitAdd(repoRoot, filename); err != nil {
 				fmt.Fprintf(os.Stderr, "Error re-staging %s: %v\n", filename, err)
-				hasErrors = true
+				ok = false
 			}
 		}
 	}
-	
-	if hasErrors {
-		os.Exit(1)
-	}
-	os.Exit(0)
+	return ok
+}
+
+// gitAdd stages files of repoRoot
+func gitAdd(repoRoot string, files ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", repoRoot, "add", "--"}, files...)...)
+	return cmd.Run()
 }
 
-func getStagedNewFiles() ([]string, error) {
-	cmd := exec.Command("git", "diff", "--cached", "--name-status")
+// getStagedNewFiles returns the files staged as added, and the new paths
+// of renamed or copied ones, relative to repoRoot
+func getStagedNewFiles(repoRoot string) ([]string, error) {
+	cmd := exec.Command("git", "-C", repoRoot, "diff", "--cached", "-z", "--name-status")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get staged files: %w", err)
 	}
 	
+	return parseNameStatus(output), nil
+}
+
+// parseNameStatus picks the new files from the NUL-separated output of
+// git diff -z --name-status: a status record followed by one path, or by
+// the source and destination for a rename (R) or copy (C). Paths are not
+// quoted with -z, so names with spaces or non-ASCII letters come through
+// as they are.
+func parseNameStatus(output []byte) []string {
 	var newFiles []string
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	
-	for _, line := range lines {
-		if line == "" {
+	fields := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+		if status == "" {
 			continue
 		}
 		
-		// Format: "A\tfilename" for added files
-		if strings.HasPrefix(line, "A\t") {
-			filename := strings.TrimPrefix(line, "A\t")
-			newFiles = append(newFiles, filename)
+		switch status[0] {
+		case 'A': // also AM and the like; a file deleted since is skipped later
+			if i+1 < len(fields) {
+				newFiles = append(newFiles, fields[i+1])
+			}
+			i++
+		case 'R', 'C':
+			if i+2 < len(fields) {
+				newFiles = append(newFiles, fields[i+2])
+			}
+			i += 2
+		default:
+			i++
 		}
 	}
-	
-	return newFiles, nil
+	return newFiles
 }
 
 func isHookInstalled(repoRoot string) bool {
@@ -195,7 +320,7 @@ func installPreCommitHook(repoRoot string, verbose bool) error {
 	// Backup existing hook if it exists
 	if _, err := os.Stat(hookPath); err == nil {
 		if verbose {
-			fmt.Printf("Backing up existing pre-commit hook to pre-commit.backup\n")
+			fmt.Fprintf(os.Stderr, "Backing up existing pre-commit hook to pre-commit.backup\n")
 		}
 		if err := os.Rename(hookPath, backupPath); err != nil {
 			return fmt.Errorf("failed to backup existing hook: %w", err)
@@ -203,12 +328,12 @@ func installPreCommitHook(repoRoot string, verbose bool) error {
 	}
 	
 	// Write new hook
-	if err := os.WriteFile(hookPath, []byte(preCommitHookScript), 0755); err != nil {
+	if err := os.WriteFile(hookPath, []byte(preCommitHookScript()), 0755); err != nil {
 		return fmt.Errorf("failed to write hook script: %w", err)
 	}
 	
 	if verbose {
-		fmt.Printf("Pre-commit hook installed at %s\n", hookPath)
+		fmt.Fprintf(os.Stderr, "Pre-commit hook installed at %s\n", hookPath)
 	}
 	
 	return nil
@@ -221,7 +346,7 @@ func uninstallPreCommitHook(repoRoot string, verbose bool) error {
 	// Check if our hook is installed
 	if !isHookInstalled(repoRoot) {
 		if verbose {
-			fmt.Println("No licer pre-commit hook found to uninstall")
+			fmt.Fprintln(os.Stderr, "No licer pre-commit hook found to uninstall")
 		}
 		return nil
 	}
@@ -234,7 +359,7 @@ func uninstallPreCommitHook(repoRoot string, verbose bool) error {
 	// Restore backup if it exists
 	if _, err := os.Stat(backupPath); err == nil {
 		if verbose {
-			fmt.Printf("Restoring backed up pre-commit hook\n")
+			fmt.Fprintf(os.Stderr, "Restoring backed up pre-commit hook\n")
 		}
 		if err := os.Rename(backupPath, hookPath); err != nil {
 			return fmt.Errorf("failed to restore backup hook: %w", err)
@@ -242,7 +367,7 @@ func uninstallPreCommitHook(repoRoot string, verbose bool) error {
 	}
 	
 	if verbose {
-		fmt.Printf("Pre-commit hook uninstalled\n")
+		fmt.Fprintf(os.Stderr, "Pre-commit hook uninstalled\n")
 	}
 	
 	return nil
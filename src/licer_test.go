@@ -10,14 +10,25 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/licer/licer/pkg/licer"
 )
 
-func testConfig() *Config {
-	return &Config{
+func testConfig() *licer.Config {
+	return &licer.Config{
 		FullName:     "Test User",
 		DefaultRole:  "Staff",
 		DeptOrLab:    "Test Lab",
@@ -25,230 +36,1842 @@ func testConfig() *Config {
 	}
 }
 
-func writeTempFile(t *testing.T, name, content string) string {
+// newTestRepo returns a directory with an empty .git and files, by path
+// relative to it
+func newTestRepo(t *testing.T, files map[string]string) string {
 	t.Helper()
-	path := filepath.Join(t.TempDir(), name)
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
-		t.Fatalf("failed to write temp file: %v", err)
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
 	}
-	return path
+	return root
 }
 
-func TestFormatHeaderLineComments(t *testing.T) {
-	style := commentStyles[".go"]
-	out := FormatHeader("Copyright 2025 Test\n\nSPDX-License-Identifier: MIT", style)
-
-	for _, line := range strings.Split(out, "\n") {
-		if !strings.HasPrefix(line, "//") {
-			t.Errorf("line does not start with //: %q", line)
+// gitRunner returns a function that runs git in root as a test user and
+// returns its output. The test is skipped when git is not installed.
+func gitRunner(t *testing.T, root string) func(args ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	return func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", root, "-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
 		}
+		return string(out)
 	}
 }
 
-func TestFormatHeaderCSSBlock(t *testing.T) {
-	style := commentStyles[".css"]
-	out := FormatHeader("Copyright 2025 Test", style)
+func TestHookInstallDetection(t *testing.T) {
+	repoRoot := t.TempDir()
+	hooksDir := filepath.Join(repoRoot, ".git", "hooks")
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if isHookInstalled(repoRoot) {
+		t.Error("hook reported installed before installation")
+	}
+
+	if err := installPreCommitHook(repoRoot, false); err != nil {
+		t.Fatalf("failed to install hook: %v", err)
+	}
+	if !isHookInstalled(repoRoot) {
+		t.Error("hook not detected after installation")
+	}
 
-	lines := strings.Split(out, "\n")
-	if lines[0] != "/*" || lines[len(lines)-1] != " */" {
-		t.Errorf("CSS header is not a /* ... */ block:\n%s", out)
+	if err := uninstallPreCommitHook(repoRoot, false); err != nil {
+		t.Fatalf("failed to uninstall hook: %v", err)
+	}
+	if isHookInstalled(repoRoot) {
+		t.Error("hook still detected after uninstallation")
 	}
 }
 
-func TestFormatHeaderHTMLIsValid(t *testing.T) {
-	style := commentStyles[".html"]
-	out := FormatHeader("Copyright 2025 Test\n\nSPDX-License-Identifier: MIT", style)
+func TestHookScriptPointsAtInstallingBinary(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoRoot, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := installPreCommitHook(repoRoot, false); err != nil {
+		t.Fatalf("failed to install hook: %v", err)
+	}
+	hookPath := filepath.Join(repoRoot, ".git", "hooks", "pre-commit")
+	script, err := os.ReadFile(hookPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skipf("no executable path: %v", err)
+	}
+	if !filepath.IsAbs(exe) || !strings.Contains(string(script), "INSTALLED_PATH="+shellQuote(exe)) {
+		t.Errorf("hook does not name the installing binary %s:\n%s", exe, script)
+	}
+
+	// LICER_BIN takes precedence over the installed path
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	marker := filepath.Join(t.TempDir(), "ran")
+	fake := filepath.Join(t.TempDir(), "licer")
+	if err := os.WriteFile(fake, []byte("#!/bin/sh\necho \"$@\" > "+shellQuote(marker)+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(bash, hookPath)
+	cmd.Dir = repoRoot
+	cmd.Env = append(os.Environ(), "LICER_BIN="+fake)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("hook failed: %v\n%s", err, out)
+	}
+	if args, err := os.ReadFile(marker); err != nil || !strings.Contains(string(args), "--pre-commit") {
+		t.Errorf("LICER_BIN was not run: %v %q", err, args)
+	}
+}
 
-	for _, line := range strings.Split(out, "\n") {
-		if !strings.HasPrefix(line, "<!--") || !strings.HasSuffix(line, "-->") {
-			t.Errorf("HTML header line is not a closed comment: %q", line)
+func TestShellQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"/usr/local/bin/licer": `'/usr/local/bin/licer'`,
+		"/home/o'brien/licer":  `'/home/o'\''brien/licer'`,
+		"":                     `''`,
+	} {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
 		}
 	}
 }
 
-func TestFormatHeaderOCamlIsValid(t *testing.T) {
-	style := commentStyles[".ml"]
-	out := FormatHeader("Copyright 2025 Test", style)
+func TestHookInstallsIntoGitFolder(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoRoot, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The test runs from the source directory, which is not a repository
+	// root, so this only succeeds if the given path is used instead of cwd
+	handleHookManagement(repoRoot, false, false)
+	if !isHookInstalled(repoRoot) {
+		t.Fatal("hook not installed into the repository given by path")
+	}
+	cwd, _ := os.Getwd()
+	if isHookInstalled(cwd) {
+		t.Error("hook installed into the current directory")
+	}
+
+	handleHookManagement(repoRoot, true, false)
+	if isHookInstalled(repoRoot) {
+		t.Error("hook not removed from the repository given by path")
+	}
+
+	if _, err := resolveRepoRoot(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory that is not a git repository")
+	}
+}
+
+func TestRepoRootIsFoundFromSubdirectory(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(root, "src", "pkg", "deep")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	if err := os.Chdir(nested); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := resolveRepoRoot(""); err != nil || got != root {
+		t.Errorf("from %s: got %q (%v), want %q", nested, got, err, root)
+	}
+
+	// A path given with --git-folder must be the root itself
+	if _, err := resolveRepoRoot(nested); err == nil {
+		t.Error("--git-folder of a subdirectory accepted")
+	}
+
+	// A worktree or submodule has a .git file instead of a directory
+	sub := filepath.Join(root, "vendor", "lib")
+	if err := os.MkdirAll(filepath.Join(sub, "inner"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, ".git"), []byte("gitdir: ../../.git/modules/lib\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(sub, "inner")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := resolveRepoRoot(""); err != nil || got != sub {
+		t.Errorf("in a submodule: got %q (%v), want %q", got, err, sub)
+	}
+}
 
-	for _, line := range strings.Split(out, "\n") {
-		if !strings.HasPrefix(line, "(*") || !strings.HasSuffix(line, "*)") {
-			t.Errorf("OCaml header line is not a closed comment: %q", line)
+func TestCrawlerSingleJobProcessesNestedTree(t *testing.T) {
+	repoRoot := t.TempDir()
+	nested := filepath.Join(repoRoot, "a", "b", "c")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{repoRoot, filepath.Join(repoRoot, "a"), nested} {
+		if err := os.WriteFile(filepath.Join(dir, "main.py"), []byte("print('hi')\n"), 0644); err != nil {
+			t.Fatal(err)
 		}
 	}
+
+	crawler := NewCrawler(testConfig(), licer.ProcessOptions{}, false, false, 1)
+	if err := crawler.ProcessRepository(repoRoot); err != nil {
+		t.Fatalf("ProcessRepository failed: %v", err)
+	}
+	if crawler.stats.FilesModified != 3 {
+		t.Errorf("expected 3 files modified with --jobs 1, got %d", crawler.stats.FilesModified)
+	}
 }
 
-func TestLicenseFilesAreExcluded(t *testing.T) {
-	for _, name := range []string{"LICENSE", "LICENSE.orig", "COPYING", "NOTICE", "license"} {
-		path := writeTempFile(t, name, "Apache License\nVersion 2.0, January 2004\n")
-		if ShouldProcessFile(path) {
-			t.Errorf("%s should be excluded from processing", name)
+func TestCrawlerJobsBoundsConcurrentFiles(t *testing.T) {
+	const jobs = 2
+	repoRoot := t.TempDir()
+	for _, dir := range []string{"", "a", "a/b", "c"} {
+		if err := os.MkdirAll(filepath.Join(repoRoot, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 4; i++ {
+			name := filepath.Join(repoRoot, dir, fmt.Sprintf("f%d.py", i))
+			if err := os.WriteFile(name, []byte("print('hi')\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Preview runs while the file holds its worker slot
+	var inFlight, peak int64
+	opts := licer.ProcessOptions{Preview: func(string, []byte, []byte) {
+		n := atomic.AddInt64(&inFlight, 1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
 		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt64(&inFlight, -1)
+	}}
+	crawler := NewCrawler(testConfig(), opts, false, false, jobs)
+	if err := crawler.ProcessRepository(repoRoot); err != nil {
+		t.Fatalf("ProcessRepository failed: %v", err)
+	}
+	if crawler.stats.FilesModified != 16 {
+		t.Errorf("expected 16 files modified, got %d", crawler.stats.FilesModified)
+	}
+	if peak > jobs {
+		t.Errorf("%d files processed at once with --jobs %d", peak, jobs)
+	}
+	if peak < jobs {
+		t.Errorf("files were not processed in parallel with --jobs %d (peak %d)", jobs, peak)
 	}
 }
 
-func TestAddHeaderIsIdempotent(t *testing.T) {
-	path := writeTempFile(t, "example.py", "def main():\n    pass\n")
-	config := testConfig()
+func TestStdinModeAddsHeaderInMemory(t *testing.T) {
+	source := "package main\n\nfunc main() {}\n"
 
-	result := ProcessFile(path, config, false, false, false)
+	output, result := processStdin([]byte(source), ".go", testConfig(), licer.ProcessOptions{})
 	if result.Action != "ADD" || !result.Modified {
 		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
 	}
+	if !strings.HasPrefix(string(output), "// Copyright") {
+		t.Errorf("expected // header at top of output:\n%s", output)
+	}
+	if !strings.HasSuffix(string(output), source) {
+		t.Errorf("original content not preserved:\n%s", output)
+	}
+
+	// Already licensed input is passed through unchanged
+	again, result := processStdin(output, "go", testConfig(), licer.ProcessOptions{})
+	if result.Modified || string(again) != string(output) {
+		t.Errorf("expected licensed input to pass through unchanged, got %s (%s)", result.Action, result.Reason)
+	}
+}
+
+func TestCoverageReport(t *testing.T) {
+	root := t.TempDir()
+	config := testConfig()
+	files := map[string]string{
+		"ours.py":     "def main():\n    pass\n",
+		"bare.py":     "print(1)\n",
+		"vendor.go":   "// Copyright (c) 2020 Other Corp\n\npackage vendor\n",
+		"foreign.go":  "// Copyright 2021 Someone Else\n// SPDX-License-Identifier: MIT\n\npackage foreign\n",
+		"README.md":   "# Readme\n",
+		".git/config": "[core]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	licer.ProcessFile(filepath.Join(root, "ours.py"), config, false, false, false)
+	before, _ := os.ReadFile(filepath.Join(root, "bare.py"))
+
+	report, err := BuildCoverageReport(root, config)
+	if err != nil {
+		t.Fatalf("BuildCoverageReport failed: %v", err)
+	}
 
-	result = ProcessFile(path, config, false, false, false)
-	if result.Action != "SKIP" || result.Modified {
-		t.Fatalf("second run should SKIP, got %s (%s)", result.Action, result.Reason)
+	want := CoverageCounts{Total: 4, OurHeader: 1, ThirdParty: 2, NoHeader: 1}
+	if report.Totals != want {
+		t.Errorf("totals = %+v, want %+v", report.Totals, want)
+	}
+	if py := report.Extensions[".py"]; py == nil || py.Total != 2 || py.OurHeader != 1 {
+		t.Errorf("unexpected .py counts: %+v", py)
+	}
+	if report.Licenses["Apache-2.0"] != 1 || report.Licenses["MIT"] != 1 {
+		t.Errorf("unexpected license counts: %v", report.Licenses)
 	}
 
-	content, _ := os.ReadFile(path)
-	if !strings.Contains(string(content), "SPDX-License-Identifier: Apache-2.0") {
-		t.Error("header missing SPDX identifier")
+	after, _ := os.ReadFile(filepath.Join(root, "bare.py"))
+	if string(before) != string(after) {
+		t.Error("report modified a file")
 	}
-	if !strings.Contains(string(content), "def main():") {
-		t.Error("original code was lost")
+
+	var out strings.Builder
+	if err := writeReportJSON(&out, report); err != nil || !strings.Contains(out.String(), `"our_header": 1`) {
+		t.Errorf("unexpected JSON report (%v):\n%s", err, out.String())
 	}
 }
 
-func TestForceReplaceIsStable(t *testing.T) {
-	path := writeTempFile(t, "example.py", "def main():\n    pass\n")
-	config := testConfig()
+func TestSinceProcessesOnlyChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	git := gitRunner(t, root)
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("old.py", "print('old')\n")
+	write("gone.py", "print('gone')\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+	git("tag", "base")
+
+	write("my file.py", "print('new')\n")
+	write("old.py", "print('changed')\n")
+	os.Remove(filepath.Join(root, "gone.py"))
+	git("add", "-A")
+
+	files, err := getChangedFilesSince(root, "base")
+	if err != nil {
+		t.Fatalf("getChangedFilesSince failed: %v", err)
+	}
+	if strings.Join(files, "|") != "my file.py|old.py" {
+		t.Fatalf("unexpected changed files: %q", files)
+	}
+
+	untouched := "print('untouched')\n"
+	write("untouched.py", untouched)
+	crawler := NewCrawler(testConfig(), licer.ProcessOptions{}, false, false, 2)
+	if err := crawler.ProcessFiles(root, append(files, "gone.py")); err != nil {
+		t.Fatalf("ProcessFiles failed: %v", err)
+	}
+
+	for _, name := range files {
+		content, _ := os.ReadFile(filepath.Join(root, name))
+		if !strings.Contains(string(content), "SPDX-License-Identifier") {
+			t.Errorf("%s did not get a header", name)
+		}
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "untouched.py")); string(content) != untouched {
+		t.Error("file outside the changed set was modified")
+	}
+	if crawler.stats.FilesProcessed != 2 {
+		t.Errorf("expected 2 files processed, got %d", crawler.stats.FilesProcessed)
+	}
+}
+
+func TestHelpListsAllFlags(t *testing.T) {
+	var out bytes.Buffer
+	printUsage(&out)
+	help := out.String()
+
+	documented := []string{"git-folder", "force", "remove", "hook", "pre-commit", "verbose", "help"}
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		documented = append(documented, f.Name)
+	})
+	for _, name := range documented {
+		if !strings.Contains(help, "-"+name+" ") && !strings.Contains(help, "-"+name+"\n") {
+			t.Errorf("--help output does not list -%s", name)
+		}
+	}
+}
+
+func TestListFileTypesHonorsOverrides(t *testing.T) {
+	licer.SetExtensionOverrides([]string{"sql"}, []string{".md"})
+	defer licer.SetExtensionOverrides(nil, nil)
+
+	list := ListFileTypes()
+	supported := make(map[string]FileType)
+	for _, ft := range list.Supported {
+		supported[ft.Extension] = ft
+	}
+	excluded := strings.Join(list.Excluded, " ") + " "
+
+	if ft, ok := supported[".go"]; !ok || ft.Line != "//" || ft.BlockStart != "/*" {
+		t.Errorf("unexpected .go entry: %+v (listed=%v)", ft, ok)
+	}
+	if _, ok := supported[".sql"]; ok || !strings.Contains(excluded, ".sql ") {
+		t.Error(".sql should move to the excluded list with --exclude-ext")
+	}
+	if strings.Contains(excluded, ".md ") {
+		t.Error(".md should not be listed as excluded with --include-ext")
+	}
+	if !strings.Contains(excluded, ".png ") {
+		t.Error("default exclusions missing")
+	}
+	if len(list.ExcludedNames) == 0 || list.ExcludedNames[0] != "AUTHORS" {
+		t.Errorf("unexpected excluded names: %v", list.ExcludedNames)
+	}
+}
+
+func TestStagedModeLicensesAndRestagesNewFiles(t *testing.T) {
+	root := t.TempDir()
+	git := gitRunner(t, root)
+
+	git("init", "-q")
+	os.WriteFile(filepath.Join(root, "old.py"), []byte("print('old')\n"), 0644)
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+
+	os.WriteFile(filepath.Join(root, "old.py"), []byte("print('changed')\n"), 0644)
+	os.WriteFile(filepath.Join(root, "new file.py"), []byte("print('new')\n"), 0644)
+	git("add", "-A")
+
+	files, err := getStagedNewFiles(root)
+	if err != nil || len(files) != 1 || files[0] != "new file.py" {
+		t.Fatalf("unexpected staged files %q (%v)", files, err)
+	}
 
-	ProcessFile(path, config, false, false, false)
-	ProcessFile(path, config, true, false, false)
-	first, _ := os.ReadFile(path)
-	ProcessFile(path, config, true, false, false)
-	second, _ := os.ReadFile(path)
+	crawler := NewCrawler(testConfig(), licer.ProcessOptions{}, false, false, 1)
+	if hasErrors := processStagedFiles(root, files, func(path string) licer.ProcessResult { return crawler.processInRepo(root, path) }); hasErrors {
+		t.Fatal("re-staging failed")
+	}
 
-	if string(first) != string(second) {
-		t.Errorf("repeated --force runs changed the file:\n--- first ---\n%s\n--- second ---\n%s", first, second)
+	if staged := git("show", ":new file.py"); !strings.Contains(staged, "SPDX-License-Identifier") {
+		t.Errorf("licensed file was not re-staged:\n%s", staged)
+	}
+	if staged := git("show", ":old.py"); strings.Contains(staged, "SPDX") {
+		t.Error("modified (not new) file was licensed")
 	}
-	if !strings.Contains(string(second), "def main():") {
-		t.Error("original code was lost during force replace")
+	if crawler.stats.FilesModified != 1 {
+		t.Errorf("expected 1 modified file in the summary, got %d", crawler.stats.FilesModified)
 	}
 }
 
-func TestShebangIsPreserved(t *testing.T) {
-	path := writeTempFile(t, "deploy.sh", "#!/bin/bash\necho hello\n")
+func TestStagedFilesAreRestagedInBatches(t *testing.T) {
+	root := t.TempDir()
+	git := gitRunner(t, root)
+
+	// Long names, so the paths exceed one batch
+	git("init", "-q")
+	dir := strings.Repeat("nested_directory_", 5)
+	if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for i := 0; i < 400; i++ {
+		name := fmt.Sprintf("%s/module_with_a_rather_long_name_%03d.py", dir, i)
+		if err := os.WriteFile(filepath.Join(root, name), []byte("x = 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if size := len(strings.Join(names, " ")); size <= restageArgBytes {
+		t.Fatalf("test paths (%d bytes) fit in one batch", size)
+	}
+	git("add", ".")
+
+	files, err := getStagedNewFiles(root)
+	if err != nil || len(files) != len(names) {
+		t.Fatalf("expected %d staged files, got %d (%v)", len(names), len(files), err)
+	}
 	config := testConfig()
+	if hasErrors := processStagedFiles(root, files, func(path string) licer.ProcessResult { return licer.ProcessFile(path, config, false, false, false) }); hasErrors {
+		t.Fatal("re-staging failed")
+	}
+	if unstaged := git("diff", "--name-only"); unstaged != "" {
+		t.Errorf("licensed files left unstaged:\n%s", unstaged)
+	}
+	if staged := git("show", ":"+names[len(names)-1]); !strings.Contains(staged, "SPDX-License-Identifier") {
+		t.Errorf("last batch not re-staged:\n%s", staged)
+	}
+
+	// A failing batch is retried file by file, so the others are still
+	// staged and only the failing path is reported
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("ignored.py\n"), 0644)
+	os.WriteFile(filepath.Join(root, "ignored.py"), []byte("x = 1\n"), 0644)
+	os.WriteFile(filepath.Join(root, names[0]), []byte("x = 2\n"), 0644)
+	if restageFiles(root, []string{names[0], "ignored.py"}) {
+		t.Error("re-staging an ignored file reported success")
+	}
+	if staged := git("show", ":"+names[0]); staged != "x = 2\n" {
+		t.Errorf("file of the failed batch not re-staged on its own:\n%s", staged)
+	}
+}
+
+func TestGetStagedNewFilesParsesRenamesAndSpaces(t *testing.T) {
+	root := t.TempDir()
+	git := gitRunner(t, root)
+
+	git("init", "-q")
+	os.WriteFile(filepath.Join(root, "old name.py"), []byte("print('a longer body so the rename is detected')\n"), 0644)
+	os.WriteFile(filepath.Join(root, "kept.py"), []byte("print('kept')\n"), 0644)
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+
+	git("mv", "old name.py", "new name.py")
+	os.WriteFile(filepath.Join(root, "kept.py"), []byte("print('changed')\n"), 0644)
+	os.WriteFile(filepath.Join(root, "tab\tand space.py"), []byte("print('new')\n"), 0644)
+	os.WriteFile(filepath.Join(root, "héllo.py"), []byte("print('new')\n"), 0644)
+	git("add", "-A")
+
+	files, err := getStagedNewFiles(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	want := []string{"héllo.py", "new name.py", "tab\tand space.py"}
+	if strings.Join(files, "|") != strings.Join(want, "|") {
+		t.Errorf("got staged files %q, want %q", files, want)
+	}
 
-	result := ProcessFile(path, config, false, false, false)
-	if !result.Modified {
-		t.Fatalf("expected file to be modified, got %s (%s)", result.Action, result.Reason)
+	// Status codes with more than one letter, and modified or deleted files
+	output := "AM\x00added.py\x00M\x00changed.py\x00D\x00gone.py\x00C75\x00src.py\x00copy.py\x00R100\x00a b.py\x00c d.py\x00"
+	if got := parseNameStatus([]byte(output)); strings.Join(got, "|") != "added.py|copy.py|c d.py" {
+		t.Errorf("parseNameStatus = %q", got)
 	}
+}
 
-	content, _ := os.ReadFile(path)
-	lines := strings.Split(string(content), "\n")
-	if lines[0] != "#!/bin/bash" {
-		t.Errorf("shebang not preserved as first line, got %q", lines[0])
+func TestProcessRepositoriesContinuesPastFailures(t *testing.T) {
+	var folders []string
+	for i := 0; i < 2; i++ {
+		root := newTestRepo(t, map[string]string{"main.py": "print('hi')\n"})
+		folders = append(folders, root)
+		if i == 0 {
+			// Not a git repository; must not stop the second repo
+			folders = append(folders, t.TempDir())
+		}
 	}
-	if !strings.Contains(string(content), "echo hello") {
-		t.Error("original code was lost")
+
+	var processed []string
+	process := func(repoRoot string) (*ProcessingStats, error) {
+		processed = append(processed, repoRoot)
+		crawler := NewCrawler(testConfig(), licer.ProcessOptions{}, false, false, 1)
+		return crawler.stats, crawler.ProcessRepository(repoRoot)
 	}
+	total, failed := processRepositories(folders, process, false)
 
-	// Force replace must also keep the shebang
-	ProcessFile(path, config, true, false, false)
-	content, _ = os.ReadFile(path)
-	if !strings.HasPrefix(string(content), "#!/bin/bash") {
-		t.Error("shebang lost after force replace")
+	if failed != 1 {
+		t.Errorf("expected 1 failed folder, got %d", failed)
+	}
+	if len(processed) != 2 {
+		t.Fatalf("expected 2 repositories processed, got %q", processed)
+	}
+	if total.FilesModified < 2 {
+		t.Errorf("expected combined stats to cover both repos, got %+v", total)
+	}
+	for _, root := range processed {
+		content, err := os.ReadFile(filepath.Join(root, "main.py"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), "SPDX-License-Identifier") {
+			t.Errorf("%s/main.py did not get a header:\n%s", root, content)
+		}
 	}
 }
 
-func TestThirdPartyCopyrightIsProtected(t *testing.T) {
-	source := "// Copyright (c) 2020 Other Corp\n\nuse std::io;\n\nfn main() {}\n"
-	path := writeTempFile(t, "lib.rs", source)
+func TestPreviewHeader(t *testing.T) {
 	config := testConfig()
+	dir := t.TempDir()
+
+	ours := filepath.Join(dir, "ours.py")
+	header := licer.FormatHeader(licer.GenerateHeaderForFile(config, ours), licer.CommentStyle{Line: "#"})
+	if err := os.WriteFile(ours, []byte("#!/usr/bin/env python3\n"+header+"\n\nprint('hi')\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	theirs := filepath.Join(dir, "theirs.go")
+	if err := os.WriteFile(theirs, []byte("// Copyright 2020 Example Corp\n// SPDX-License-Identifier: MIT\n\npackage x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	none := filepath.Join(dir, "none.go")
+	if err := os.WriteFile(none, []byte("package x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file    string
+		class   string
+		license string
+		start   int
+		lines   int
+	}{
+		{ours, headerOurs, licer.GetLicenseType(config), 2, len(strings.Split(header, "\n"))},
+		{theirs, headerThirdParty, "MIT", 1, 2},
+		{none, headerNone, "", 0, 0},
+	}
+	for _, tt := range tests {
+		preview, err := PreviewHeader(tt.file, config)
+		if err != nil {
+			t.Fatalf("PreviewHeader(%s) failed: %v", tt.file, err)
+		}
+		if preview.Classification != tt.class || preview.LicenseID != tt.license {
+			t.Errorf("%s: got %s/%q, want %s/%q", filepath.Base(tt.file), preview.Classification, preview.LicenseID, tt.class, tt.license)
+		}
+		if preview.StartLine != tt.start || len(preview.Lines) != tt.lines {
+			t.Errorf("%s: got start %d with %d lines, want start %d with %d lines: %q",
+				filepath.Base(tt.file), preview.StartLine, len(preview.Lines), tt.start, tt.lines, preview.Lines)
+		}
+	}
+}
+
+func TestUndoRestoresOnlyUntouchedFiles(t *testing.T) {
+	root := t.TempDir()
+	git := gitRunner(t, root)
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	git("init", "-q")
+	write("clean.py", "print('clean')\n")
+	write("edited.py", "print('edited')\n")
+	write("dirty.py", "print('dirty')\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+	write("dirty.py", "print('unstaged work')\n")
+
+	crawler := NewCrawler(testConfig(), licer.ProcessOptions{}, false, false, 2)
+	if err := crawler.ProcessFiles(root, []string{"clean.py", "edited.py", "dirty.py"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeRunManifest(root, crawler.ModifiedFiles()); err != nil {
+		t.Fatalf("writeRunManifest failed: %v", err)
+	}
 
-	result := ProcessFile(path, config, false, false, false)
-	if result.Action != "SKIP" || result.Modified {
-		t.Fatalf("third-party copyright should be skipped without --force, got %s (%s)", result.Action, result.Reason)
+	manifest, err := readRunManifest(root)
+	if err != nil {
+		t.Fatalf("readRunManifest failed: %v", err)
+	}
+	var paths []string
+	for _, file := range manifest.Files {
+		paths = append(paths, file.Path)
+		if file.WrittenSHA256 != licer.ContentSum([]byte(read(file.Path))) {
+			t.Errorf("%s: written checksum does not match the file", file.Path)
+		}
+	}
+	if strings.Join(paths, "|") != "clean.py|dirty.py|edited.py" {
+		t.Fatalf("unexpected manifest files: %q", paths)
+	}
+
+	write("edited.py", read("edited.py")+"print('more')\n")
+	result, err := UndoLastRun(root)
+	if err != nil {
+		t.Fatalf("UndoLastRun failed: %v", err)
 	}
 
-	// With --force the header is replaced but code must survive
-	result = ProcessFile(path, config, true, false, false)
-	if !result.Modified {
-		t.Fatalf("expected --force to replace third-party header, got %s (%s)", result.Action, result.Reason)
+	if strings.Join(result.Restored, "|") != "clean.py" {
+		t.Errorf("expected only clean.py restored, got %q (kept %v)", result.Restored, result.Kept)
+	}
+	if read("clean.py") != "print('clean')\n" {
+		t.Errorf("clean.py not restored:\n%s", read("clean.py"))
+	}
+	if !strings.Contains(read("edited.py"), "print('more')") || !strings.Contains(read("dirty.py"), "unstaged work") {
+		t.Error("undo clobbered changes it should have kept")
 	}
 
-	content, _ := os.ReadFile(path)
-	if strings.Contains(string(content), "Other Corp") {
-		t.Error("third-party copyright not replaced under --force")
+	manifest, err = readRunManifest(root)
+	if err != nil {
+		t.Fatalf("kept files should stay in the manifest: %v", err)
 	}
-	if !strings.Contains(string(content), "use std::io;") || !strings.Contains(string(content), "fn main() {}") {
-		t.Errorf("code lines were lost during third-party replacement:\n%s", content)
+	if len(manifest.Files) != 2 {
+		t.Errorf("expected 2 kept files in the manifest, got %+v", manifest.Files)
 	}
 }
 
-func TestCodeStartingWithCIsNotAComment(t *testing.T) {
-	if isCommentLine("Config = load()") {
-		t.Error("code starting with 'C' misdetected as comment")
+func TestUndoAfterNoOpRunRevertsTheModifyingRun(t *testing.T) {
+	source := "print('hi')\n"
+	root := newTestRepo(t, map[string]string{"main.py": source})
+	git := gitRunner(t, root)
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+
+	if code, out := runLicer(t, "--git-folder", root); code != exitOK {
+		t.Fatalf("first run: exit code %d, want %d\n%s", code, exitOK, out)
 	}
-	if isCommentLine(`"""Module docstring."""`) {
-		t.Error("Python docstring misdetected as comment")
+	// A run with nothing left to change, like a pre-commit hook after the
+	// first one, must not replace the record --undo reverts
+	if code, out := runLicer(t, "--git-folder", root); code != exitOK {
+		t.Fatalf("second run: exit code %d, want %d\n%s", code, exitOK, out)
 	}
-	if !isCommentLine("C Fortran comment") {
-		t.Error("Fortran comment not detected")
+	if code, out := runLicer(t, "--undo", "--git-folder", root); code != exitOK {
+		t.Fatalf("--undo: exit code %d, want %d\n%s", code, exitOK, out)
 	}
-	if !isCommentLine("# shell comment") || !isCommentLine("// go comment") {
-		t.Error("standard comments not detected")
+	if content, _ := os.ReadFile(filepath.Join(root, "main.py")); string(content) != source {
+		t.Errorf("--undo did not revert the modifying run:\n%s", content)
 	}
 }
 
-func TestRemoveHeaderWithOwnershipMatch(t *testing.T) {
-	path := writeTempFile(t, "example.py", "def main():\n    pass\n")
-	config := testConfig()
+func TestGitDatesUseFirstCommitYear(t *testing.T) {
+	root := t.TempDir()
+	run := gitRunner(t, root)
+	git := func(date string, args ...string) {
+		t.Helper()
+		t.Setenv("GIT_AUTHOR_DATE", date)
+		t.Setenv("GIT_COMMITTER_DATE", date)
+		run(args...)
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("2019-06-01T12:00:00", "init", "-q")
+	write("old.py", "print('old')\n")
+	git("2019-06-01T12:00:00", "add", ".")
+	git("2019-06-01T12:00:00", "commit", "-q", "-m", "first")
+	write("old.py", "print('old, changed')\n")
+	git("2022-06-01T12:00:00", "commit", "-q", "-am", "second")
+	write("new.py", "print('new')\n")
+
+	years := NewGitYears(root)
+	if year, ok := years.FirstYear(filepath.Join(root, "old.py")); !ok || year != 2019 {
+		t.Errorf("expected old.py first committed in 2019, got %d (%v)", year, ok)
+	}
+	if _, ok := years.FirstYear(filepath.Join(root, "new.py")); ok {
+		t.Error("untracked new.py should have no first commit year")
+	}
 
-	ProcessFile(path, config, false, false, false)
-	result := ProcessFile(path, config, false, true, false)
-	if result.Action != "REMOVE" || !result.Modified {
-		t.Fatalf("expected REMOVE, got %s (%s)", result.Action, result.Reason)
+	opts := licer.ProcessOptions{FirstYear: years.FirstYear}
+	now := time.Now().Year()
+	for name, want := range map[string]string{
+		"old.py": fmt.Sprintf("Copyright 2019-%d ", now),
+		"new.py": fmt.Sprintf("Copyright %d ", now),
+	} {
+		filename := filepath.Join(root, name)
+		if result := licer.ProcessFileWithOptions(filename, testConfig(), opts); result.Action != "ADD" {
+			t.Fatalf("%s: expected ADD, got %s (%s)", name, result.Action, result.Reason)
+		}
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(content), "# "+want) {
+			t.Errorf("%s: expected header starting %q, got:\n%s", name, want, content)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 12; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
 	}
+	a := strings.Join(lines, "\n") + "\n"
+	lines[1] = "changed 2"
+	lines = append(lines[:10], "added", "line 11", "line 12")
+	b := strings.Join(lines, "\n") + "\n"
 
-	content, _ := os.ReadFile(path)
-	if strings.Contains(string(content), "SPDX-License-Identifier") {
-		t.Error("header not removed")
+	want := `--- a/f.txt
++++ b/f.txt
+@@ -1,5 +1,5 @@
+ line 1
+-line 2
++changed 2
+ line 3
+ line 4
+ line 5
+@@ -8,5 +8,6 @@
+ line 8
+ line 9
+ line 10
++added
+ line 11
+ line 12
+`
+	if got := unifiedDiff("f.txt", []byte(a), []byte(b), false); got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
 	}
-	if !strings.Contains(string(content), "def main():") {
-		t.Error("original code was lost during removal")
+	if got := unifiedDiff("f.txt", []byte(a), []byte(a), false); got != "" {
+		t.Errorf("equal content should give no diff, got:\n%s", got)
 	}
 }
 
-func TestRemoveHeaderOwnershipMismatch(t *testing.T) {
-	source := "# Copyright (c) 2025 Someone Else\n#\n# SPDX-License-Identifier: MIT\n\ndef main():\n    pass\n"
-	path := writeTempFile(t, "example.py", source)
+func TestDiffPreviewDoesNotWrite(t *testing.T) {
+	root := t.TempDir()
+	filename := filepath.Join(root, "main.py")
+	if err := os.WriteFile(filename, []byte("print('hi')\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
 
-	result := ProcessFile(path, testConfig(), false, true, false)
-	if result.Action != "SKIP" || result.Modified {
-		t.Fatalf("foreign header should not be removed, got %s (%s)", result.Action, result.Reason)
+	var out bytes.Buffer
+	opts := licer.ProcessOptions{Preview: diffPreview(&out, root, false)}
+	if result := licer.ProcessFileWithOptions(filename, testConfig(), opts); result.Action != "ADD" {
+		t.Fatalf("expected ADD, got %s (%s)", result.Action, result.Reason)
 	}
 
-	content, _ := os.ReadFile(path)
-	if string(content) != source {
-		t.Error("file was modified despite ownership mismatch")
+	if content, _ := os.ReadFile(filename); string(content) != "print('hi')\n" {
+		t.Errorf("--diff modified the file:\n%s", content)
+	}
+	diff := out.String()
+	if !strings.HasPrefix(diff, "--- a/main.py\n+++ b/main.py\n@@ -1,1 +1,") || !strings.Contains(diff, "\n+# SPDX-License-Identifier: ") || !strings.HasSuffix(diff, "\n print('hi')\n") {
+		t.Errorf("unexpected diff:\n%s", diff)
 	}
 }
 
-func TestHookInstallDetection(t *testing.T) {
-	repoRoot := t.TempDir()
-	hooksDir := filepath.Join(repoRoot, ".git", "hooks")
-	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+func TestEditPlanHasReplacedLineRange(t *testing.T) {
+	root := t.TempDir()
+	filename := filepath.Join(root, "pkg", "main.py")
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatal(err)
+	}
+	source := "#!/usr/bin/env python3\n# Copyright 2019 Oregon State University\n# SPDX-License-Identifier: MIT\n\nprint('hi')\n"
+	if err := os.WriteFile(filename, []byte(source), 0644); err != nil {
 		t.Fatal(err)
 	}
 
-	if isHookInstalled(repoRoot) {
-		t.Error("hook reported installed before installation")
+	edits := newEditPlanner(root)
+	opts := licer.ProcessOptions{ForceReplace: true, Preview: edits.preview}
+	result := licer.ProcessFileWithOptions(filename, testConfig(), opts)
+	if result.Action != "REPLACE" {
+		t.Fatalf("expected REPLACE, got %s (%s)", result.Action, result.Reason)
 	}
+	edits.record(filename, result)
 
-	if err := installPreCommitHook(repoRoot, false); err != nil {
-		t.Fatalf("failed to install hook: %v", err)
+	if content, _ := os.ReadFile(filename); string(content) != source {
+		t.Errorf("the plan modified the file:\n%s", content)
 	}
-	if !isHookInstalled(repoRoot) {
-		t.Error("hook not detected after installation")
+	var out bytes.Buffer
+	if err := writeEditPlanJSON(&out, edits.Plan()); err != nil {
+		t.Fatal(err)
+	}
+	var plan EditPlan
+	if err := json.Unmarshal(out.Bytes(), &plan); err != nil {
+		t.Fatalf("invalid JSON (%v):\n%s", err, out.String())
 	}
+	if len(plan.Files) != 1 {
+		t.Fatalf("expected one planned file, got %+v", plan.Files)
+	}
+	edit := plan.Files[0]
+	if edit.Path != "pkg/main.py" || edit.Action != "REPLACE" {
+		t.Errorf("unexpected path or action: %+v", edit)
+	}
+	// The shebang stays; the old header on lines 2-3 is replaced
+	if edit.StartLine != 2 || edit.EndLine != 3 {
+		t.Errorf("replaced lines %d-%d, want 2-3", edit.StartLine, edit.EndLine)
+	}
+	if edit.Removed != "# Copyright 2019 Oregon State University\n# SPDX-License-Identifier: MIT\n" {
+		t.Errorf("unexpected removed text: %q", edit.Removed)
+	}
+	if !strings.HasPrefix(edit.Inserted, "# Copyright ") || !strings.Contains(edit.Inserted, "# SPDX-License-Identifier: Apache-2.0\n") {
+		t.Errorf("unexpected inserted header: %q", edit.Inserted)
+	}
+}
 
-	if err := uninstallPreCommitHook(repoRoot, false); err != nil {
-		t.Fatalf("failed to uninstall hook: %v", err)
+// TestMain lets tests run the CLI itself: the test binary re-executed with
+// LICER_TEST_MAIN=1 behaves as licer
+func TestMain(m *testing.M) {
+	if os.Getenv("LICER_TEST_MAIN") == "1" {
+		main()
+		os.Exit(exitOK)
 	}
-	if isHookInstalled(repoRoot) {
-		t.Error("hook still detected after uninstallation")
+	os.Exit(m.Run())
+}
+
+// runLicer runs the CLI with a config in a temporary home and returns its
+// exit code and output
+func runLicer(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	home := t.TempDir()
+	if err := os.Mkdir(filepath.Join(home, ".config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := "VERSION: 2\nFULL_NAME: Test User\nDEFAULT_ROLE: Staff\nDEPT_OR_LAB: Test Lab\nORGANIZATION: Oregon State University\n"
+	if err := os.WriteFile(filepath.Join(home, ".config", "licer.yml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "LICER_TEST_MAIN=1", "HOME="+home)
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), string(out)
+	}
+	if err != nil {
+		t.Fatalf("running licer failed: %v", err)
+	}
+	return exitOK, string(out)
+}
+
+func TestExitCodes(t *testing.T) {
+	root := newTestRepo(t, map[string]string{"main.py": "print('hi')\n"})
+
+	steps := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"conflicting flags", []string{"--force", "--remove", "--git-folder", root}, exitSetupError},
+		{"not a repository", []string{"--git-folder", t.TempDir()}, exitSetupError},
+		{"check with a missing header", []string{"--check", "--git-folder", root}, exitCheckFailed},
+		{"processing", []string{"--git-folder", root}, exitOK},
+		{"check after processing", []string{"--check", "--git-folder", root}, exitOK},
+	}
+	for _, step := range steps {
+		if code, out := runLicer(t, step.args...); code != step.want {
+			t.Fatalf("%s: exit code %d, want %d\n%s", step.name, code, step.want, out)
+		}
+	}
+
+	// A dangling symlink can't be read, which is a file error
+	if err := os.Symlink(filepath.Join(root, "missing.py"), filepath.Join(root, "broken.py")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if code, out := runLicer(t, "--git-folder", root); code != exitFileErrors {
+		t.Errorf("file error: exit code %d, want %d\n%s", code, exitFileErrors, out)
+	}
+}
+
+func TestForceOwnIsAnAliasOfForce(t *testing.T) {
+	root := newTestRepo(t, map[string]string{"main.py": "# Copyright 2020 Test User\n# SPDX-License-Identifier: MIT\n\nx = 1\n"})
+	path := filepath.Join(root, "main.py")
+
+	code, out := runLicer(t, "--force-own", "--force", "--yes", "--git-folder", root)
+	if code != exitOK || !strings.Contains(out, "--force-own is deprecated") {
+		t.Fatalf("exit code %d, want %d with a deprecation warning\n%s", code, exitOK, out)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "2020") {
+		t.Errorf("header not replaced:\n%s", data)
+	}
+}
+
+func TestStrictFailsOnUnknownSourceFiles(t *testing.T) {
+	files := map[string]string{
+		"main.py":   "print('hi')\n",
+		"lexer.xyz": "fn lex() {}\n", // source in a language licer doesn't know
+		"notes.txt": "excluded on purpose\n",
+		"blob.dat":  "\x00\x01\x02\x03",
+		"README.md": "# Readme\n",
+		"LICENSE":   "license text\n",
+	}
+	root := newTestRepo(t, files)
+
+	code, out := runLicer(t, "--strict", "--summary", "--git-folder", root)
+	if code != exitUnsupported {
+		t.Fatalf("exit code %d, want %d\n%s", code, exitUnsupported, out)
+	}
+	start := strings.Index(out, "(--strict)")
+	if start < 0 {
+		t.Fatalf("no list of unsupported files:\n%s", out)
+	}
+	listing := out[start:]
+	if !strings.Contains(listing, filepath.Join(root, "lexer.xyz")) {
+		t.Errorf("unknown source file not listed:\n%s", out)
+	}
+	for _, name := range []string{"notes.txt", "blob.dat", "README.md", "LICENSE"} {
+		if strings.Contains(listing, name) {
+			t.Errorf("%s listed although it is excluded on purpose:\n%s", name, out)
+		}
+	}
+
+	// The other files are still processed, and without --strict the
+	// unknown file is an ordinary skip
+	if code, out := runLicer(t, "--git-folder", root); code != exitOK {
+		t.Errorf("without --strict: exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	if code, out := runLicer(t, "--strict", "--exclude-ext", ".xyz", "--git-folder", root); code != exitOK {
+		t.Errorf("with the extension excluded: exit code %d, want %d\n%s", code, exitOK, out)
+	}
+}
+
+func TestCacheSkipsUnchangedFilesWithHeader(t *testing.T) {
+	root := newTestRepo(t, map[string]string{"a.py": "x = 1\n", "b.py": "x = 1\n"})
+
+	config := testConfig()
+	results := func(hash string) map[string]string {
+		t.Helper()
+		crawler := NewCrawler(config, licer.ProcessOptions{}, false, false, 1)
+		crawler.cache = loadResultCache(root, hash)
+		reasons := map[string]string{}
+		for _, name := range []string{"a.py", "b.py"} {
+			reasons[name] = crawler.processFile(filepath.Join(root, name), crawler.config).Reason
+		}
+		if err := crawler.cache.Save(true); err != nil {
+			t.Fatal(err)
+		}
+		return reasons
+	}
+
+	hash := cacheConfigHash(config, nil, nil)
+	results(hash) // Adds the headers and caches the written files
+	if got := results(hash); got["a.py"] != "Header already exists (cached)" || got["b.py"] != "Header already exists (cached)" {
+		t.Fatalf("unchanged files not answered from the cache: %v", got)
+	}
+
+	// An edited file is read again
+	if err := os.WriteFile(filepath.Join(root, "b.py"), []byte("y = 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := results(hash)
+	if got["a.py"] != "Header already exists (cached)" || !strings.HasPrefix(got["b.py"], "Added") {
+		t.Fatalf("edited file answered from the cache: %v", got)
+	}
+
+	// A different config invalidates every entry
+	if got := results(cacheConfigHash(config, []string{".sql"}, nil)); got["a.py"] != "Header already exists" {
+		t.Fatalf("cache used with a different config: %v", got)
+	}
+}
+
+func TestCheckWithCacheValidatesSPDX(t *testing.T) {
+	root := newTestRepo(t, map[string]string{"main.py": "# Copyright 2025 Oregon State University\n# SPDX-License-Identifier: NOT-A-LICENSE\n\nx = 1\n"})
+
+	// A plain run caches the file as having a header, which --check must
+	// not trust, on the first run or any later one
+	if code, out := runLicer(t, "--cache", "--git-folder", root); code != exitOK {
+		t.Fatalf("--cache: exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	for run := 1; run <= 2; run++ {
+		code, out := runLicer(t, "--check", "--cache", "--git-folder", root)
+		if code != exitFileErrors || !strings.Contains(out, "NOT-A-LICENSE") {
+			t.Fatalf("run %d: exit code %d, want %d with the invalid id reported\n%s", run, code, exitFileErrors, out)
+		}
+	}
+}
+
+// BenchmarkCrawlerRerun re-runs the crawler over a repository whose files
+// all have headers already, without and with a warm --cache
+func BenchmarkCrawlerRerun(b *testing.B) {
+	root := b.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		b.Fatal(err)
+	}
+	body := strings.Repeat("value = compute(1, 2, 3)\n", 2048)
+	for i := 0; i < 200; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("pkg%d", i%10))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("mod%d.py", i)), []byte(body), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	config := testConfig()
+	hash := cacheConfigHash(config, nil, nil)
+
+	run := func(cache bool) {
+		crawler := NewCrawler(config, licer.ProcessOptions{}, false, false, 4)
+		if cache {
+			crawler.cache = loadResultCache(root, hash)
+		}
+		if err := crawler.ProcessRepository(root); err != nil {
+			b.Fatal(err)
+		}
+		if cache {
+			if err := crawler.cache.Save(true); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	run(true) // Adds the headers and warms the cache
+
+	for _, cache := range []bool{false, true} {
+		name := "no-cache"
+		if cache {
+			name = "warm-cache"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				run(cache)
+			}
+		})
+	}
+}
+
+func TestOwnerOverridesCopyrightOwner(t *testing.T) {
+	theirs := "# Copyright (c) 2020 Jane Collaborator\n# SPDX-License-Identifier: MIT\n\nprint('theirs')\n"
+	root := newTestRepo(t, map[string]string{"main.py": "print('hi')\n", "theirs.py": theirs})
+
+	if code, out := runLicer(t, "--owner", "Jane Collaborator", "--git-folder", root); code != exitOK {
+		t.Fatalf("exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	content, err := os.ReadFile(filepath.Join(root, "main.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	year := time.Now().Year()
+	if !strings.Contains(string(content), fmt.Sprintf("# Copyright %d Jane Collaborator\n", year)) {
+		t.Errorf("header does not name the overridden owner:\n%s", content)
+	}
+	if strings.Contains(string(content), "Oregon State University") {
+		t.Errorf("header still names the configured organization:\n%s", content)
+	}
+
+	// The header of the overridden owner only counts as ours with --owner
+	if code, out := runLicer(t, "--remove", "--yes", "--git-folder", root); code != exitOK {
+		t.Fatalf("remove: exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "theirs.py")); string(content) != theirs {
+		t.Errorf("header removed without --owner:\n%s", content)
+	}
+	if code, out := runLicer(t, "--remove", "--yes", "--owner", "Jane Collaborator", "--git-folder", root); code != exitOK {
+		t.Fatalf("remove with --owner: exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "theirs.py")); string(content) != "print('theirs')\n" {
+		t.Errorf("header of the overridden owner not removed:\n%s", content)
+	}
+}
+
+func TestFilesResolvingOutsideRootAreRefused(t *testing.T) {
+	root := newTestRepo(t, map[string]string{"main.py": "print('hi')\n"})
+	outside := filepath.Join(t.TempDir(), "outside.py")
+	if err := os.WriteFile(outside, []byte("print('outside')\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link.py")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink("main.py", filepath.Join(root, "inside.py")); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out := runLicer(t, "--git-folder", root)
+	if code != exitFileErrors {
+		t.Fatalf("exit code %d, want %d\n%s", code, exitFileErrors, out)
+	}
+	if !strings.Contains(out, "outside the repository") {
+		t.Errorf("no error naming the escaping link:\n%s", out)
+	}
+	if content, _ := os.ReadFile(outside); string(content) != "print('outside')\n" {
+		t.Errorf("file outside the repository was modified:\n%s", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "main.py")); !strings.Contains(string(content), "SPDX-License-Identifier") {
+		t.Errorf("file inside the repository not processed:\n%s", content)
+	}
+
+	// Staged files get the same check
+	crawler := NewCrawler(testConfig(), licer.ProcessOptions{}, false, false, 1)
+	if hasErrors := processStagedFiles(root, []string{"link.py"}, func(path string) licer.ProcessResult { return crawler.processInRepo(root, path) }); !hasErrors {
+		t.Error("staged link outside the repository not reported")
+	}
+	if crawler.stats.FilesProcessed != 0 {
+		t.Errorf("staged link outside the repository was processed")
+	}
+}
+
+func TestReportUnlicensedListsFilesWithoutHeader(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"ours.py":         "def main():\n    pass\n",
+		"bare.py":         "print(1)\n",
+		"pkg/bare.go":     "package pkg\n",
+		"vendor.go":       "// Copyright (c) 2020 Other Corp\n\npackage vendor\n",
+		"foreign.go":      "// Copyright 2021 Someone Else\n// SPDX-License-Identifier: MIT\n\npackage foreign\n",
+		"notes.txt":       "not processable\n",
+		".git/HEAD":       "ref: refs/heads/main\n",
+		"lib/unknown.xyz": "fn lex() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	licer.ProcessFile(filepath.Join(root, "ours.py"), testConfig(), false, false, false)
+
+	code, out := runLicer(t, "--report-unlicensed", "--summary", "--git-folder", root)
+	if code != exitOK {
+		t.Fatalf("exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	if out != "bare.py\npkg/bare.go\n" {
+		t.Errorf("unexpected list:\n%s", out)
+	}
+
+	code, out = runLicer(t, "--report-unlicensed", "--format=json", "--summary", "--git-folder", root)
+	if code != exitOK {
+		t.Fatalf("json: exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	var report UnlicensedReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("invalid JSON (%v):\n%s", err, out)
+	}
+	if len(report.Files) != 2 || report.Files[0] != "bare.py" || report.Files[1] != "pkg/bare.go" {
+		t.Errorf("unexpected JSON files: %v", report.Files)
+	}
+
+	if content, _ := os.ReadFile(filepath.Join(root, "bare.py")); string(content) != "print(1)\n" {
+		t.Errorf("report modified a file:\n%s", content)
+	}
+	if code, out := runLicer(t, "--report-unlicensed", "--force", "--git-folder", root); code != exitSetupError {
+		t.Errorf("--report-unlicensed with --force: exit code %d, want %d\n%s", code, exitSetupError, out)
+	}
+}
+
+func TestFailOnThirdPartyListsForeignCopyrights(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"ours.py":           "def main():\n    pass\n",
+		"bare.py":           "print(1)\n",
+		"spdx.go":           "// SPDX-License-Identifier: Apache-2.0\n\npackage spdx\n",
+		"vendor/lib.go":     "// Copyright (c) 2020 Other Corp\n\npackage vendor\n",
+		"foreign.go":        "// Copyright 2021 Someone Else\n// SPDX-License-Identifier: MIT\n\npackage foreign\n",
+		"pasted/snippet.js": "/*\n * Copyright 2019 Example Inc. All rights reserved.\n */\nexport const x = 1;\n",
+		".git/HEAD":         "ref: refs/heads/main\n",
+		"notes/third.txt":   "Copyright 2018 Not Processable\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	licer.ProcessFile(filepath.Join(root, "ours.py"), testConfig(), false, false, false)
+
+	// Our header, unheadered files and an SPDX line naming no holder pass
+	code, out := runLicer(t, "--fail-on-third-party", "--summary", "--git-folder", root)
+	if code != exitThirdPartyFound {
+		t.Fatalf("exit code %d, want %d\n%s", code, exitThirdPartyFound, out)
+	}
+	want := "foreign.go: Copyright 2021 Someone Else\n" +
+		"pasted/snippet.js: Copyright 2019 Example Inc. All rights reserved.\n" +
+		"vendor/lib.go: Copyright (c) 2020 Other Corp\n"
+	if !strings.HasPrefix(out, want) || !strings.Contains(out, "3 file(s) carry a third-party copyright") {
+		t.Errorf("unexpected list:\n%s", out)
+	}
+
+	code, out = runLicer(t, "--fail-on-third-party", "--format=json", "--summary", "--git-folder", root)
+	if code != exitThirdPartyFound {
+		t.Fatalf("json: exit code %d, want %d\n%s", code, exitThirdPartyFound, out)
+	}
+	var report ThirdPartyReport
+	if err := json.NewDecoder(strings.NewReader(out)).Decode(&report); err != nil {
+		t.Fatalf("invalid JSON (%v):\n%s", err, out)
+	}
+	if len(report.Files) != 3 || report.Files[2].Path != "vendor/lib.go" || report.Files[2].Copyright != "Copyright (c) 2020 Other Corp" {
+		t.Errorf("unexpected JSON files: %+v", report.Files)
+	}
+
+	if content, _ := os.ReadFile(filepath.Join(root, "vendor/lib.go")); string(content) != files["vendor/lib.go"] {
+		t.Errorf("audit modified a file:\n%s", content)
+	}
+
+	// Without third-party code the audit passes
+	for _, name := range []string{"vendor/lib.go", "foreign.go", "pasted/snippet.js"} {
+		os.Remove(filepath.Join(root, name))
+	}
+	if code, out := runLicer(t, "--fail-on-third-party", "--summary", "--git-folder", root); code != exitOK || out != "" {
+		t.Errorf("clean tree: exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	if code, out := runLicer(t, "--fail-on-third-party", "--force", "--git-folder", root); code != exitSetupError {
+		t.Errorf("--fail-on-third-party with --force: exit code %d, want %d\n%s", code, exitSetupError, out)
+	}
+}
+
+func TestDirectoryConfigAppliesToSubtree(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.py":                     "x = 1\n",
+		"labs/ocean/.licer.yml":       "ORGANIZATION: Ocean Institute\n",
+		"labs/ocean/model/sim.py":     "x = 1\n",
+		"labs/ocean/model/.licer.yml": "DEPT_OR_LAB: Model Group\n",
+		"labs/soil/soil.py":           "x = 1\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	crawler := NewCrawler(testConfig(), licer.ProcessOptions{}, false, false, 2)
+	if err := crawler.ProcessRepository(root); err != nil {
+		t.Fatalf("ProcessRepository failed: %v", err)
+	}
+
+	year := time.Now().Year()
+	for name, want := range map[string][]string{
+		"main.py":                 {fmt.Sprintf("# Copyright %d Oregon State University\n", year), "#               Test Lab\n"},
+		"labs/soil/soil.py":       {fmt.Sprintf("# Copyright %d Oregon State University\n", year), "#               Test Lab\n"},
+		"labs/ocean/model/sim.py": {fmt.Sprintf("# Copyright %d Ocean Institute\n", year), "#               Model Group\n"},
+	} {
+		content, _ := os.ReadFile(filepath.Join(root, name))
+		for _, line := range want {
+			if !strings.Contains(string(content), line) {
+				t.Errorf("%s: missing %q:\n%s", name, line, content)
+			}
+		}
+	}
+
+	// Files processed without the walk, e.g. with --since, get the same config
+	target := filepath.Join(root, "labs/ocean/new.py")
+	if err := os.WriteFile(target, []byte("x = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	crawler = NewCrawler(testConfig(), licer.ProcessOptions{}, false, false, 1)
+	if err := crawler.ProcessFiles(root, []string{"labs/ocean/new.py"}); err != nil {
+		t.Fatalf("ProcessFiles failed: %v", err)
+	}
+	if content, _ := os.ReadFile(target); !strings.Contains(string(content), "Ocean Institute\n#\n") || !strings.Contains(string(content), "Test Lab\n") {
+		t.Errorf("subtree config not used for an explicit file:\n%s", content)
+	}
+}
+
+func TestStatsTallyLicensesApplied(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.py":                    "x = 1\n",
+		"tools/run.sh":               "echo hi\n",
+		"students/.licer.yml":        "DEFAULT_ROLE: Student\n",
+		"students/a.py":              "x = 1\n",
+		"students/b.py":              "x = 1\n",
+		"students/c.go":              "package c\n",
+		"students/done.py":           "# SPDX-License-Identifier: MIT\nx = 1\n",
+		"students/thesis/.licer.yml": "DEFAULT_ROLE: Faculty\n",
+		"students/thesis/t.py":       "x = 1\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := testConfig()
+	config.RoleLicenses = map[string]string{"Faculty": "BSD-3-Clause"}
+	crawler := NewCrawler(config, licer.ProcessOptions{}, false, false, 4)
+	if err := crawler.ProcessRepository(root); err != nil {
+		t.Fatalf("ProcessRepository failed: %v", err)
+	}
+
+	// The .licer.yml files get a header of their directory's license too
+	want := map[string]int64{"Apache-2.0": 2, "MIT": 4, "BSD-3-Clause": 2}
+	got := crawler.stats.LicenseCounts()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected licenses %v, got %v", want, got)
+	}
+
+	// Removals are not counted as applied
+	crawler = NewCrawler(config, licer.ProcessOptions{RemoveMode: true}, false, false, 4)
+	if err := crawler.ProcessRepository(root); err != nil {
+		t.Fatalf("ProcessRepository failed: %v", err)
+	}
+	if got := crawler.stats.LicenseCounts(); len(got) != 0 {
+		t.Errorf("removal counted as applied licenses: %v", got)
+	}
+}
+
+func TestStatsTallyKeptLicenseOnNormalize(t *testing.T) {
+	// Our own MIT header, messily indented, in a tree whose config is Apache
+	root := newTestRepo(t, map[string]string{
+		"mit.py": "#   Copyright 2019 Oregon State University\n#\n#\n#    SPDX-License-Identifier: MIT\n#\n# Developed by: Test User\n\nx = 1\n",
+	})
+
+	crawler := NewCrawler(testConfig(), licer.ProcessOptions{Normalize: true}, false, false, 1)
+	if err := crawler.ProcessRepository(root); err != nil {
+		t.Fatalf("ProcessRepository failed: %v", err)
+	}
+	if crawler.stats.FilesModified != 1 {
+		t.Fatalf("expected the header to be normalized, modified %d files", crawler.stats.FilesModified)
+	}
+	want := map[string]int64{"MIT": 1}
+	if got := crawler.stats.LicenseCounts(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected licenses %v, got %v", want, got)
+	}
+}
+
+func TestRepoConfigOverridesRole(t *testing.T) {
+	newRepo := func(repoConfig string) string {
+		return newTestRepo(t, map[string]string{"main.py": "print('hi')\n", licer.RepoConfigName: repoConfig})
+	}
+
+	// The global config of runLicer is Staff
+	root := newRepo("DEFAULT_ROLE: Student\n")
+	if code, out := runLicer(t, "--git-folder", root); code != exitOK {
+		t.Fatalf("exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	content, _ := os.ReadFile(filepath.Join(root, "main.py"))
+	if !strings.Contains(string(content), fmt.Sprintf("# Copyright (c) %d Test User\n", time.Now().Year())) ||
+		!strings.Contains(string(content), "SPDX-License-Identifier: MIT") {
+		t.Errorf("repository role not used for the header:\n%s", content)
+	}
+	if license, _ := os.ReadFile(filepath.Join(root, "LICENSE")); !strings.Contains(string(license), "MIT License") {
+		t.Errorf("repository role not used for the LICENSE file:\n%s", license)
+	}
+
+	// --role wins over the repository
+	root = newRepo("DEFAULT_ROLE: Student\n")
+	if code, out := runLicer(t, "--role", "Faculty", "--git-folder", root); code != exitOK {
+		t.Fatalf("--role: exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "main.py")); !strings.Contains(string(content), "SPDX-License-Identifier: Apache-2.0") {
+		t.Errorf("--role not used for the header:\n%s", content)
+	}
+
+	if code, out := runLicer(t, "--git-folder", newRepo("DEFAULT_ROLE: Intern\n")); code != exitSetupError || !strings.Contains(out, "invalid role 'Intern'") {
+		t.Errorf("invalid repository role: exit code %d, want %d\n%s", code, exitSetupError, out)
+	}
+	if code, out := runLicer(t, "--role", "Intern", "--git-folder", root); code != exitSetupError {
+		t.Errorf("invalid --role: exit code %d, want %d\n%s", code, exitSetupError, out)
+	}
+}
+
+func TestLogJSONWritesOneObjectPerLine(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".git/HEAD":  "ref: refs/heads/main\n",
+		"main.py":    "print('hi')\n",
+		"lib/app.go": "package lib\n",
+		"notes.txt":  "excluded\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	code, out := runLicer(t, "--log-json", "--git-folder", root)
+	if code != exitOK {
+		t.Fatalf("exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	actions := map[string]string{}
+	summaries := 0
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line is not JSON (%v): %q\nfull output:\n%s", err, line, out)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, fmt.Sprint(entry["timestamp"])); err != nil {
+			t.Errorf("bad timestamp in %q: %v", line, err)
+		}
+		if path, ok := entry["path"].(string); ok {
+			rel, _ := filepath.Rel(root, path)
+			actions[filepath.ToSlash(rel)] = fmt.Sprint(entry["action"])
+			if _, ok := entry["reason"].(string); !ok {
+				t.Errorf("no reason in %q", line)
+			}
+		} else if entry["summary"] != nil {
+			summaries++
+			if entry["modified"] != float64(2) {
+				t.Errorf("unexpected summary: %q", line)
+			}
+		}
+	}
+	want := map[string]string{"main.py": "ADD", "lib/app.go": "ADD", "notes.txt": "SKIP", "LICENSE": "SKIP"}
+	for path, action := range want {
+		if actions[path] != action {
+			t.Errorf("%s: action %q, want %q (all: %v)", path, actions[path], action, actions)
+		}
+	}
+	if summaries != 1 {
+		t.Errorf("%d summary lines, want 1", summaries)
+	}
+}
+
+func TestCrawlerRecoversFromPanicOnOneFile(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.py", "bad.py", "c.py"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("x = 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	previewed := 0
+	opts := licer.ProcessOptions{Preview: func(filename string, original, modified []byte) {
+		if filepath.Base(filename) == "bad.py" {
+			panic("pathological input")
+		}
+		previewed++
+	}}
+	crawler := NewCrawler(testConfig(), opts, false, false, 1)
+	var logged bytes.Buffer
+	crawler.logger = newJSONLogger(&logged)
+	if err := crawler.ProcessRepository(root); err != nil {
+		t.Fatalf("ProcessRepository failed: %v", err)
+	}
+
+	if crawler.stats.FilesErrored != 1 || crawler.stats.FilesProcessed != 3 || previewed != 2 {
+		t.Errorf("unexpected stats after a panic: %+v, %d previewed", crawler.stats, previewed)
+	}
+	result := crawler.safeProcess(filepath.Join(root, "bad.py"), crawler.config)
+	if result.Action != "ERROR" || !strings.HasPrefix(result.Reason, "Error: panic while processing: pathological input") {
+		t.Errorf("unexpected result for the panicking file: %+v", result)
+	}
+	if !strings.Contains(result.Stack, "safeProcess") {
+		t.Errorf("result has no stack of the panic:\n%s", result.Stack)
+	}
+
+	// --log-json, as used for unattended runs, reports the stack too
+	var reported jsonResult
+	for _, line := range strings.Split(strings.TrimSpace(logged.String()), "\n") {
+		var entry jsonResult
+		if err := json.Unmarshal([]byte(line), &entry); err == nil && filepath.Base(entry.Path) == "bad.py" {
+			reported = entry
+		}
+	}
+	if reported.Action != "ERROR" || !strings.Contains(reported.Stack, "safeProcess") {
+		t.Errorf("panic not logged with its stack: %+v", reported)
+	}
+}
+
+func TestConfirmationAbortLeavesFilesUntouched(t *testing.T) {
+	files := map[string]string{
+		"ours.py":   "# Copyright 2019 Oregon State University\n#\n# SPDX-License-Identifier: MIT\n\nx = 1\n",
+		"theirs.py": "# Copyright 2019 Example Corp\n# SPDX-License-Identifier: MIT\n\nx = 2\n",
+		"notice.py": "# Copyright (c) 2019 Example Corp. All rights reserved.\n\nx = 3\n",
+		"new.py":    "x = 4\n",
+	}
+	root := newTestRepo(t, files)
+	unchanged := func(step string) {
+		t.Helper()
+		for name, content := range files {
+			if got, _ := os.ReadFile(filepath.Join(root, name)); string(got) != content {
+				t.Errorf("%s: %s changed:\n%s", step, name, got)
+			}
+		}
+	}
+
+	// The counting pass writes nothing and finds every kind of change
+	plan := &changePlan{}
+	opts := licer.ProcessOptions{ForceReplace: true, ReplaceThirdParty: true, Preview: func(string, []byte, []byte) {}}
+	crawler := NewCrawler(testConfig(), opts, false, false, 1)
+	crawler.plan = plan
+	if err := crawler.ProcessRepository(root); err != nil {
+		t.Fatalf("ProcessRepository failed: %v", err)
+	}
+	if plan.Files != 4 || plan.Replacements != 3 || plan.ThirdParty != 2 || plan.Removals != 0 {
+		t.Errorf("unexpected plan: %d files, %d replacements, %d third-party, %d removals", plan.Files, plan.Replacements, plan.ThirdParty, plan.Removals)
+	}
+	unchanged("counting pass")
+
+	for answer, want := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		var out bytes.Buffer
+		if got := confirmChanges(strings.NewReader(answer), &out, plan); got != want {
+			t.Errorf("answer %q: got %v, want %v", answer, got, want)
+		}
+		if !strings.Contains(out.String(), "About to modify 4 files (3 replacements, 2 third-party overwrites, 0 removals); proceed? (y/N)") {
+			t.Errorf("unexpected prompt %q", out.String())
+		}
+	}
+
+	// Without a terminal to ask on (stdin is /dev/null here), destructive
+	// runs abort unless --yes is given
+	for _, args := range [][]string{{"--force"}, {"--force", "--replace-third-party"}, {"--remove"}} {
+		code, out := runLicer(t, append(args, "--git-folder", root)...)
+		if code != exitSetupError || !strings.Contains(out, "pass --yes") {
+			t.Errorf("%v: exit code %d, want %d\n%s", args, code, exitSetupError, out)
+		}
+		unchanged(strings.Join(args, " "))
+	}
+
+	if code, out := runLicer(t, "--force", "--replace-third-party", "--yes", "--git-folder", root); code != exitOK {
+		t.Fatalf("--yes: exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "theirs.py")); strings.Contains(string(content), "Example Corp") {
+		t.Errorf("--yes did not apply the changes:\n%s", content)
+	}
+}
+
+func TestFirstRunWritesNothingWithoutConfirmation(t *testing.T) {
+	source := "print('hi')\n"
+	root := newTestRepo(t, map[string]string{"main.py": source})
+	if repos := firstRunRepos([]string{root}); len(repos) != 1 || repos[0] != root {
+		t.Fatalf("new repository not treated as a first run: %q", repos)
+	}
+
+	confirm := func(answer string) (bool, string) {
+		var out bytes.Buffer
+		plan := &changePlan{}
+		scan := func(repoRoot string) error {
+			opts := licer.ProcessOptions{Preview: func(string, []byte, []byte) {}}
+			crawler := NewCrawler(testConfig(), opts, false, false, 1)
+			crawler.plan = plan
+			return crawler.ProcessRepository(repoRoot)
+		}
+		return confirmFirstRun(strings.NewReader(answer), &out, []string{root}, plan, scan), out.String()
+	}
+
+	for _, answer := range []string{"", "n\n"} {
+		ok, out := confirm(answer)
+		if ok || !strings.Contains(out, "[ADD] "+filepath.Join(root, "main.py")) || !strings.Contains(out, "About to modify 1 files") {
+			t.Errorf("answer %q: confirmed %v\n%s", answer, ok, out)
+		}
+		if content, _ := os.ReadFile(filepath.Join(root, "main.py")); string(content) != source {
+			t.Errorf("answer %q: file changed before confirmation:\n%s", answer, content)
+		}
+		if _, err := os.Stat(filepath.Join(root, "LICENSE")); err == nil {
+			t.Errorf("answer %q: LICENSE created before confirmation", answer)
+		}
+	}
+	if ok, out := confirm("y\n"); !ok {
+		t.Errorf("yes not accepted:\n%s", out)
+	}
+
+	// A run that wrote to the repository marks it, so later runs don't ask,
+	// even after --undo removed the run manifest
+	git := gitRunner(t, root)
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+	if code, out := runLicer(t, "--git-folder", root); code != exitOK {
+		t.Fatalf("exit code %d, want %d\n%s", code, exitOK, out)
+	}
+	result, err := UndoLastRun(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Restored) != 1 {
+		t.Errorf("undo restored %q", result.Restored)
+	}
+	if _, err := os.Stat(manifestPath(root)); err == nil {
+		t.Errorf("undo kept the run manifest")
+	}
+	if repos := firstRunRepos([]string{root}); len(repos) != 0 {
+		t.Errorf("repository treated as a first run after --undo: %q", repos)
+	}
+}
+
+func TestFirstRunWithNothingToChangeDoesNotAsk(t *testing.T) {
+	root := newTestRepo(t, map[string]string{"notes.txt": "nothing to license\n"})
+	var out bytes.Buffer
+	plan := &changePlan{}
+	scan := func(string) error { return nil }
+	if !confirmFirstRun(strings.NewReader(""), &out, []string{root}, plan, scan) {
+		t.Errorf("a first run with nothing to change was declined")
+	}
+	if out.Len() != 0 {
+		t.Errorf("a first run with nothing to change asked:\n%s", out.String())
+	}
+}
+
+func TestFirstRunConfirmationSkippedWhenUnattended(t *testing.T) {
+	savedFolders, savedYes := gitFolders, yes
+	t.Cleanup(func() { gitFolders, yes = savedFolders, savedYes })
+
+	gitFolders, yes = nil, false
+	if !needsFirstRunConfirmation() {
+		t.Errorf("a plain run does not confirm the first run")
+	}
+	gitFolders = pathList{t.TempDir()}
+	if needsFirstRunConfirmation() {
+		t.Errorf("--git-folder confirms the first run")
+	}
+	gitFolders, yes = nil, true
+	if needsFirstRunConfirmation() {
+		t.Errorf("--yes confirms the first run")
+	}
+}
+
+func TestReadOnlyWritesNothing(t *testing.T) {
+	files := map[string]string{
+		"main.py":   "print('hi')\n",
+		"old.py":    "# Copyright 2019 Oregon State University\n# SPDX-License-Identifier: MIT\n\nx = 1\n",
+		"vendor.py": "# Copyright 2019 Other Corp\n# SPDX-License-Identifier: MIT\n\nx = 1\n",
+	}
+	root := newTestRepo(t, files)
+
+	code, out := runLicer(t, "--read-only", "--force", "--replace-third-party", "--yes", "--git-folder", root)
+	if code != exitOK || !strings.Contains(out, "no files will be written") {
+		t.Errorf("expected a warning and exit 0, got %d:\n%s", code, out)
+	}
+	if !strings.Contains(out, "[ADD] "+filepath.Join(root, "main.py")) {
+		t.Errorf("changes were not reported:\n%s", out)
+	}
+	for name, content := range files {
+		if got, _ := os.ReadFile(filepath.Join(root, name)); string(got) != content {
+			t.Errorf("%s was modified under --read-only:\n%s", name, got)
+		}
+	}
+	entries, _ := os.ReadDir(root)
+	gitEntries, _ := os.ReadDir(filepath.Join(root, ".git"))
+	if len(entries) != len(files)+1 || len(gitEntries) != 0 {
+		t.Errorf("files were created under --read-only: %d in the repository, %d in .git", len(entries), len(gitEntries))
+	}
+
+	if code, out := runLicer(t, "--read-only", "--hook", "--git-folder", root); code != exitSetupError {
+		t.Errorf("--read-only --hook should be refused, got %d:\n%s", code, out)
+	}
+
+	// The core refuses writes too, whoever calls it
+	licer.SetReadOnly(true)
+	defer licer.SetReadOnly(false)
+	if result := licer.ProcessFileWithOptions(filepath.Join(root, "main.py"), testConfig(), licer.ProcessOptions{}); !strings.Contains(result.Reason, licer.ErrReadOnly.Error()) {
+		t.Errorf("write not refused: %s (%s)", result.Action, result.Reason)
+	}
+	if err := licer.ManageLicenseFile(root, testConfig(), false); !errors.Is(err, licer.ErrReadOnly) {
+		t.Errorf("LICENSE write not refused: %v", err)
+	}
+}
+
+func TestUnreadableDirectoriesAreReported(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"main.py":               "x = 1\n",
+		"locked/secret.py":      "x = 1\n",
+		"broken/.licer.yml":     "DEFAULT_ROLE: [\n",
+		"broken/inner/other.py": "x = 1\n",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	locked := filepath.Join(root, "locked")
+	if err := os.Chmod(locked, 0000); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0755)
+
+	// root reads a 0000 directory anyway; the broken config skips its
+	// subtree for everyone
+	want := []string{filepath.Join(root, "broken")}
+	if _, err := os.ReadDir(locked); err != nil {
+		want = append(want, locked)
+	}
+
+	crawler := NewCrawler(testConfig(), licer.ProcessOptions{Preview: func(string, []byte, []byte) {}}, false, false, 2)
+	if err := crawler.ProcessRepository(root); err != nil {
+		t.Fatalf("ProcessRepository failed: %v", err)
+	}
+	got := crawler.SkippedDirs()
+	sort.Strings(got)
+	sort.Strings(want)
+	if fmt.Sprint(got) != fmt.Sprint(want) || crawler.stats.DirsSkipped != int64(len(want)) {
+		t.Errorf("expected skipped directories %q, got %q (%d counted)", want, got, crawler.stats.DirsSkipped)
+	}
+
+	// Incomplete coverage fails --check and --strict, not a normal run
+	if code := exitCode(crawler.stats, true, false); code != exitFileErrors {
+		t.Errorf("--check: expected exit %d, got %d", exitFileErrors, code)
+	}
+	if code := exitCode(crawler.stats, false, true); code != exitFileErrors {
+		t.Errorf("--strict: expected exit %d, got %d", exitFileErrors, code)
+	}
+	stats := &ProcessingStats{DirsSkipped: crawler.stats.DirsSkipped}
+	if code := exitCode(stats, false, false); code != exitOK {
+		t.Errorf("normal run: expected exit %d, got %d", exitOK, code)
+	}
+}
+
+func TestLicenseFileOnlyAndNoLicenseFile(t *testing.T) {
+	source := "print('hi')\n"
+
+	// --license-file-only creates the LICENSE and touches no source file
+	root := newTestRepo(t, map[string]string{"main.py": source})
+	if code, out := runLicer(t, "--license-file-only", "--git-folder", root); code != exitOK {
+		t.Fatalf("--license-file-only: exit code %d\n%s", code, out)
+	}
+	if license, err := os.ReadFile(filepath.Join(root, "LICENSE")); err != nil || !strings.Contains(string(license), "Apache License") {
+		t.Errorf("LICENSE not created: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "main.py")); string(content) != source {
+		t.Errorf("--license-file-only added a header:\n%s", content)
+	}
+
+	// --no-license-file adds headers and no LICENSE
+	root = newTestRepo(t, map[string]string{"main.py": source})
+	if code, out := runLicer(t, "--no-license-file", "--git-folder", root); code != exitOK {
+		t.Fatalf("--no-license-file: exit code %d\n%s", code, out)
+	}
+	if _, err := os.Stat(filepath.Join(root, "LICENSE")); !os.IsNotExist(err) {
+		t.Error("--no-license-file created a LICENSE")
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "main.py")); !strings.Contains(string(content), "SPDX-License-Identifier") {
+		t.Errorf("--no-license-file added no header:\n%s", content)
+	}
+
+	for _, args := range [][]string{{"--license-file-only", "--remove", "--yes"}, {"--license-file-only", "--no-license-file"}} {
+		if code, out := runLicer(t, append(args, "--git-folder", root)...); code != exitSetupError {
+			t.Errorf("%v: exit code %d, want %d\n%s", args, code, exitSetupError, out)
+		}
+	}
+}
+
+func TestSortedResultsAreStable(t *testing.T) {
+	files := map[string]string{}
+	for _, dir := range []string{"a", "b", "b/c", "d"} {
+		for i := 0; i < 5; i++ {
+			files[filepath.Join(dir, fmt.Sprintf("f%d.py", i))] = "print(1)\n"
+		}
+	}
+	root := newTestRepo(t, files)
+
+	// --check writes nothing, so both runs see the same tree
+	var first string
+	for run := 0; run < 2; run++ {
+		code, out := runLicer(t, "--sorted", "--check", "--jobs", "8", "--git-folder", root)
+		if code != exitCheckFailed {
+			t.Fatalf("exit code %d, want %d\n%s", code, exitCheckFailed, out)
+		}
+		if run == 0 {
+			first = out
+		} else if out != first {
+			t.Errorf("output differs between runs:\n%s\n---\n%s", first, out)
+		}
+	}
+
+	var paths []string
+	for _, line := range strings.Split(first, "\n") {
+		if strings.HasPrefix(line, "[ADD] ") {
+			paths = append(paths, strings.SplitN(strings.TrimPrefix(line, "[ADD] "), " - ", 2)[0])
+		}
+	}
+	if len(paths) != 20 || !sort.StringsAreSorted(paths) {
+		t.Errorf("expected 20 results sorted by path, got %v", paths)
 	}
 }
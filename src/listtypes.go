@@ -0,0 +1,128 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/licer/licer/pkg/licer"
+)
+
+// FileType is one supported extension and the comment style used for it
+type FileType struct {
+	Extension  string `json:"extension"`
+	Line       string `json:"line,omitempty"`
+	BlockStart string `json:"block_start,omitempty"`
+	BlockEnd   string `json:"block_end,omitempty"`
+}
+
+// FileTypeList is what --list-types prints: the extensions licer adds
+// headers to and the ones it skips, after --exclude-ext/--include-ext
+type FileTypeList struct {
+	Supported     []FileType `json:"supported"`
+	Excluded      []string   `json:"excluded"`
+	ExcludedNames []string   `json:"excluded_names"`
+}
+
+// handleListTypesMode prints the supported and excluded file types to stdout
+func handleListTypesMode(format string) {
+	list := ListFileTypes()
+
+	var err error
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(list)
+	} else {
+		err = writeFileTypesTable(os.Stdout, list)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file types: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// ListFileTypes collects the effective file type configuration, sorted by
+// extension
+func ListFileTypes() FileTypeList {
+	var list FileTypeList
+
+	excluded := make(map[string]bool)
+	for ext := range licer.AlwaysExcludedExtensions {
+		excluded[ext] = true
+	}
+	for ext := range licer.DefaultExcludedExtensions {
+		excluded[ext] = true
+	}
+	for ext := range licer.RuntimeExcluded {
+		excluded[ext] = true
+	}
+
+	for ext, style := range licer.CommentStyles {
+		if licer.IsExcludedExtension(ext) {
+			excluded[ext] = true
+			continue
+		}
+		if ext == "" {
+			ext = noExtensionKey
+		}
+		list.Supported = append(list.Supported, FileType{
+			Extension:  ext,
+			Line:       style.Line,
+			BlockStart: style.BlockStart,
+			BlockEnd:   style.BlockEnd,
+		})
+	}
+	sort.Slice(list.Supported, func(i, j int) bool {
+		return list.Supported[i].Extension < list.Supported[j].Extension
+	})
+
+	for ext := range excluded {
+		if licer.IsExcludedExtension(ext) {
+			list.Excluded = append(list.Excluded, ext)
+		}
+	}
+	sort.Strings(list.Excluded)
+
+	for name := range licer.ExcludedBasenames {
+		list.ExcludedNames = append(list.ExcludedNames, name)
+	}
+	sort.Strings(list.ExcludedNames)
+
+	return list
+}
+
+func writeFileTypesTable(w io.Writer, list FileTypeList) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Supported extensions:\n")
+	fmt.Fprintf(&b, "  %-12s %-8s %s\n", "Extension", "Line", "Block")
+	for _, ft := range list.Supported {
+		block := ""
+		if ft.BlockStart != "" {
+			block = ft.BlockStart + " " + ft.BlockEnd
+		}
+		row := fmt.Sprintf("  %-12s %-8s %s", ft.Extension, ft.Line, block)
+		fmt.Fprintf(&b, "%s\n", strings.TrimRight(row, " "))
+	}
+	fmt.Fprintf(&b, "  (files without an extension take the style of their shebang interpreter)\n")
+
+	fmt.Fprintf(&b, "\nExcluded extensions:\n  %s\n", strings.Join(list.Excluded, " "))
+	fmt.Fprintf(&b, "\nExcluded file names:\n  %s\n", strings.Join(list.ExcludedNames, " "))
+	fmt.Fprintf(&b, "  (and capitalized license file variants such as LICENSE-MIT or COPYING.LESSER)\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
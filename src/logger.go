@@ -0,0 +1,126 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/licer/licer/pkg/licer"
+)
+
+// ResultLogger reports the result of every processed file and the
+// statistics of a run. Callers serialize the calls.
+type ResultLogger interface {
+	LogResult(filename string, result licer.ProcessResult)
+	LogStats(title string, stats *ProcessingStats)
+}
+
+// newResultLogger returns the logger for this run: JSON lines with
+// --log-json, otherwise text
+func newResultLogger(verbose, summary bool) ResultLogger {
+	if logJSON {
+		return newJSONLogger(os.Stderr)
+	}
+	return textLogger{verbose: verbose, summary: summary}
+}
+
+// textLogger is the human-readable output: every file when verbose, only
+// the errors in summary mode
+type textLogger struct {
+	verbose bool
+	summary bool
+}
+
+func (l textLogger) LogResult(filename string, result licer.ProcessResult) {
+	if l.verbose {
+		licer.LogResult(filename, result, true)
+	} else if l.summary && strings.HasPrefix(result.Reason, "Error") {
+		fmt.Fprintf(os.Stderr, "[ERROR] %s - %s\n%s", filename, result.Reason, result.Stack)
+	}
+}
+
+func (l textLogger) LogStats(title string, stats *ProcessingStats) {
+	printStats(title, stats)
+}
+
+// jsonLogger writes one JSON object per line for every file and summary
+// (--log-json), for ingestion into a logging stack
+type jsonLogger struct {
+	w   io.Writer
+	now func() time.Time
+}
+
+func newJSONLogger(w io.Writer) *jsonLogger {
+	return &jsonLogger{w: w, now: time.Now}
+}
+
+// jsonResult is the line --log-json writes for a file
+type jsonResult struct {
+	Timestamp string `json:"timestamp"`
+	Path      string `json:"path"`
+	Action    string `json:"action"`
+	Reason    string `json:"reason"`
+	Stack     string `json:"stack,omitempty"` // of a panic while processing the file
+}
+
+// jsonStats is the line --log-json writes for a summary
+type jsonStats struct {
+	Timestamp   string `json:"timestamp"`
+	Summary     string `json:"summary"`
+	Processed   int64  `json:"processed"`
+	Modified    int64  `json:"modified"`
+	Skipped     int64  `json:"skipped"`
+	Errored     int64  `json:"errored"`
+	Unsupported int64  `json:"unsupported"`
+	DirsSkipped int64  `json:"dirs_skipped"`
+
+	Licenses map[string]int64 `json:"licenses,omitempty"`
+}
+
+func (l *jsonLogger) LogResult(filename string, result licer.ProcessResult) {
+	l.write(jsonResult{
+		Timestamp: l.timestamp(),
+		Path:      filename,
+		Action:    result.Action,
+		Reason:    result.Reason,
+		Stack:     result.Stack,
+	})
+}
+
+func (l *jsonLogger) LogStats(title string, stats *ProcessingStats) {
+	l.write(jsonStats{
+		Timestamp:   l.timestamp(),
+		Summary:     title,
+		Processed:   stats.FilesProcessed,
+		Modified:    stats.FilesModified,
+		Skipped:     stats.FilesSkipped,
+		Errored:     stats.FilesErrored,
+		Unsupported: stats.FilesUnsupported,
+		DirsSkipped: stats.DirsSkipped,
+		Licenses:    stats.LicenseCounts(),
+	})
+}
+
+func (l *jsonLogger) timestamp() string {
+	return l.now().UTC().Format(time.RFC3339Nano)
+}
+
+func (l *jsonLogger) write(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	l.w.Write(append(data, '\n'))
+}
@@ -12,36 +12,187 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/licer/licer/pkg/licer"
 )
 
 var (
-	gitFolder string
-	force     bool
-	remove    bool
-	hook      bool
-	preCommit bool
-	verbose   bool
-	help      bool
+	gitFolders            pathList
+	force                 bool
+	replaceThirdParty     bool
+	yes                   bool
+	remove                bool
+	hook                  bool
+	preCommit             bool
+	verbose               bool
+	summary               bool
+	help                  bool
+	jobs                  int
+	stdin                 bool
+	extHint               string
+	migrate               bool
+	report                bool
+	reportUnlicensed      bool
+	failOnThirdParty      bool
+	format                string
+	since                 string
+	listTypes             bool
+	showHeader            string
+	fixLicense            bool
+	normalize             bool
+	staged                bool
+	undo                  bool
+	gitDates              bool
+	diff                  bool
+	check                 bool
+	strict                bool
+	cache                 bool
+	readOnly              bool
+	replaceOlderThan      int
+	excludeExt            stringList
+	includeExt            stringList
+	ownerMatch            stringList
+	owner                 string
+	ownerOrgOnly          bool
+	role                  string
+	headerDir             string
+	spdxOnly              bool
+	templateName          string
+	headerWidth           int
+	noThirdPartyDetection bool
+	replaceOwner          pathList // not split on commas, which names may contain
+	updateLicenseYear     bool
+	noLicenseFile         bool
+	licenseFileOnly       bool
+	headerAfterLine       pathList // not split on commas, which regexes use
+	encoding              string
+	forceText             bool
+	logJSON               bool
+	sorted                bool
 )
 
+// stringList collects a repeatable flag; each value may itself be a
+// comma-separated list
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// pathList collects a repeatable path flag; unlike stringList it does not
+// split on commas, which are valid in paths
+type pathList []string
+
+func (l *pathList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *pathList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
 func init() {
-	flag.StringVar(&gitFolder, "git-folder", "", "Path to git repository (default: current directory)")
-	flag.BoolVar(&force, "force", false, "Force replacement of existing headers")
+	flag.Var(&gitFolders, "git-folder", "Path to git repository (default: the one the current directory is in, repeatable to process several)")
+	flag.BoolVar(&force, "force", false, "Force replacement of your own existing headers (third-party ones need --replace-third-party)")
+	flag.BoolVar(&force, "force-own", false, "Deprecated alias of --force")
+	flag.BoolVar(&replaceThirdParty, "replace-third-party", false, "Replace third-party headers and copyright notices with yours (only with permission!)")
+	flag.BoolVar(&yes, "yes", false, "Don't ask before the first run in a repository or before --force, --replace-third-party or --remove modify files")
 	flag.BoolVar(&remove, "remove", false, "Remove existing headers (requires SPDX-License-Identifier and ownership match)")
+	flag.BoolVar(&fixLicense, "fix-license", false, "Rewrite your own headers that declare the wrong license, keeping their year")
+	flag.Var(&replaceOwner, "replace-owner", "Rename an owner, organization or department in your own headers, as old=new, changing nothing else (repeatable)")
+	flag.BoolVar(&normalize, "normalize", false, "Re-render your own headers in the file type's comment style, keeping their text")
+	flag.StringVar(&role, "role", "", "Role for this run (Student, Faculty or Staff), overriding DEFAULT_ROLE and the repository's .licer.yml")
+	flag.StringVar(&owner, "owner", "", "Copyright owner for this run, overriding COPYRIGHT_OWNER and the role default")
+	flag.BoolVar(&ownerOrgOnly, "owner-org-only", false, "Name the organization as copyright owner for this run, keeping the role's license (OWNER_ORG_ONLY)")
+	flag.Var(&ownerMatch, "owner-match", "Extra name that marks a header as ours for --remove (repeatable, adds to OWNER_ALIASES)")
+	flag.IntVar(&replaceOlderThan, "replace-if-older-than", 0, "Replace your own headers dated before this year (all with --replace-third-party), leaving newer ones alone")
+	flag.BoolVar(&migrate, "migrate", false, "Rewrite legacy headers matching LEGACY_PATTERNS to the current template")
+	flag.BoolVar(&readOnly, "read-only", false, "Never write any file (headers, LICENSE, config, hook, cache), whatever the other flags; only report")
+	flag.BoolVar(&check, "check", false, "Write nothing; exit 3 if any file would be changed (e.g. a header is missing), 2 on invalid SPDX ids")
+	flag.BoolVar(&strict, "strict", false, "Exit 4 and list text files skipped for having no known comment style")
+	flag.BoolVar(&diff, "diff", false, "Print a unified diff of the changes a run would make, without writing files")
+	flag.BoolVar(&report, "report", false, "Print license header coverage by extension without modifying files")
+	flag.BoolVar(&reportUnlicensed, "report-unlicensed", false, "List the files that have no header, relative to the repository, without modifying files")
+	flag.BoolVar(&noThirdPartyDetection, "no-third-party-detection", false, "Don't take copyright notices without SPDX identifier for third-party headers (DETECT_THIRD_PARTY: false)")
+	flag.BoolVar(&failOnThirdParty, "fail-on-third-party", false, "List the files with a third-party copyright and exit 5 if there are any, without modifying files")
+	flag.BoolVar(&spdxOnly, "spdx-only", false, "Write headers of only the SPDX-License-Identifier line (HEADER_STYLE: spdx)")
+	flag.IntVar(&headerWidth, "header-width", 0, "Wrap the prose lines of headers to this many columns, comment markers included; never the SPDX line (HEADER_WIDTH, default unlimited)")
+	flag.StringVar(&templateName, "template-name", "", "Built-in header wording: minimal, standard, spdx-only or apache-full (HEADER_TEMPLATE)")
+	flag.StringVar(&encoding, "encoding", "utf-8", "Encoding of files without a UTF-16 byte order mark: utf-8 or latin1 (implies --force-text)")
+	flag.BoolVar(&forceText, "force-text", false, "Process extensionless files that look binary, e.g. legacy scripts with non-UTF-8 bytes")
+	flag.Var(&headerAfterLine, "header-after-line", "Regular expression for leading lines new headers go after, in every file type (repeatable, adds to HEADER_AFTER_LINES)")
+	flag.BoolVar(&noLicenseFile, "no-license-file", false, "Add headers only; leave the LICENSE and NOTICE files alone")
+	flag.BoolVar(&licenseFileOnly, "license-file-only", false, "Create or update only the LICENSE (and NOTICE) file; add no headers")
+	flag.BoolVar(&updateLicenseYear, "update-license-year", false, "Update the copyright year of a LICENSE licer wrote in an earlier year (UPDATE_LICENSE_YEAR)")
+	flag.StringVar(&headerDir, "header-dir", "", "Directory of header.<ext>.txt files whose text replaces the generated header for that file type")
+	flag.BoolVar(&gitDates, "git-dates", false, "Start the copyright year at each file's first commit, e.g. 2019-2025 (slower)")
+	flag.BoolVar(&cache, "cache", false, "Skip files unchanged since they last had a header (cache in .git/licer-cache.json)")
+	flag.StringVar(&since, "since", "", "Only process files changed since this git ref (e.g. main or a tag)")
+	flag.BoolVar(&listTypes, "list-types", false, "List supported and excluded file extensions and exit")
+	flag.StringVar(&showHeader, "show-header", "", "Print the header detected in this file, its classification and SPDX id, and exit")
+	flag.StringVar(&format, "format", "text", "Output format for --diff, --report, --report-unlicensed, --fail-on-third-party, --list-types and --show-header: text or json")
+	flag.BoolVar(&undo, "undo", false, "Revert the files modified by the last run with git checkout, unless edited since")
 	flag.BoolVar(&hook, "hook", false, "Install/uninstall Git pre-commit hook")
 	flag.BoolVar(&preCommit, "pre-commit", false, "Pre-commit mode: process only newly staged files")
+	flag.BoolVar(&staged, "staged", false, "Add headers to newly staged files and re-stage them, like the pre-commit hook")
 	flag.BoolVar(&verbose, "verbose", true, "Verbose output")
+	flag.BoolVar(&logJSON, "log-json", false, "Log one JSON object per file and summary to stderr instead of the text output")
+	flag.BoolVar(&sorted, "sorted", false, "Print the per-file results sorted by path when the run is done, instead of as files finish")
+	flag.BoolVar(&summary, "summary", false, "Only print the final summary and errors, not every file")
 	flag.BoolVar(&help, "help", false, "Show help message")
+	flag.BoolVar(&stdin, "stdin", false, "Read a file from stdin and write it with a header to stdout")
+	flag.StringVar(&extHint, "ext", "", "File extension used to pick the comment style in --stdin mode (e.g. .go)")
+	flag.Var(&excludeExt, "exclude-ext", "Skip files with this extension for this run (repeatable, e.g. .sql, adds to EXCLUDE_EXTENSIONS)")
+	flag.Var(&includeExt, "include-ext", "Process a text extension that is excluded by default, e.g. .md (repeatable, adds to INCLUDE_EXTENSIONS)")
+	flag.IntVar(&jobs, "jobs", runtime.NumCPU(), "Number of files processed concurrently")
 }
 
 func main() {
 	flag.Parse()
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "force-own" {
+			fmt.Fprintf(os.Stderr, "Warning: --force-own is deprecated, use --force\n")
+		}
+	})
 	
 	if help {
-		printUsage()
+		printUsage(os.Stdout)
+		return
+	}
+	
+	// --read-only turns licer into a pure analyzer, whatever else is given
+	if readOnly {
+		if hook || undo || staged || (flag.NArg() > 0 && flag.Arg(0) == "init") {
+			log.Fatalf("--read-only cannot be combined with --hook, --undo, --staged or init")
+		}
+		if force || replaceThirdParty || remove || migrate || fixLicense || normalize || len(replaceOwner) > 0 || replaceOlderThan > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: --read-only is set, no files will be written; changes are only reported\n")
+		}
+		licer.SetReadOnly(true)
+	}
+
+	// Create or update the config and exit (no git repository required)
+	if flag.NArg() > 0 && flag.Arg(0) == "init" {
+		if _, err := licer.InitConfig(); err != nil {
+			log.Fatalf("Failed to set up config: %v", err)
+		}
 		return
 	}
 
@@ -49,10 +200,160 @@ func main() {
 	if force && remove {
 		log.Fatalf("--force and --remove cannot be used together")
 	}
+	if replaceThirdParty && (remove || migrate || fixLicense || staged || report || reportUnlicensed || undo || cache || showHeader != "") {
+		log.Fatalf("--replace-third-party cannot be combined with --remove, --migrate, --fix-license, --staged, --report, --report-unlicensed, --undo, --cache or --show-header")
+	}
+	textEncoding, encErr := licer.ParseEncoding(encoding)
+	if encErr != nil {
+		log.Fatalf("Invalid --encoding: %v", encErr)
+	}
+	if textEncoding == licer.EncodingLatin1 && stdin {
+		log.Fatalf("--encoding latin1 cannot be combined with --stdin")
+	}
+	if migrate && (force || remove) {
+		log.Fatalf("--migrate cannot be used with --force or --remove")
+	}
+	if fixLicense && (force || remove || migrate) {
+		log.Fatalf("--fix-license cannot be used with --force, --remove or --migrate")
+	}
+	if normalize && (force || replaceThirdParty || remove || migrate || fixLicense || replaceOlderThan > 0 || staged || report || reportUnlicensed || undo || cache || showHeader != "") {
+		log.Fatalf("--normalize cannot be combined with --force, --replace-third-party, --remove, --migrate, --fix-license, --replace-if-older-than, --staged, --report, --report-unlicensed, --undo, --cache or --show-header")
+	}
+	if len(replaceOwner) > 0 && (force || replaceThirdParty || remove || migrate || fixLicense || normalize || replaceOlderThan > 0 || staged || report || reportUnlicensed || failOnThirdParty || undo || cache || licenseFileOnly || showHeader != "") {
+		log.Fatalf("--replace-owner cannot be combined with --force, --replace-third-party, --remove, --migrate, --fix-license, --normalize, --replace-if-older-than, --staged, --report, --report-unlicensed, --fail-on-third-party, --undo, --cache, --license-file-only or --show-header")
+	}
+	var ownerReplacements []licer.OwnerReplacement
+	for _, value := range replaceOwner {
+		replacement, err := licer.ParseOwnerReplacement(value)
+		if err != nil {
+			log.Fatalf("--replace-owner: %v", err)
+		}
+		ownerReplacements = append(ownerReplacements, replacement)
+	}
+	if staged && (force || remove || migrate || fixLicense || since != "") {
+		log.Fatalf("--staged cannot be combined with --force, --remove, --migrate, --fix-license or --since")
+	}
+	if report && (force || remove || migrate || fixLicense) {
+		log.Fatalf("--report cannot be used with --force, --remove, --migrate or --fix-license")
+	}
+	if reportUnlicensed && (force || remove || migrate || fixLicense || replaceOlderThan > 0 || report || staged || undo || diff || check || strict || cache || since != "") {
+		log.Fatalf("--report-unlicensed cannot be combined with --force, --remove, --migrate, --fix-license, --replace-if-older-than, --report, --staged, --undo, --diff, --check, --strict, --cache or --since")
+	}
+	if failOnThirdParty && (force || replaceThirdParty || remove || migrate || fixLicense || normalize || replaceOlderThan > 0 || report || reportUnlicensed || staged || undo || diff || check || strict || cache || since != "") {
+		log.Fatalf("--fail-on-third-party cannot be combined with --force, --replace-third-party, --remove, --migrate, --fix-license, --normalize, --replace-if-older-than, --report, --report-unlicensed, --staged, --undo, --diff, --check, --strict, --cache or --since")
+	}
+	if licenseFileOnly && (noLicenseFile || force || replaceThirdParty || remove || migrate || fixLicense || normalize || replaceOlderThan > 0 || staged || report || reportUnlicensed || failOnThirdParty || undo || diff || check || strict || cache || since != "") {
+		log.Fatalf("--license-file-only cannot be combined with --no-license-file, --force, --replace-third-party, --remove, --migrate, --fix-license, --normalize, --replace-if-older-than, --staged, --report, --report-unlicensed, --fail-on-third-party, --undo, --diff, --check, --strict, --cache or --since")
+	}
+	if showHeader != "" && (force || remove || migrate || fixLicense) {
+		log.Fatalf("--show-header cannot be used with --force, --remove, --migrate or --fix-license")
+	}
+	if undo && (force || remove || migrate || fixLicense || staged || report || since != "") {
+		log.Fatalf("--undo cannot be combined with --force, --remove, --migrate, --fix-license, --staged, --report or --since")
+	}
+	if (diff || check) && (staged || report || undo) {
+		log.Fatalf("--diff and --check cannot be combined with --staged, --report or --undo")
+	}
+	if strict && (staged || report || undo) {
+		log.Fatalf("--strict cannot be combined with --staged, --report or --undo")
+	}
+	if replaceOlderThan < 0 {
+		log.Fatalf("--replace-if-older-than must be a year")
+	}
+	if replaceOlderThan > 0 && (remove || migrate || fixLicense || staged || report || undo) {
+		log.Fatalf("--replace-if-older-than cannot be combined with --remove, --migrate, --fix-license, --staged, --report or --undo")
+	}
+	if cache && (force || remove || migrate || fixLicense || replaceOlderThan > 0 || staged || report || undo) {
+		log.Fatalf("--cache only applies to adding headers and cannot be combined with --force, --remove, --migrate, --fix-license, --replace-if-older-than, --staged, --report or --undo")
+	}
+	if len(gitFolders) > 1 && (hook || staged || report || reportUnlicensed || failOnThirdParty || undo || (diff && format == "json")) {
+		log.Fatalf("--git-folder can only be given once with --hook, --staged, --report, --report-unlicensed, --fail-on-third-party, --undo or --diff --format=json")
+	}
+	for _, pattern := range headerAfterLine {
+		if _, err := regexp.Compile(pattern); err != nil {
+			log.Fatalf("Invalid --header-after-line %q: %v", pattern, err)
+		}
+	}
+	if ownerOrgOnly && owner != "" {
+		log.Fatalf("--owner-org-only cannot be combined with --owner")
+	}
+	if headerDir != "" && (remove || report || reportUnlicensed || undo || showHeader != "") {
+		log.Fatalf("--header-dir cannot be combined with --remove, --report, --report-unlicensed, --undo or --show-header")
+	}
+	if err := licer.ValidateHeaderTemplate(templateName); err != nil {
+		log.Fatalf("--template-name: %v", err)
+	}
+	if err := licer.ValidateHeaderWidth(headerWidth); err != nil {
+		log.Fatalf("--header-width: %v", err)
+	}
+	if templateName != "" && spdxOnly {
+		log.Fatalf("--template-name cannot be combined with --spdx-only (use --template-name spdx-only)")
+	}
+	if role != "" {
+		if err := licer.ValidateRole(role); err != nil {
+			log.Fatalf("--role: %v", err)
+		}
+	}
+	if format != "text" && format != "json" {
+		log.Fatalf("--format must be text or json")
+	}
+	if jobs < 1 {
+		log.Fatalf("--jobs must be at least 1")
+	}
 	
+	// Summary mode replaces the per-file output with just the final stats.
+	// --log-json logs every file and the stats as JSON and nothing else.
+	if summary || logJSON {
+		verbose = false
+	}
+	if logJSON {
+		summary = true
+	}
+
+	gitFolder := ""
+	if len(gitFolders) == 1 {
+		gitFolder = gitFolders[0]
+	}
+
+	// Apply per-run extension overrides before any file is looked at
+	licer.SetExtensionOverrides(excludeExt, includeExt)
+	for _, ext := range includeExt {
+		if err := licer.ValidateIncludeExtension(ext); err != nil {
+			log.Fatalf("--include-ext: %v", err)
+		}
+		if _, ok := licer.CommentStyles[licer.NormalizeExtension(ext)]; !ok {
+			fmt.Fprintf(os.Stderr, "Warning: no comment style known for %s, those files will still be skipped\n", licer.NormalizeExtension(ext))
+		}
+	}
+
+	// List the file types this run would touch (no git repository required)
+	if listTypes {
+		handleListTypesMode(format)
+		return
+	}
+
+	// Preview a single file's header (no git repository required)
+	if showHeader != "" {
+		config, err := licer.LoadOrCreateConfig()
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		applyConfigOverrides(config)
+		handleShowHeaderMode(showHeader, config, format)
+		return
+	}
+
+	headerTemplates := loadHeaderTemplates()
+
+	// Handle stdin mode (no git repository required)
+	if stdin {
+		handleStdinMode(extHint, licer.ProcessOptions{ForceReplace: force, ReplaceThirdParty: replaceThirdParty, RemoveMode: remove, HeaderTemplates: headerTemplates, ForceText: forceText}, verbose)
+		return
+	}
+
 	// Handle hook management mode
 	if hook {
-		handleHookManagement(remove, verbose)
+		handleHookManagement(gitFolder, remove, verbose)
 		return
 	}
 	
@@ -62,100 +363,491 @@ func main() {
 		return
 	}
 
-	// Determine the git repository root
-	repoRoot := gitFolder
-	if repoRoot == "" {
-		var err error
-		repoRoot, err = os.Getwd()
+	// Determine the git repository root. Several --git-folder values are
+	// each validated when processed, so one bad path doesn't stop the rest.
+	var absRepoRoot string
+	var err error
+	if len(gitFolders) <= 1 {
+		absRepoRoot, err = resolveRepoRoot(gitFolder)
 		if err != nil {
-			log.Fatalf("Failed to get current directory: %v", err)
+			log.Fatalf("%v", err)
 		}
 	}
 
-	// Convert to absolute path
-	absRepoRoot, err := filepath.Abs(repoRoot)
-	if err != nil {
-		log.Fatalf("Failed to get absolute path: %v", err)
-	}
-
-	// Verify it's a git repository
-	gitDir := filepath.Join(absRepoRoot, ".git")
-	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
-		log.Fatalf("Not a git repository: %s", absRepoRoot)
+	// Undo only needs git and the manifest of the last run, not the config
+	if undo {
+		handleUndoMode(absRepoRoot, verbose)
+		return
 	}
 
 	if verbose {
-		fmt.Printf("Licer - License Header Management Tool\n")
-		fmt.Printf("Working in git repository: %s\n", absRepoRoot)
-		fmt.Printf("Force mode: %v\n", force)
-		fmt.Printf("Remove mode: %v\n", remove)
-		fmt.Printf("Verbose mode: %v\n", verbose)
-		fmt.Println()
+		fmt.Fprintf(os.Stderr, "Licer - License Header Management Tool\n")
+		if absRepoRoot != "" {
+			fmt.Fprintf(os.Stderr, "Working in git repository: %s\n", absRepoRoot)
+		} else {
+			fmt.Fprintf(os.Stderr, "Working in %d git repositories\n", len(gitFolders))
+		}
+		fmt.Fprintf(os.Stderr, "Force mode: %v\n", force)
+		fmt.Fprintf(os.Stderr, "Replace third-party: %v\n", replaceThirdParty)
+		fmt.Fprintf(os.Stderr, "Remove mode: %v\n", remove)
+		fmt.Fprintf(os.Stderr, "Migrate mode: %v\n", migrate)
+		fmt.Fprintf(os.Stderr, "Fix license mode: %v\n", fixLicense)
+		fmt.Fprintf(os.Stderr, "Normalize mode: %v\n", normalize)
+		fmt.Fprintf(os.Stderr, "Replace owner: %v\n", []string(replaceOwner))
+		fmt.Fprintf(os.Stderr, "Diff mode: %v\n", diff)
+		fmt.Fprintf(os.Stderr, "Check mode: %v\n", check)
+		fmt.Fprintf(os.Stderr, "Read-only: %v\n", readOnly)
+		fmt.Fprintf(os.Stderr, "Strict mode: %v\n", strict)
+		fmt.Fprintf(os.Stderr, "Cache: %v\n", cache)
+		fmt.Fprintf(os.Stderr, "Verbose mode: %v\n", verbose)
+		fmt.Fprintf(os.Stderr, "Jobs: %d\n", jobs)
+		fmt.Fprintln(os.Stderr)
 	}
 
 	// Load or create configuration
-	config, err := LoadOrCreateConfig()
+	config, err := licer.LoadOrCreateConfig()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	applyConfigOverrides(config)
+	if absRepoRoot != "" {
+		if config, err = repoConfigFor(config, absRepoRoot); err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+
 	if verbose {
-		fmt.Printf("Configuration:\n")
-		fmt.Printf("  Name: %s\n", config.FullName)
-		fmt.Printf("  Role: %s\n", config.DefaultRole)
-		fmt.Printf("  Department/Lab: %s\n", config.DeptOrLab)
-		fmt.Printf("  Organization: %s\n", config.Organization)
+		fmt.Fprintf(os.Stderr, "Configuration:\n")
+		fmt.Fprintf(os.Stderr, "  Name: %s\n", config.FullName)
+		fmt.Fprintf(os.Stderr, "  Role: %s\n", config.DefaultRole)
+		fmt.Fprintf(os.Stderr, "  Department/Lab: %s\n", config.DeptOrLab)
+		fmt.Fprintf(os.Stderr, "  Organization: %s\n", config.Organization)
 		
-		template := GetHeaderTemplate(config)
-		fmt.Printf("  License: %s\n", template.LicenseType)
-		fmt.Printf("  Copyright Owner: %s\n", template.CopyrightOwner)
-		fmt.Println()
+		template := licer.GetHeaderTemplate(config)
+		fmt.Fprintf(os.Stderr, "  License: %s\n", template.LicenseType)
+		fmt.Fprintf(os.Stderr, "  Copyright Owner: %s\n", template.CopyrightOwner)
+		fmt.Fprintln(os.Stderr)
 	}
 
-	// Check for hook installation prompt (only if no git-folder specified)
-	if gitFolder == "" && !isHookInstalled(absRepoRoot) {
+	// Staged mode runs the pre-commit logic by hand
+	if staged {
+		handleStagedMode(absRepoRoot, config, headerTemplates, verbose, summary)
+		return
+	}
+
+	// Report modes are read-only and writes only the report to stdout
+	if report {
+		handleReportMode(absRepoRoot, config, format)
+		return
+	}
+	if reportUnlicensed {
+		handleReportUnlicensedMode(absRepoRoot, config, format)
+		return
+	}
+	if failOnThirdParty {
+		handleFailOnThirdPartyMode(absRepoRoot, config, format)
+		return
+	}
+
+	// Check for hook installation prompt (only if no git-folder specified
+	// and the run writes files)
+	if len(gitFolders) == 0 && !diff && !check && !readOnly && !isHookInstalled(absRepoRoot) {
 		if promptForHookInstallation() {
 			if err := installPreCommitHook(absRepoRoot, verbose); err != nil {
-				fmt.Printf("Warning: Failed to install hook: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Warning: Failed to install hook: %v\n", err)
+			}
+		}
+	}
+
+	opts := licer.ProcessOptions{
+		ForceReplace:      force,
+		ReplaceThirdParty: replaceThirdParty,
+		RemoveMode:        remove,
+		Migrate:           migrate,
+		FixLicense:        fixLicense,
+		Normalize:         normalize,
+		ReplaceOwner:      ownerReplacements,
+		ValidateSPDX:      check,
+		ReplaceOlderThan:  replaceOlderThan,
+		HeaderTemplates:   headerTemplates,
+		ForceText:         forceText,
+		Encoding:          textEncoding,
+	}
+	if migrate {
+		if len(config.LegacyPatterns) == 0 {
+			log.Fatalf("--migrate requires LEGACY_PATTERNS in the config file")
+		}
+		opts.LegacyPatterns, err = licer.CompileLegacyPatterns(config.LegacyPatterns)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+
+	// Start crawling and processing; --since limits the run to changed files.
+	// With plan set, the run only counts the changes it would make.
+	var unsupported, skippedDirs []string
+	var plan *changePlan
+	var edits *editPlanner
+	run := func(repoRoot string) (*ProcessingStats, error) {
+		repoConfig, err := repoConfigFor(config, repoRoot)
+		if err != nil {
+			return nil, err
+		}
+		repoOpts := opts
+		if gitDates {
+			repoOpts.FirstYear = NewGitYears(repoRoot).FirstYear
+		}
+		if diff && format == "json" {
+			edits = newEditPlanner(repoRoot)
+			repoOpts.Preview = edits.preview
+		} else if diff {
+			repoOpts.Preview = diffPreview(os.Stdout, repoRoot, isTerminal(os.Stdout))
+		} else if check || readOnly || plan != nil {
+			repoOpts.Preview = func(string, []byte, []byte) {} // Only count the changes
+		}
+		crawler := NewCrawler(repoConfig, repoOpts, verbose && plan == nil, summary && plan == nil, jobs)
+		if plan != nil {
+			crawler.logger = textLogger{} // Quiet, even with --log-json
+			crawler.plan = plan
+		}
+		crawler.edits = edits
+		crawler.sortResults = sorted
+		crawler.skipLicenseFile = noLicenseFile
+		crawler.licenseFileOnly = licenseFileOnly
+		if cache && plan == nil && !readOnly {
+			crawler.cache = loadResultCache(repoRoot, cacheConfigHash(repoConfig, excludeExt, includeExt))
+		}
+		if since == "" {
+			err = crawler.ProcessRepository(repoRoot)
+		} else {
+			var files []string
+			files, err = getChangedFilesSince(repoRoot, since)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list changed files: %w", err)
+			}
+			err = crawler.ProcessFiles(repoRoot, files)
+		}
+
+		// Record what changed so --undo can revert this run. A run that
+		// changed nothing keeps the previous record, so --undo still reverts
+		// the last run that did.
+		if repoOpts.Preview == nil {
+			if modified := crawler.ModifiedFiles(); len(modified) > 0 {
+				if err := writeRunManifest(repoRoot, modified); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to record modified files for --undo: %v\n", err)
+				}
+			}
+			if err := writeFirstRunMarker(repoRoot); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to mark the repository as initialized: %v\n", err)
+			}
+		}
+		if crawler.cache != nil && err == nil {
+			if err := crawler.cache.Save(since == ""); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save the cache: %v\n", err)
 			}
 		}
+		unsupported = append(unsupported, crawler.UnsupportedFiles()...)
+		skippedDirs = append(skippedDirs, crawler.SkippedDirs()...)
+		return crawler.stats, err
 	}
 
-	// Start crawling and processing
-	crawler := NewCrawler(config, force, remove, verbose)
-	if err := crawler.ProcessRepository(absRepoRoot); err != nil {
+	// Destructive runs show what they would change and ask first
+	if needsConfirmation() {
+		if !isTerminal(os.Stdin) {
+			log.Fatalf("--force, --replace-third-party and --remove ask for confirmation; pass --yes when not running in a terminal")
+		}
+		plan = &changePlan{}
+		if len(gitFolders) > 1 {
+			processRepositories(gitFolders, run, false)
+		} else if _, err := run(absRepoRoot); err != nil {
+			log.Fatalf("Failed to process repository: %v", err)
+		}
+		if plan.Files > 0 && !confirmChanges(os.Stdin, os.Stderr, plan) {
+			log.Fatalf("Aborted, no files were changed")
+		}
+		plan = nil
+		unsupported, skippedDirs = nil, nil
+	}
+
+	// The first run in a repository lists what it would change and asks, in
+	// case licer was pointed at the wrong directory
+	if needsFirstRunConfirmation() && isTerminal(os.Stdin) {
+		if repos := firstRunRepos([]string{absRepoRoot}); len(repos) > 0 {
+			plan = &changePlan{}
+			scan := func(repoRoot string) error {
+				_, err := run(repoRoot)
+				return err
+			}
+			if !confirmFirstRun(os.Stdin, os.Stderr, repos, plan, scan) {
+				log.Fatalf("Aborted, no files were changed")
+			}
+			plan = nil
+			unsupported, skippedDirs = nil, nil
+		}
+	}
+
+	var stats *ProcessingStats
+	if len(gitFolders) > 1 {
+		var failed int
+		stats, failed = processRepositories(gitFolders, run, verbose || summary)
+		if failed > 0 {
+			log.Fatalf("%d of %d repositories failed", failed, len(gitFolders))
+		}
+	} else if stats, err = run(absRepoRoot); err != nil {
 		log.Fatalf("Failed to process repository: %v", err)
 	}
 
-	if verbose {
-		fmt.Println("Processing completed successfully!")
+	if edits != nil {
+		if err := writeEditPlanJSON(os.Stdout, edits.Plan()); err != nil {
+			log.Fatalf("Failed to write the edit plan: %v", err)
+		}
+	}
+	if strict && len(unsupported) > 0 {
+		printUnsupportedFiles(unsupported)
+	}
+	if len(skippedDirs) > 0 && !logJSON {
+		printSkippedDirs(skippedDirs)
+	}
+
+	code := exitCode(stats, check, strict)
+	if verbose && code == exitOK {
+		fmt.Fprintln(os.Stderr, "Processing completed successfully!")
+	}
+	os.Exit(code)
+}
+
+// Exit codes. Usage and setup errors exit through log.Fatalf with
+// exitSetupError.
+const (
+	exitOK              = 0
+	exitSetupError      = 1
+	exitFileErrors      = 2 // one or more files (with --check or --strict, directories) could not be processed
+	exitCheckFailed     = 3 // --check: one or more files would be changed
+	exitUnsupported     = 4 // --strict: text files with no known comment style
+	exitThirdPartyFound = 5 // --fail-on-third-party: files carry another's copyright
+)
+
+// exitCode maps the stats of a processing run to the exit code
+func exitCode(stats *ProcessingStats, check, strict bool) int {
+	switch {
+	case stats.FilesErrored > 0:
+		return exitFileErrors
+	case (check || strict) && stats.DirsSkipped > 0:
+		return exitFileErrors // coverage is incomplete
+	case check && stats.FilesModified > 0:
+		return exitCheckFailed
+	case strict && stats.FilesUnsupported > 0:
+		return exitUnsupported
+	default:
+		return exitOK
+	}
+}
+
+// applyConfigOverrides applies the flags that change the loaded config for
+// this run only: --role, --owner, --owner-org-only, --owner-match,
+// --spdx-only, --template-name, --update-license-year, --header-width and
+// --no-third-party-detection
+func applyConfigOverrides(config *licer.Config) {
+	if role != "" {
+		config.DefaultRole = role
+	}
+	if owner = strings.TrimSpace(owner); owner != "" {
+		config.CopyrightOwner = owner
+	}
+	if ownerOrgOnly {
+		config.OwnerOrgOnly = true
+		config.CopyrightOwner = ""
+	}
+	config.OwnerAliases = append(config.OwnerAliases, ownerMatch...)
+	*config = *licer.WithHeaderAfterLines(config, headerAfterLine)
+	// HEADER_STYLE: spdx-copyright is already SPDX-only plus its copyright line
+	if spdxOnly && config.HeaderStyle != licer.HeaderStyleSPDXCopyright {
+		config.HeaderStyle = licer.HeaderStyleSPDX
+	}
+	if templateName != "" {
+		config.HeaderTemplate = templateName
+	}
+	if updateLicenseYear {
+		config.UpdateLicenseYear = true
+	}
+	if headerWidth > 0 {
+		config.HeaderWidth = headerWidth
+	}
+	if noThirdPartyDetection {
+		detect := false
+		config.DetectThirdParty = &detect
+	}
+}
+
+// loadHeaderTemplates reads the header files of --header-dir, if given
+func loadHeaderTemplates() map[string]string {
+	if headerDir == "" {
+		return nil
+	}
+	templates, err := licer.LoadHeaderTemplates(headerDir)
+	if err != nil {
+		log.Fatalf("--header-dir: %v", err)
+	}
+	return templates
+}
+
+// repoConfigFor returns config with the .licer.yml of repoRoot, or of a
+// directory below it, applied. --role, --owner and --owner-org-only still
+// win over it.
+func repoConfigFor(config *licer.Config, repoRoot string) (*licer.Config, error) {
+	repoConfig, err := licer.WithRepoConfig(config, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	if role != "" && repoConfig.DefaultRole != role {
+		overridden := *repoConfig
+		overridden.DefaultRole = role
+		repoConfig = &overridden
+	}
+	if owner != "" && repoConfig.CopyrightOwner != owner {
+		overridden := *repoConfig
+		overridden.CopyrightOwner = owner
+		repoConfig = &overridden
+	}
+	if ownerOrgOnly && repoConfig.CopyrightOwner != "" {
+		overridden := *repoConfig
+		overridden.CopyrightOwner = ""
+		repoConfig = &overridden
+	}
+	if noThirdPartyDetection && repoConfig.DetectsThirdParty() {
+		overridden := *repoConfig
+		overridden.DetectThirdParty = config.DetectThirdParty
+		repoConfig = &overridden
+	}
+	return repoConfig, nil
+}
+
+// resolveRepoRoot returns the absolute path of gitFolder and checks that it
+// is a git repository. Without gitFolder it returns the repository the
+// current directory is in, walking up to its root like git does.
+func resolveRepoRoot(gitFolder string) (string, error) {
+	repoRoot := gitFolder
+	if repoRoot == "" {
+		var err error
+		repoRoot, err = os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get current directory: %w", err)
+		}
+	}
+
+	// Convert to absolute path
+	absRepoRoot, err := filepath.Abs(repoRoot)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	if gitFolder == "" {
+		if root, ok := findRepoRoot(absRepoRoot); ok {
+			return root, nil
+		}
+		return "", fmt.Errorf("not a git repository (or any of the parent directories): %s", absRepoRoot)
+	}
+
+	// Verify it's a git repository
+	gitDir := filepath.Join(absRepoRoot, ".git")
+	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
+		return "", fmt.Errorf("not a git repository: %s", absRepoRoot)
+	}
+
+	return absRepoRoot, nil
+}
+
+// findRepoRoot returns the nearest of dir and its parents that has a .git
+// directory, or file for worktrees and submodules
+func findRepoRoot(dir string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
 	}
 }
 
-func printUsage() {
-	fmt.Println("Licer - License Header Management Tool")
-	fmt.Println()
-	fmt.Println("Usage:")
-	fmt.Println("  licer [flags]")
-	fmt.Println()
-	fmt.Println("Flags:")
+// printUsage writes the help text, including every registered flag, to w
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Licer - License Header Management Tool")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  licer [flags]")
+	fmt.Fprintln(w, "  licer init                           # Create or update the config, then exit")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	flag.CommandLine.SetOutput(w)
 	flag.PrintDefaults()
-	fmt.Println()
-	fmt.Println("Description:")
-	fmt.Println("  Licer recursively crawls a git repository and adds copyright headers")
-	fmt.Println("  to source files based on your role configuration.")
-	fmt.Println()
-	fmt.Println("  On first run, you'll be prompted to create a configuration file at")
-	fmt.Println("  ~/.config/licer.yml with your name, role, department, and organization.")
-	fmt.Println()
-	fmt.Println("  Students get MIT license headers, Faculty/Staff get Apache 2.0 headers.")
-	fmt.Println()
-	fmt.Println("Examples:")
-	fmt.Println("  licer                                # Process current git repository")
-	fmt.Println("  licer --git-folder /path/to/repo     # Process specific repository")
-	fmt.Println("  licer --force                        # Replace existing headers")
-	fmt.Println("  licer --remove                       # Remove existing headers (safe mode)")
-	fmt.Println("  licer --hook                         # Install Git pre-commit hook")
-	fmt.Println("  licer --hook --remove                # Uninstall pre-commit hook")
-	fmt.Println("  licer --verbose=false                # Quiet mode")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Description:")
+	fmt.Fprintln(w, "  Licer recursively crawls a git repository and adds copyright headers")
+	fmt.Fprintln(w, "  to source files based on your role configuration.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  On first run, you'll be prompted to create a configuration file at")
+	fmt.Fprintln(w, "  ~/.config/licer.yml with your name, role, department, and organization.")
+	fmt.Fprintln(w, "  Run licer init to change them later, e.g. to switch roles.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  Students get MIT license headers, Faculty/Staff get Apache 2.0 headers.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  licer                                # Process current git repository")
+	fmt.Fprintln(w, "  licer --git-folder /path/to/repo     # Process specific repository")
+	fmt.Fprintln(w, "  licer --git-folder a --git-folder b  # Process several repositories")
+	fmt.Fprintln(w, "  licer --check                        # Exit 3 if any file lacks a header")
+	fmt.Fprintln(w, "  licer --read-only                    # Report only; no flag can make it write")
+	fmt.Fprintln(w, "  licer --strict                       # Exit 4 on files with no comment style")
+	fmt.Fprintln(w, "  licer --cache                        # Skip files unchanged since the last run")
+	fmt.Fprintln(w, "  licer --diff                         # Show the changes as a diff, write nothing")
+	fmt.Fprintln(w, "  licer --diff --format=json           # Plan of the edits for review tooling")
+	fmt.Fprintln(w, "  licer --force                        # Replace your existing headers")
+	fmt.Fprintln(w, "  licer --force --replace-third-party  # Also replace third-party notices")
+	fmt.Fprintln(w, "  licer --force --yes                  # Replace without asking (scripts, CI)")
+	fmt.Fprintln(w, "  licer --replace-if-older-than 2022   # Refresh your headers dated before 2022")
+	fmt.Fprintln(w, "  licer --remove                       # Remove existing headers (safe mode)")
+	fmt.Fprintln(w, "  licer --role Student                 # Headers and license of another role")
+	fmt.Fprintln(w, "  licer --owner \"Jane Doe\"             # Name another copyright owner for this run")
+	fmt.Fprintln(w, "  licer --owner-org-only               # Copyright to the organization, role's license")
+	fmt.Fprintln(w, "  licer --remove --owner-match \"J Doe\" # Also remove headers under another name")
+	fmt.Fprintln(w, "  licer --undo                         # Revert the files changed by the last run")
+	fmt.Fprintln(w, "  licer --migrate                      # Rewrite legacy headers (LEGACY_PATTERNS)")
+	fmt.Fprintln(w, "  licer --fix-license                  # Correct the license in your own headers")
+	fmt.Fprintln(w, "  licer --normalize                    # Tidy the formatting of your own headers")
+	fmt.Fprintln(w, "  licer --replace-owner OLD=NEW        # Rename an owner or department in your headers")
+	fmt.Fprintln(w, "  licer --staged                       # Only newly staged files, then re-stage")
+	fmt.Fprintln(w, "  licer --spdx-only                    # Headers of just the SPDX identifier line")
+	fmt.Fprintln(w, "  licer --template-name apache-full    # Full Apache boilerplate with NOTICE pointer")
+	fmt.Fprintln(w, "  licer --header-width 80              # Wrap long owner or org names in headers")
+	fmt.Fprintln(w, "  licer --no-license-file              # Headers only, leave LICENSE alone")
+	fmt.Fprintln(w, "  licer --license-file-only            # LICENSE (and NOTICE) only, no headers")
+	fmt.Fprintln(w, "  licer --update-license-year          # Bump the year of an older licer LICENSE")
+	fmt.Fprintln(w, "  licer --header-dir legal/headers     # Use header.<ext>.txt files as header text")
+	fmt.Fprintln(w, "  licer --header-after-line '^set -e'  # Keep matching leading lines above headers")
+	fmt.Fprintln(w, "  licer --encoding latin1              # Read and write files as Latin-1")
+	fmt.Fprintln(w, "  licer --force-text                   # Don't skip extensionless files that look binary")
+	fmt.Fprintln(w, "  licer --git-dates                    # Copyright years from each file's first commit")
+	fmt.Fprintln(w, "  licer --since main                   # Only files changed since main")
+	fmt.Fprintln(w, "  licer --report                       # Show header coverage, change nothing")
+	fmt.Fprintln(w, "  licer --report --format=json         # Coverage report as JSON")
+	fmt.Fprintln(w, "  licer --report-unlicensed            # List the files that still need a header")
+	fmt.Fprintln(w, "  licer --fail-on-third-party          # Audit for vendored or copy-pasted code")
+	fmt.Fprintln(w, "  licer --no-third-party-detection     # Greenfield repo: no third-party heuristics")
+	fmt.Fprintln(w, "  licer --show-header main.go          # Show the header licer detects in a file")
+	fmt.Fprintln(w, "  licer --list-types                   # Show which extensions get headers")
+	fmt.Fprintln(w, "  licer --hook                         # Install Git pre-commit hook")
+	fmt.Fprintln(w, "  licer --hook --remove                # Uninstall pre-commit hook")
+	fmt.Fprintln(w, "  licer --hook --git-folder /path      # Install the hook into another repository")
+	fmt.Fprintln(w, "  licer --stdin --ext .go < main.go    # Add a header to stdin, write to stdout")
+	fmt.Fprintln(w, "  licer --exclude-ext .sql             # Skip SQL files for this run")
+	fmt.Fprintln(w, "  licer --include-ext .md              # Also license Markdown files")
+	fmt.Fprintln(w, "  licer --jobs 2                       # Limit concurrency on slow storage")
+	fmt.Fprintln(w, "  licer --log-json 2>licer.log         # One JSON object per file for log ingestion")
+	fmt.Fprintln(w, "  licer --sorted --check 2>run.log     # Results in path order, to diff two runs")
+	fmt.Fprintln(w, "  licer --summary                      # Only print the summary and errors")
+	fmt.Fprintln(w, "  licer --verbose=false                # Quiet mode")
 }
\ No newline at end of file
@@ -0,0 +1,307 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/licer/licer/pkg/licer"
+)
+
+// CoverageCounts classifies processable files by the header they carry
+type CoverageCounts struct {
+	Total      int `json:"total"`
+	OurHeader  int `json:"our_header"`
+	ThirdParty int `json:"third_party"`
+	NoHeader   int `json:"no_header"`
+}
+
+// CoverageReport is the result of a read-only --report walk
+type CoverageReport struct {
+	Root       string                     `json:"root"`
+	Totals     CoverageCounts             `json:"totals"`
+	Extensions map[string]*CoverageCounts `json:"extensions"`
+	Licenses   map[string]int             `json:"licenses"`
+
+	unlicensed []string         // files without any header, for --report-unlicensed
+	thirdParty []ThirdPartyFile // files with another's copyright, for --fail-on-third-party
+}
+
+// UnlicensedReport lists the processable files that carry neither our
+// header nor a third-party one
+type UnlicensedReport struct {
+	Root  string   `json:"root"`
+	Files []string `json:"files"` // relative to Root, with forward slashes
+}
+
+// ThirdPartyReport lists the processable files whose header names a
+// copyright holder that is not ours, e.g. vendored or copy-pasted code
+type ThirdPartyReport struct {
+	Root  string           `json:"root"`
+	Files []ThirdPartyFile `json:"files"`
+}
+
+// ThirdPartyFile is a file of a ThirdPartyReport with its copyright line
+type ThirdPartyFile struct {
+	Path      string `json:"path"` // relative to Root, with forward slashes
+	Copyright string `json:"copyright"`
+}
+
+// noExtensionKey groups files without an extension in the report
+const noExtensionKey = "(none)"
+
+// handleReportMode prints the header coverage of the repository to stdout
+// without modifying any file
+func handleReportMode(repoRoot string, config *licer.Config, format string) {
+	report, err := BuildCoverageReport(repoRoot, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building report: %v\n", err)
+		os.Exit(1)
+	}
+
+	if format == "json" {
+		err = writeReportJSON(os.Stdout, report)
+	} else {
+		err = writeReportTable(os.Stdout, report)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// handleReportUnlicensedMode prints the repository-relative paths of the
+// files that lack a header to stdout, one per line, without modifying any
+// file
+func handleReportUnlicensedMode(repoRoot string, config *licer.Config, format string) {
+	report, err := BuildUnlicensedReport(repoRoot, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building report: %v\n", err)
+		os.Exit(1)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		var b strings.Builder
+		for _, file := range report.Files {
+			fmt.Fprintln(&b, file)
+		}
+		_, err = io.WriteString(os.Stdout, b.String())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// BuildUnlicensedReport walks repoRoot like BuildCoverageReport and returns
+// the files it counts as having no header, sorted
+func BuildUnlicensedReport(repoRoot string, config *licer.Config) (*UnlicensedReport, error) {
+	coverage, err := BuildCoverageReport(repoRoot, config)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UnlicensedReport{Root: repoRoot, Files: []string{}}
+	for _, path := range coverage.unlicensed {
+		report.Files = append(report.Files, relativeSlashPath(repoRoot, path))
+	}
+	sort.Strings(report.Files)
+	return report, nil
+}
+
+// handleFailOnThirdPartyMode prints the files whose header carries a
+// copyright that is not ours, with that copyright line, and exits with
+// exitThirdPartyFound when there are any. Nothing is modified.
+func handleFailOnThirdPartyMode(repoRoot string, config *licer.Config, format string) {
+	report, err := BuildThirdPartyReport(repoRoot, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building report: %v\n", err)
+		os.Exit(1)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		var b strings.Builder
+		for _, file := range report.Files {
+			fmt.Fprintf(&b, "%s: %s\n", file.Path, file.Copyright)
+		}
+		_, err = io.WriteString(os.Stdout, b.String())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(report.Files) > 0 {
+		fmt.Fprintf(os.Stderr, "%d file(s) carry a third-party copyright\n", len(report.Files))
+		os.Exit(exitThirdPartyFound)
+	}
+}
+
+// BuildThirdPartyReport walks repoRoot like BuildCoverageReport and returns
+// the files it counts as third-party that name a copyright holder, sorted.
+// Headers without one, like a bare SPDX line, are not reported.
+func BuildThirdPartyReport(repoRoot string, config *licer.Config) (*ThirdPartyReport, error) {
+	coverage, err := BuildCoverageReport(repoRoot, config)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ThirdPartyReport{Root: repoRoot, Files: []ThirdPartyFile{}}
+	for _, file := range coverage.thirdParty {
+		file.Path = relativeSlashPath(repoRoot, file.Path)
+		report.Files = append(report.Files, file)
+	}
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].Path < report.Files[j].Path
+	})
+	return report, nil
+}
+
+// relativeSlashPath returns path relative to root with forward slashes
+func relativeSlashPath(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// BuildCoverageReport walks repoRoot and counts processable files that have
+// our header, a third-party copyright or SPDX header, or no header at all
+func BuildCoverageReport(repoRoot string, config *licer.Config) (*CoverageReport, error) {
+	report := &CoverageReport{
+		Root:       repoRoot,
+		Extensions: make(map[string]*CoverageCounts),
+		Licenses:   make(map[string]int),
+	}
+
+	err := filepath.WalkDir(repoRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable entries are skipped, as in the crawler
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		content, _ = licer.DecodeText(content)
+		if !licer.ShouldProcessContentForConfig(path, content, config) {
+			return nil
+		}
+		report.add(path, content, config)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (r *CoverageReport) add(filename string, content []byte, config *licer.Config) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" {
+		ext = noExtensionKey
+	}
+	counts, ok := r.Extensions[ext]
+	if !ok {
+		counts = &CoverageCounts{}
+		r.Extensions[ext] = counts
+	}
+
+	headerInfo := licer.DetectHeaderForConfig(filename, content, config)
+	class := classifyHeader(content, headerInfo, config)
+	for _, c := range []*CoverageCounts{&r.Totals, counts} {
+		c.Total++
+		switch class {
+		case headerOurs:
+			c.OurHeader++
+		case headerThirdParty:
+			c.ThirdParty++
+		default:
+			c.NoHeader++
+		}
+	}
+	if class == headerNone {
+		r.unlicensed = append(r.unlicensed, filename)
+	}
+	if class == headerThirdParty {
+		if copyright := licer.HeaderCopyrightLine(content, headerInfo); copyright != "" {
+			r.thirdParty = append(r.thirdParty, ThirdPartyFile{Path: filename, Copyright: copyright})
+		}
+	}
+
+	if headerInfo.LicenseID != "" {
+		r.Licenses[headerInfo.LicenseID]++
+	}
+}
+
+func writeReportJSON(w io.Writer, report *CoverageReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func writeReportTable(w io.Writer, report *CoverageReport) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "License header coverage for %s\n\n", report.Root)
+	fmt.Fprintf(&b, "%-12s %8s %8s %12s %8s\n", "Extension", "Files", "Ours", "Third-party", "None")
+
+	exts := make([]string, 0, len(report.Extensions))
+	for ext := range report.Extensions {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+
+	for _, ext := range exts {
+		c := report.Extensions[ext]
+		fmt.Fprintf(&b, "%-12s %8d %8d %12d %8d\n", ext, c.Total, c.OurHeader, c.ThirdParty, c.NoHeader)
+	}
+	t := report.Totals
+	fmt.Fprintf(&b, "%-12s %8d %8d %12d %8d\n", "Total", t.Total, t.OurHeader, t.ThirdParty, t.NoHeader)
+
+	if len(report.Licenses) > 0 {
+		ids := make([]string, 0, len(report.Licenses))
+		for id := range report.Licenses {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		fmt.Fprintf(&b, "\nLicenses:\n")
+		for _, id := range ids {
+			fmt.Fprintf(&b, "  %-20s %d\n", id, report.Licenses[id])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/licer/licer/pkg/licer"
+)
+
+// Header classifications shared by --show-header and --report
+const (
+	headerOurs       = "ours"
+	headerThirdParty = "third-party"
+	headerNone       = "none"
+)
+
+// HeaderPreview is the detector's view of one file's header. StartLine and
+// EndLine are 1-based and zero when no header block was found.
+type HeaderPreview struct {
+	File           string   `json:"file"`
+	Classification string   `json:"classification"`
+	LicenseID      string   `json:"license_id,omitempty"`
+	Format         string   `json:"format,omitempty"` // tool format recognized without an SPDX identifier
+	StartLine      int      `json:"start_line"`
+	EndLine        int      `json:"end_line"`
+	Lines          []string `json:"lines"`
+}
+
+// handleShowHeaderMode prints the header detected in filename to stdout
+// without modifying it
+func handleShowHeaderMode(filename string, config *licer.Config, format string) {
+	preview, err := PreviewHeader(filename, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading header: %v\n", err)
+		os.Exit(1)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(preview)
+	} else {
+		err = writeHeaderPreview(os.Stdout, preview)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing header: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// PreviewHeader runs header detection on the start of filename, as
+// DetectExistingHeader does, and returns the lines it bounded together with
+// their classification
+func PreviewHeader(filename string, config *licer.Config) (*HeaderPreview, error) {
+	prefix, err := licer.ReadFileForProcessing(filename, func([]byte) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	prefix, _ = licer.DecodeText(prefix)
+
+	headerInfo := licer.DetectHeaderForConfig(filename, prefix, config)
+	preview := &HeaderPreview{
+		File:           filename,
+		Classification: classifyHeader(prefix, headerInfo, config),
+		LicenseID:      headerInfo.LicenseID,
+		Format:         headerInfo.Format,
+		Lines:          []string{},
+	}
+
+	_, body := licer.SplitBOM(prefix)
+	lines := licer.SplitLines(body)
+	start, end := headerInfo.StartLine, headerInfo.EndLine
+	if start >= 0 && end >= start && start < len(lines) {
+		if end >= len(lines) {
+			end = len(lines) - 1
+		}
+		preview.StartLine = start + 1
+		preview.EndLine = end + 1
+		preview.Lines = lines[start : end+1]
+	}
+
+	return preview, nil
+}
+
+// classifyHeader reports whether content carries our header, a third-party
+// header or copyright notice, or neither
+func classifyHeader(content []byte, headerInfo licer.HeaderInfo, config *licer.Config) string {
+	switch {
+	case headerInfo.HasHeader && licer.CanRemoveHeaderContent(content, headerInfo, config):
+		return headerOurs
+	case headerInfo.HasHeader || headerInfo.HasThirdPartyCopyright:
+		return headerThirdParty
+	default:
+		return headerNone
+	}
+}
+
+func writeHeaderPreview(w io.Writer, preview *HeaderPreview) error {
+	var b strings.Builder
+
+	license := preview.LicenseID
+	if license == "" {
+		license = "(none)"
+	}
+	fmt.Fprintf(&b, "File:           %s\n", preview.File)
+	fmt.Fprintf(&b, "Classification: %s\n", preview.Classification)
+	fmt.Fprintf(&b, "SPDX id:        %s\n", license)
+	if preview.Format != "" {
+		fmt.Fprintf(&b, "Format:         %s\n", preview.Format)
+	}
+
+	if len(preview.Lines) == 0 {
+		fmt.Fprintf(&b, "Header lines:   (none)\n")
+	} else {
+		fmt.Fprintf(&b, "Header lines:   %d-%d\n", preview.StartLine, preview.EndLine)
+		for i, line := range preview.Lines {
+			fmt.Fprintf(&b, "%5d  %s\n", preview.StartLine+i, line)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,39 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// getChangedFilesSince returns the files that differ between ref and the
+// working tree, relative to repoRoot. Deleted files are left out and the
+// NUL-separated output keeps paths with spaces or quotes intact.
+func getChangedFilesSince(repoRoot, ref string) ([]string, error) {
+	cmd := exec.Command("git", "-C", repoRoot, "diff", "--name-only", "-z", "--diff-filter=d", ref, "--")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("git diff %s failed: %s", ref, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("git diff %s failed: %w", ref, err)
+	}
+
+	var files []string
+	for _, name := range strings.Split(string(output), "\x00") {
+		if name != "" {
+			files = append(files, name)
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/licer/licer/pkg/licer"
+)
+
+// handleStdinMode reads a single file from stdin, adds a header using the
+// comment style for extHint (or removes it with --remove) and writes the result to stdout. No git
+// repository is required and nothing is written to disk.
+func handleStdinMode(extHint string, opts licer.ProcessOptions, verbose bool) {
+	config, err := licer.LoadExistingConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	applyConfigOverrides(config)
+
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+		os.Exit(1)
+	}
+
+	output, result := processStdin(content, extHint, config, opts)
+
+	if _, err := os.Stdout.Write(output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing stdout: %v\n", err)
+		os.Exit(1)
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "[%s] <stdin> - %s\n", result.Action, result.Reason)
+	}
+}
+
+// processStdin returns the content to emit for stdin mode: the modified
+// content, or the original content unchanged when the file is skipped.
+func processStdin(content []byte, extHint string, config *licer.Config, opts licer.ProcessOptions) ([]byte, licer.ProcessResult) {
+	filename := "stdin" + licer.NormalizeExtension(extHint)
+
+	newContent, result := licer.ProcessContent(filename, content, config, opts)
+	if !result.Modified {
+		return content, result
+	}
+	return newContent, result
+}
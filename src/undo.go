@@ -0,0 +1,183 @@
+// Copyright 2025 Oregon State University
+//
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file for details.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Developed by: Dirk Petersen
+//               UIT/ARCS
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/licer/licer/pkg/licer"
+)
+
+// lastRunManifestName is the file in .git that records what the last run
+// modified, so --undo can put it back
+const lastRunManifestName = "licer-last-run.json"
+
+// ModifiedFile is one file a run rewrote. The checksums tell --undo whether
+// the file still holds exactly what licer wrote and whether git's index
+// holds exactly what was there before.
+type ModifiedFile struct {
+	Path           string `json:"path"`
+	OriginalSHA256 string `json:"original_sha256"`
+	WrittenSHA256  string `json:"written_sha256"`
+}
+
+// RunManifest lists the files modified by the last run, relative to the
+// repository root
+type RunManifest struct {
+	Time  time.Time      `json:"time"`
+	Files []ModifiedFile `json:"files"`
+}
+
+// UndoResult reports what --undo restored and what it left alone, and why
+type UndoResult struct {
+	Restored []string
+	Kept     map[string]string
+}
+
+func manifestPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".git", lastRunManifestName)
+}
+
+// writeRunManifest replaces the manifest with the files of this run. Paths
+// in files are absolute and stored relative to repoRoot.
+func writeRunManifest(repoRoot string, files []ModifiedFile) error {
+	manifest := RunManifest{Time: time.Now().UTC(), Files: []ModifiedFile{}}
+	for _, file := range files {
+		rel, err := filepath.Rel(repoRoot, file.Path)
+		if err != nil {
+			return fmt.Errorf("failed to make %s relative: %w", file.Path, err)
+		}
+		file.Path = filepath.ToSlash(rel)
+		manifest.Files = append(manifest.Files, file)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath(repoRoot), append(data, '\n'), 0644)
+}
+
+func readRunManifest(repoRoot string) (*RunManifest, error) {
+	data, err := os.ReadFile(manifestPath(repoRoot))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no previous run recorded in %s", manifestPath(repoRoot))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest RunManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", manifestPath(repoRoot), err)
+	}
+	return &manifest, nil
+}
+
+// handleUndoMode reverts the files modified by the last run and prints what
+// it did to stderr
+func handleUndoMode(repoRoot string, verbose bool) {
+	result, err := UndoLastRun(repoRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error undoing last run: %v\n", err)
+		os.Exit(1)
+	}
+
+	kept := make([]string, 0, len(result.Kept))
+	for path := range result.Kept {
+		kept = append(kept, path)
+	}
+	sort.Strings(kept)
+
+	if verbose {
+		for _, path := range result.Restored {
+			fmt.Fprintf(os.Stderr, "[RESTORE] %s\n", path)
+		}
+	}
+	for _, path := range kept {
+		fmt.Fprintf(os.Stderr, "[SKIP] %s - %s\n", path, result.Kept[path])
+	}
+	fmt.Fprintf(os.Stderr, "Restored %d files, kept %d\n", len(result.Restored), len(kept))
+}
+
+// UndoLastRun restores the files recorded in the manifest with
+// `git checkout --`. A file is only restored when it still holds what licer
+// wrote and the index holds what it held before the run, so neither later
+// edits nor unstaged changes from before the run are lost. Files that are
+// kept stay in the manifest for another attempt.
+func UndoLastRun(repoRoot string) (*UndoResult, error) {
+	manifest, err := readRunManifest(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UndoResult{Kept: make(map[string]string)}
+	var remaining []ModifiedFile
+	for _, file := range manifest.Files {
+		if reason := undoBlocker(repoRoot, file); reason != "" {
+			result.Kept[file.Path] = reason
+			remaining = append(remaining, file)
+			continue
+		}
+		result.Restored = append(result.Restored, file.Path)
+	}
+
+	if len(result.Restored) > 0 {
+		args := append([]string{"-C", repoRoot, "checkout", "--"}, result.Restored...)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("git checkout failed: %w: %s", err, out)
+		}
+	}
+
+	if len(remaining) == 0 {
+		if err := os.Remove(manifestPath(repoRoot)); err != nil {
+			return nil, err
+		}
+	} else {
+		for i := range remaining {
+			remaining[i].Path = filepath.Join(repoRoot, filepath.FromSlash(remaining[i].Path))
+		}
+		if err := writeRunManifest(repoRoot, remaining); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// undoBlocker returns why file can't be restored safely, or "" if it can
+func undoBlocker(repoRoot string, file ModifiedFile) string {
+	current, err := os.ReadFile(filepath.Join(repoRoot, filepath.FromSlash(file.Path)))
+	if err != nil {
+		return fmt.Sprintf("Cannot read file: %v", err)
+	}
+	if licer.ContentSum(current) != file.WrittenSHA256 {
+		return "Changed since the last run"
+	}
+
+	indexed, err := exec.Command("git", "-C", repoRoot, "show", ":"+file.Path).Output()
+	if err != nil {
+		return "Not tracked by git"
+	}
+	if licer.ContentSum(indexed) != file.OriginalSHA256 {
+		return "Had unstaged changes before the last run"
+	}
+	return ""
+}